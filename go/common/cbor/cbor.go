@@ -6,6 +6,8 @@
 package cbor
 
 import (
+	"bytes"
+	"errors"
 	"io"
 
 	"github.com/fxamacker/cbor/v2"
@@ -25,6 +27,9 @@ type Marshaler = cbor.Marshaler
 // must copy the CBOR data if it needs to use it after returning.
 type Unmarshaler = cbor.Unmarshaler
 
+// ErrNonCanonical is the error returned when decoding CBOR that is not in canonical form.
+var ErrNonCanonical = errors.New("common/cbor: non-canonical encoding")
+
 var (
 	encOptions = cbor.EncOptions{
 		Sort:          cbor.SortCanonical,
@@ -98,6 +103,32 @@ func Unmarshal(data []byte, dst interface{}) error {
 	return decMode.Unmarshal(data, dst)
 }
 
+// UnmarshalCanonical deserializes a CBOR byte vector into a given type, rejecting inputs that are
+// not in canonical form (e.g., non-shortest integer encodings or unsorted map keys).
+//
+// This should be used when decoding signed blobs to ensure that there is only a single valid
+// encoding of each message.
+func UnmarshalCanonical(data []byte, dst interface{}) error {
+	if data == nil {
+		return nil
+	}
+
+	// Make sure that re-encoding the generic representation yields the same encoding.
+	var generic interface{}
+	if err := decMode.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	canonical, err := encMode.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(canonical, data) {
+		return ErrNonCanonical
+	}
+
+	return decMode.Unmarshal(data, dst)
+}
+
 // UnmarshalTrusted deserializes a CBOR byte vector into a given type.
 //
 // This method MUST ONLY BE USED FOR TRUSTED INPUTS as it relaxes some decoding restrictions.
@@ -70,3 +70,35 @@ func TestDecodeUnknowField(t *testing.T) {
 	err = UnmarshalTrusted(raw, &dec)
 	require.NoError(err, "unknown fields from trusted sources should pass")
 }
+
+func TestUnmarshalCanonical(t *testing.T) {
+	require := require.New(t)
+
+	type a struct {
+		A  uint64
+		BB []byte
+	}
+	src := a{A: 42, BB: []byte("hello")}
+
+	var dec a
+	err := UnmarshalCanonical(Marshal(&src), &dec)
+	require.NoError(err, "canonical encoding should be accepted")
+	require.EqualValues(src, dec)
+
+	for _, raw := range [][]byte{
+		// Non-shortest integer encoding (42 encoded as uint16).
+		[]byte("\xa2\x61A\x19\x00\x2a\x62BB\x45hello"),
+		// Unsorted map keys.
+		[]byte("\xa2\x62BB\x45hello\x61A\x18\x2a"),
+	} {
+		err = Unmarshal(raw, &dec)
+		require.NoError(err, "non-canonical encoding should be accepted by Unmarshal")
+		require.EqualValues(src, dec)
+
+		err = UnmarshalCanonical(raw, &dec)
+		require.ErrorIs(err, ErrNonCanonical, "non-canonical encoding should be rejected")
+	}
+
+	err = UnmarshalCanonical(append(Marshal(&src), 0x00), &dec)
+	require.Error(err, "trailing data should be rejected")
+}
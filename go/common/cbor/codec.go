@@ -37,6 +37,20 @@ type MessageReader struct {
 
 	// module is the module name where the message is read to.
 	module string
+
+	// maxMessageSize is the maximum size of a message that will be accepted.
+	maxMessageSize uint64
+}
+
+// SetMaxMessageSize configures the maximum size of a message that will be accepted by Read.
+//
+// Messages with a length prefix larger than the given size are rejected before any of the message
+// body is read. The size is capped at the codec-wide maximum message size.
+func (c *MessageReader) SetMaxMessageSize(size uint64) {
+	if size > maxMessageSize {
+		size = maxMessageSize
+	}
+	c.maxMessageSize = size
 }
 
 // Read deserializes a single CBOR-encoded Message from the underlying reader.
@@ -50,7 +64,7 @@ func (c *MessageReader) Read(msg interface{}) error {
 	labels := prometheus.Labels{"module": c.module, "call": "read"}
 	length := binary.BigEndian.Uint32(rawLength)
 	codecValueSize.With(labels).Observe(float64(length))
-	if length > maxMessageSize {
+	if uint64(length) > c.maxMessageSize {
 		return errMessageTooLarge
 	}
 
@@ -112,7 +126,7 @@ func NewMessageCodec(rw io.ReadWriter, module string) *MessageCodec {
 	})
 
 	return &MessageCodec{
-		MessageReader: MessageReader{module: module, reader: rw},
+		MessageReader: MessageReader{module: module, reader: rw, maxMessageSize: maxMessageSize},
 		MessageWriter: MessageWriter{module: module, writer: rw},
 	}
 }
@@ -71,3 +71,19 @@ func TestCodecMalformed(t *testing.T) {
 	require.Error(err, "Read should fail with malformed message")
 	require.EqualValues(errMessageMalformed, err)
 }
+
+func TestCodecMaxMessageSize(t *testing.T) {
+	require := require.New(t)
+
+	var buffer bytes.Buffer
+	codec := NewMessageCodec(&buffer, t.Name())
+	codec.SetMaxMessageSize(4)
+
+	err := codec.Write([]byte("this message is larger than four bytes"))
+	require.NoError(err, "Write")
+
+	var x []byte
+	err = codec.Read(&x)
+	require.Error(err, "Read should fail with message larger than configured maximum")
+	require.EqualValues(errMessageTooLarge, err)
+}
@@ -95,6 +95,15 @@ func errorFromGrpc(err error) error {
 	return err
 }
 
+// ErrorFromGrpc converts an error received over gRPC back into the registered error that it was
+// mapped from, if any.
+//
+// Errors returned by unary calls and when opening streams are converted automatically, so this is
+// only needed for errors returned by other stream operations.
+func ErrorFromGrpc(err error) error {
+	return errorFromGrpc(err)
+}
+
 func serverUnaryErrorMapper(
 	ctx context.Context,
 	req interface{},
@@ -32,6 +32,15 @@ type Address struct {
 	net.TCPAddr
 }
 
+// clone returns a deep copy of the address.
+func (a *Address) clone() Address {
+	c := *a
+	if a.IP != nil {
+		c.IP = append(net.IP{}, a.IP...)
+	}
+	return c
+}
+
 // Equal compares vs another address for equality.
 func (a *Address) Equal(other *Address) bool {
 	if !a.IP.Equal(other.IP) {
@@ -6,10 +6,17 @@ package node
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
@@ -36,6 +43,10 @@ var (
 	// does not contain the node's RAK hash.
 	ErrRAKHashMismatch = errors.New("node: RAK hash mismatch")
 
+	// ErrReportDataMismatch is the error returned when the additional
+	// TEE report data does not match the required value.
+	ErrReportDataMismatch = errors.New("node: report data mismatch")
+
 	// ErrBadEnclaveIdentity is the error returned when the TEE enclave
 	// identity doesn't match the required values.
 	ErrBadEnclaveIdentity = errors.New("node: bad TEE enclave identity")
@@ -44,9 +55,39 @@ var (
 	// fails to conform to the optional additional constraints.
 	ErrConstraintViolation = errors.New("node: TEE constraint violation")
 
-	teeHashContext = []byte("oasis-core/node: TEE RAK binding")
+	// ErrBadSoftwareVersion is the error returned when the node's
+	// software version does not satisfy the required minimum.
+	ErrBadSoftwareVersion = errors.New("node: bad software version")
+
+	// ErrTEEHardwareMismatch is the error returned when the TEE
+	// constraints are for a different TEE hardware implementation.
+	ErrTEEHardwareMismatch = errors.New("node: TEE constraints hardware mismatch")
+
+	// ErrInvalidVRFInfo is the error returned when the node's VRF info
+	// is missing or invalid.
+	ErrInvalidVRFInfo = errors.New("node: invalid VRF info")
+
+	// ErrSignerThresholdNotMet is the error returned when a multi-signed
+	// node descriptor is not signed by enough of the allowed signers.
+	ErrSignerThresholdNotMet = errors.New("node: signer threshold not met")
+
+	// ErrMissingConsensusInfo is the error returned when the node's
+	// consensus ID or addresses required by its roles are missing.
+	ErrMissingConsensusInfo = errors.New("node: missing consensus info")
+
+	// ErrMissingP2PInfo is the error returned when the node's P2P ID or
+	// addresses required by its roles are missing.
+	ErrMissingP2PInfo = errors.New("node: missing P2P info")
+
+	// ErrMissingTLSInfo is the error returned when the node's TLS public
+	// key or addresses required by its roles are missing.
+	ErrMissingTLSInfo = errors.New("node: missing TLS info")
+
+	teeHashContext           = []byte("oasis-core/node: TEE RAK binding")
+	teeCapabilityHashContext = []byte("oasis-core/node: TEE capability")
 
 	_ prettyprint.PrettyPrinter = (*MultiSignedNode)(nil)
+	_ prettyprint.PrettyPrinter = (*Node)(nil)
 )
 
 const (
@@ -122,6 +163,20 @@ const (
 	// that are reserved and must not be used.
 	RoleReserved RolesMask = ((1<<32)-1) & ^((RoleStorageRPC<<1)-1) | roleReserved2
 
+	// VRFRequiredRoles are the Oasis node roles that participate in
+	// VRF-based elections and thus require VRF info.
+	VRFRequiredRoles = RoleComputeWorker | RoleValidator
+
+	// ConsensusInfoRequiredRoles are the Oasis node roles that require
+	// the consensus ID and addresses.
+	ConsensusInfoRequiredRoles = RoleValidator
+	// P2PInfoRequiredRoles are the Oasis node roles that require the P2P
+	// ID and addresses.
+	P2PInfoRequiredRoles = RoleComputeWorker | RoleKeyManager | RoleStorageRPC
+	// TLSInfoRequiredRoles are the Oasis node roles that require the TLS
+	// public key and addresses.
+	TLSInfoRequiredRoles = RoleConsensusRPC
+
 	// Human friendly role names.
 	RoleComputeWorkerName = "compute"
 	RoleKeyManagerName    = "key-manager"
@@ -149,6 +204,14 @@ func (m RolesMask) IsSingleRole() bool {
 	return m != 0 && m&(m-1) == 0 && m&RoleReserved == 0
 }
 
+// Validate returns an error in case the roles mask contains any reserved roles.
+func (m RolesMask) Validate() error {
+	if reserved := m & RoleReserved; reserved != 0 {
+		return fmt.Errorf("%w: reserved roles set (%#x)", ErrInvalidRole, uint32(reserved))
+	}
+	return nil
+}
+
 func (m RolesMask) String() string {
 	if m&RoleReserved != 0 {
 		return "[invalid roles]"
@@ -224,6 +287,52 @@ func (m *RolesMask) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// RolesMaskArray is a RolesMask that is JSON-encoded as an array of role
+// names (e.g., ["compute","validator"]) instead of a comma-separated string.
+type RolesMaskArray RolesMask
+
+// MarshalJSON encodes a RolesMaskArray into a canonically ordered JSON
+// array of role names.
+func (m RolesMaskArray) MarshalJSON() ([]byte, error) {
+	mask := RolesMask(m)
+	if err := mask.Validate(); err != nil {
+		return nil, err
+	}
+
+	roles := []string{}
+	if mask != 0 {
+		roles = strings.Split(mask.String(), rolesMaskStringSep)
+	}
+	return json.Marshal(roles)
+}
+
+// UnmarshalJSON decodes a JSON array of role names into a RolesMaskArray.
+func (m *RolesMaskArray) UnmarshalJSON(data []byte) error {
+	var roles []string
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return err
+	}
+
+	var mask RolesMask
+	for _, role := range roles {
+		// Make sure that each entry is a single role name.
+		if strings.Contains(role, rolesMaskStringSep) {
+			return fmt.Errorf("%w: '%s'", ErrInvalidRole, role)
+		}
+
+		var r RolesMask
+		if err := r.UnmarshalText([]byte(role)); err != nil {
+			return err
+		}
+		if err := checkDuplicateRole(r, mask); err != nil {
+			return err
+		}
+		mask |= r
+	}
+	*m = RolesMaskArray(mask)
+	return nil
+}
+
 // UnmarshalCBOR is a custom deserializer that handles both v1 and v2 Node structures.
 func (n *Node) UnmarshalCBOR(data []byte) error {
 	// Determine Entity structure version.
@@ -285,6 +394,165 @@ func (n *Node) ValidateBasic(strictVersion bool) error {
 	return nil
 }
 
+// CheckVRF checks that the node's VRF info is present when the node has any
+// of the VRFRequiredRoles and that the VRF ID is set when the VRF info is
+// present.
+//
+// The deprecated PVSS beacon field is not considered.
+func (n *Node) CheckVRF() error {
+	if n.VRF == nil {
+		if n.HasRoles(VRFRequiredRoles) {
+			return fmt.Errorf("%w: missing VRF info for roles %s", ErrInvalidVRFInfo, n.Roles&VRFRequiredRoles)
+		}
+		return nil
+	}
+
+	var zero signature.PublicKey
+	if n.VRF.ID.Equal(zero) {
+		return fmt.Errorf("%w: zero VRF ID", ErrInvalidVRFInfo)
+	}
+	return nil
+}
+
+// CheckConnectivity checks that the node's identifiers and addresses required
+// by its roles are present and non-zero:
+//
+// - ConsensusInfoRequiredRoles require the consensus ID and addresses.
+// - P2PInfoRequiredRoles require the P2P ID and addresses.
+// - TLSInfoRequiredRoles require the TLS public key and addresses.
+//
+// The addresses themselves are not validated.
+func (n *Node) CheckConnectivity() error {
+	isValidID := func(id signature.PublicKey) bool {
+		return id.IsValid() && !id.Equal(signature.PublicKey{})
+	}
+
+	if n.HasRoles(ConsensusInfoRequiredRoles) {
+		if !isValidID(n.Consensus.ID) {
+			return fmt.Errorf("%w: missing consensus ID", ErrMissingConsensusInfo)
+		}
+		if len(n.Consensus.Addresses) == 0 {
+			return fmt.Errorf("%w: missing consensus addresses", ErrMissingConsensusInfo)
+		}
+	}
+	if n.HasRoles(P2PInfoRequiredRoles) {
+		if !isValidID(n.P2P.ID) {
+			return fmt.Errorf("%w: missing P2P ID", ErrMissingP2PInfo)
+		}
+		if len(n.P2P.Addresses) == 0 {
+			return fmt.Errorf("%w: missing P2P addresses", ErrMissingP2PInfo)
+		}
+	}
+	if n.HasRoles(TLSInfoRequiredRoles) {
+		if !isValidID(n.TLS.PubKey) {
+			return fmt.Errorf("%w: missing TLS public key", ErrMissingTLSInfo)
+		}
+		if len(n.TLS.Addresses) == 0 {
+			return fmt.Errorf("%w: missing TLS addresses", ErrMissingTLSInfo)
+		}
+	}
+	return nil
+}
+
+// AddressTransport is the transport over which a node claims to be reachable.
+type AddressTransport string
+
+const (
+	// AddressTransportConsensus is the consensus transport.
+	AddressTransportConsensus AddressTransport = "consensus"
+	// AddressTransportP2P is the P2P transport.
+	AddressTransportP2P AddressTransport = "p2p"
+	// AddressTransportTLS is the TLS transport.
+	AddressTransportTLS AddressTransport = "tls"
+)
+
+// TransportAddress is a node address together with its transport.
+type TransportAddress struct {
+	// Transport is the transport over which the address is reachable.
+	Transport AddressTransport `json:"transport"`
+
+	// Address is the address.
+	Address Address `json:"address"`
+}
+
+// String returns a string representation of the transport address.
+func (ta TransportAddress) String() string {
+	return string(ta.Transport) + "://" + ta.Address.String()
+}
+
+// AllTransportAddresses returns all of the addresses at which the node claims to be reachable
+// together with their transports.
+//
+// Duplicate addresses are removed and the result is sorted by transport and then by address.
+func (n *Node) AllTransportAddresses() []TransportAddress {
+	var tas []TransportAddress
+	for _, addr := range n.Consensus.Addresses {
+		tas = append(tas, TransportAddress{AddressTransportConsensus, addr.Address})
+	}
+	for _, addr := range n.P2P.Addresses {
+		tas = append(tas, TransportAddress{AddressTransportP2P, addr})
+	}
+	for _, addr := range n.TLS.Addresses {
+		tas = append(tas, TransportAddress{AddressTransportTLS, addr.Address})
+	}
+
+	sort.SliceStable(tas, func(i, j int) bool {
+		if tas[i].Transport != tas[j].Transport {
+			return tas[i].Transport < tas[j].Transport
+		}
+		return tas[i].Address.String() < tas[j].Address.String()
+	})
+
+	var deduped []TransportAddress
+	for i, ta := range tas {
+		if i > 0 {
+			prev := deduped[len(deduped)-1]
+			if prev.Transport == ta.Transport && prev.Address.Equal(&ta.Address) {
+				continue
+			}
+		}
+		deduped = append(deduped, ta)
+	}
+	return deduped
+}
+
+// AllAddresses returns the sorted and deduplicated string representations of all of the addresses
+// at which the node claims to be reachable, across all transports.
+func (n *Node) AllAddresses() []string {
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, ta := range n.AllTransportAddresses() {
+		addr := ta.Address.String()
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Clone returns a deep copy of the node descriptor.
+func (n *Node) Clone() *Node {
+	c := *n
+	c.TLS.Addresses = cloneTLSAddresses(n.TLS.Addresses)
+	c.P2P.Addresses = cloneAddresses(n.P2P.Addresses)
+	c.Consensus.Addresses = cloneConsensusAddresses(n.Consensus.Addresses)
+	if n.VRF != nil {
+		vrf := *n.VRF
+		c.VRF = &vrf
+	}
+	c.DeprecatedBeacon = cloneBytes(n.DeprecatedBeacon)
+	if n.Runtimes != nil {
+		c.Runtimes = make([]*Runtime, 0, len(n.Runtimes))
+		for _, rt := range n.Runtimes {
+			c.Runtimes = append(c.Runtimes, rt.clone())
+		}
+	}
+	return &c
+}
+
 // AddRoles adds a new node role to the existing roles mask.
 func (n *Node) AddRoles(r RolesMask) {
 	n.Roles |= r
@@ -300,10 +568,57 @@ func (n *Node) OnlyHasRoles(r RolesMask) bool {
 	return n.Roles == r
 }
 
+// identityFingerprintSize is the size of the node identity fingerprint in bytes.
+const identityFingerprintSize = 8
+
+// IdentityFingerprint returns a short hex-encoded fingerprint of the node's identity keys.
+//
+// The fingerprint covers the node, P2P, consensus and TLS public keys and is stable across
+// updates that do not change any of these keys (e.g., address, expiration or runtime changes).
+func (n *Node) IdentityFingerprint() string {
+	h := hash.NewFromBytes(
+		n.ID[:],
+		n.P2P.ID[:],
+		n.Consensus.ID[:],
+		n.TLS.PubKey[:],
+	)
+	return hex.EncodeToString(h[:identityFingerprintSize])
+}
+
+// BuildRoleIndex builds an index of the given nodes by role.
+//
+// For each valid single role the index contains the nodes that have that role, preserving the
+// order of the passed nodes. A node with multiple roles appears under each of its roles while
+// reserved role bits are ignored.
+func BuildRoleIndex(nodes []*Node) map[RolesMask][]*Node {
+	index := make(map[RolesMask][]*Node)
+	for _, n := range nodes {
+		for _, role := range Roles() {
+			if n.HasRoles(role) {
+				index[role] = append(index[role], n)
+			}
+		}
+	}
+	return index
+}
+
 // IsExpired returns true if the node expiration epoch is strictly smaller
 // than the passed (current) epoch.
 func (n *Node) IsExpired(epoch uint64) bool {
-	return n.Expiration < epoch
+	return n.IsExpiredWithGrace(epoch, 0)
+}
+
+// IsExpiredWithGrace returns true if the node expiration epoch extended
+// by the given grace period is strictly smaller than the passed (current)
+// epoch.
+//
+// In case the extended expiration epoch would overflow, the node is
+// considered to never expire.
+func (n *Node) IsExpiredWithGrace(epoch, grace uint64) bool {
+	if n.Expiration > math.MaxUint64-grace {
+		return false
+	}
+	return n.Expiration+grace < epoch
 }
 
 // HasRuntime returns true iff the node supports a runtime (ignoring version).
@@ -316,6 +631,73 @@ func (n *Node) HasRuntime(id common.Namespace) bool {
 	return false
 }
 
+// SupportsRuntime returns true iff the node supports the given version of a runtime.
+//
+// Use HasRuntime to check whether the node supports any version of a runtime.
+func (n *Node) SupportsRuntime(id common.Namespace, version version.Version) bool {
+	return n.GetRuntime(id, version) != nil
+}
+
+// RuntimeIDs returns the sorted and deduplicated identifiers of all runtimes supported by the node.
+func (n *Node) RuntimeIDs() []common.Namespace {
+	seen := make(map[common.Namespace]bool)
+	var ids []common.Namespace
+	for _, rt := range n.Runtimes {
+		if seen[rt.ID] {
+			continue
+		}
+		seen[rt.ID] = true
+		ids = append(ids, rt.ID)
+	}
+	sortNamespaces(ids)
+	return ids
+}
+
+// CanBackupResolve returns true iff the node is eligible to act as a backup worker for discrepancy
+// resolution of the given runtime at the provided timestamp.
+//
+// The node must be a compute worker supporting the runtime. In case the runtime requires a TEE (the
+// passed TEE constraints are non-empty), the node's TEE capability for the runtime must also verify
+// against the constraints.
+func (n *Node) CanBackupResolve(runtimeID common.Namespace, ts time.Time, constraints []byte) bool {
+	if !n.HasRoles(RoleComputeWorker) {
+		return false
+	}
+
+	for _, rt := range n.Runtimes {
+		if !rt.ID.Equal(&runtimeID) {
+			continue
+		}
+		if len(constraints) == 0 {
+			return true
+		}
+		if rt.Capabilities.TEE != nil && rt.Capabilities.TEE.Verify(ts, constraints) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSoftwareVersion checks that the node's reported software version is at least the given
+// minimum version. A zero minimum version disables the check.
+func (n *Node) CheckSoftwareVersion(min version.Version) error {
+	if min == (version.Version{}) {
+		return nil
+	}
+	if n.SoftwareVersion == "" {
+		return fmt.Errorf("%w: software version not set", ErrBadSoftwareVersion)
+	}
+
+	v, err := version.FromString(n.SoftwareVersion)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBadSoftwareVersion, err)
+	}
+	if v.ToU64() < min.ToU64() {
+		return fmt.Errorf("%w: %s is older than %s", ErrBadSoftwareVersion, v, min)
+	}
+	return nil
+}
+
 // GetRuntime searches for an existing supported runtime descriptor
 // in Runtimes with the specified version and returns it.
 func (n *Node) GetRuntime(id common.Namespace, version version.Version) *Runtime {
@@ -349,6 +731,69 @@ func (n *Node) AddOrUpdateRuntime(id common.Namespace, version version.Version)
 	return rt
 }
 
+// TEERuntimes returns the sorted identifiers of supported runtimes for which the node advertises
+// a TEE capability for at least one of the supported runtime versions.
+func (n *Node) TEERuntimes() []common.Namespace {
+	return n.partitionRuntimes(true)
+}
+
+// NonTEERuntimes returns the sorted identifiers of supported runtimes for which the node does not
+// advertise a TEE capability for any of the supported runtime versions.
+//
+// This is the complement of TEERuntimes.
+func (n *Node) NonTEERuntimes() []common.Namespace {
+	return n.partitionRuntimes(false)
+}
+
+func (n *Node) partitionRuntimes(tee bool) []common.Namespace {
+	hasTEE := make(map[common.Namespace]bool)
+	for _, rt := range n.Runtimes {
+		hasTEE[rt.ID] = hasTEE[rt.ID] || rt.Capabilities.TEE != nil
+	}
+
+	var ids []common.Namespace
+	for id, v := range hasTEE {
+		if v == tee {
+			ids = append(ids, id)
+		}
+	}
+	sortNamespaces(ids)
+	return ids
+}
+
+func sortNamespaces(ids []common.Namespace) {
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+}
+
+// Normalize canonicalizes the node descriptor so that semantically identical descriptors have
+// identical serializations.
+//
+// Supported runtimes are stably sorted by runtime identifier (and then by version as nodes may
+// support multiple versions of the same runtime) and duplicate entries for the same runtime
+// version are removed, retaining the first one.
+func (n *Node) Normalize() {
+	sort.SliceStable(n.Runtimes, func(i, j int) bool {
+		if cmp := bytes.Compare(n.Runtimes[i].ID[:], n.Runtimes[j].ID[:]); cmp != 0 {
+			return cmp < 0
+		}
+		return n.Runtimes[i].Version.ToU64() < n.Runtimes[j].Version.ToU64()
+	})
+
+	var deduped []*Runtime
+	for i, rt := range n.Runtimes {
+		if i > 0 {
+			prev := deduped[len(deduped)-1]
+			if prev.ID.Equal(&rt.ID) && prev.Version == rt.Version {
+				continue
+			}
+		}
+		deduped = append(deduped, rt)
+	}
+	n.Runtimes = deduped
+}
+
 // Runtime represents the runtimes supported by a given Oasis node.
 type Runtime struct {
 	// ID is the public key identifying the runtime.
@@ -365,6 +810,22 @@ type Runtime struct {
 	ExtraInfo []byte `json:"extra_info"`
 }
 
+// clone returns a deep copy of the runtime descriptor.
+func (r *Runtime) clone() *Runtime {
+	if r == nil {
+		return nil
+	}
+
+	c := *r
+	if r.Capabilities.TEE != nil {
+		tee := *r.Capabilities.TEE
+		tee.Attestation = cloneBytes(r.Capabilities.TEE.Attestation)
+		c.Capabilities.TEE = &tee
+	}
+	c.ExtraInfo = cloneBytes(r.ExtraInfo)
+	return &c
+}
+
 // TLSInfo contains information for connecting to this node via TLS.
 type TLSInfo struct {
 	// PubKey is the public key used for establishing TLS connections.
@@ -378,6 +839,23 @@ type TLSInfo struct {
 	Addresses []TLSAddress `json:"addresses"`
 }
 
+// IsRotating returns true iff the node has announced the public key that will be used for
+// establishing TLS connections after certificate rotation.
+//
+// Note: An all-zero NextPubKey is treated as not set.
+func (t *TLSInfo) IsRotating() bool {
+	return t.NextPubKey.IsValid() && !t.NextPubKey.Equal(signature.PublicKey{})
+}
+
+// IsValidPubKey returns true iff the given public key is either the current or (in case the
+// node is rotating its TLS certificates) the next TLS public key of the node.
+func (t *TLSInfo) IsValidPubKey(pk signature.PublicKey) bool {
+	if pk.Equal(t.PubKey) {
+		return true
+	}
+	return t.IsRotating() && pk.Equal(t.NextPubKey)
+}
+
 // Equal compares vs another TLSInfo for equality.
 func (t *TLSInfo) Equal(other *TLSInfo) bool {
 	if !t.PubKey.Equal(other.PubKey) {
@@ -450,30 +928,71 @@ const (
 	teeIntelSGX = "intel-sgx"
 )
 
+// teeHardwareDescriptor describes a TEE hardware implementation.
+type teeHardwareDescriptor struct {
+	// name is the string representation of the TEE hardware.
+	name string
+	// verify verifies the TEE capability at the provided timestamp. It may
+	// be nil in which case the hardware cannot be verified.
+	verify func(c *CapabilityTEE, ts time.Time, constraints []byte, trustRoots *x509.CertPool) error
+	// verifyAll is like verify, but runs all constraint checks and returns
+	// all of the failures. It may be nil in which case only the first
+	// failure reported by verify is returned.
+	verifyAll func(c *CapabilityTEE, ts time.Time, constraints []byte, trustRoots *x509.CertPool) []error
+}
+
+// teeHardwareRegistry is the registry of known TEE hardware implementations.
+var teeHardwareRegistry = make(map[TEEHardware]*teeHardwareDescriptor)
+
+// registerTEEHardware registers a new TEE hardware implementation.
+//
+// This must only be called from an init function.
+func registerTEEHardware(h TEEHardware, desc *teeHardwareDescriptor) {
+	if _, exists := teeHardwareRegistry[h]; exists {
+		panic(fmt.Sprintf("node: TEE hardware %d already registered", h))
+	}
+	for _, d := range teeHardwareRegistry {
+		if d.name == desc.name {
+			panic(fmt.Sprintf("node: TEE hardware name '%s' already registered", desc.name))
+		}
+	}
+	teeHardwareRegistry[h] = desc
+}
+
 // String returns the string representation of a TEEHardware.
 func (h TEEHardware) String() string {
-	switch h {
-	case TEEHardwareInvalid:
-		return teeInvalid
-	case TEEHardwareIntelSGX:
-		return teeIntelSGX
-	default:
+	desc, ok := teeHardwareRegistry[h]
+	if !ok {
 		return "[unsupported TEEHardware]"
 	}
+	return desc.name
 }
 
 // FromString deserializes a string into a TEEHardware.
 func (h *TEEHardware) FromString(str string) error {
-	switch strings.ToLower(str) {
-	case "", teeInvalid:
-		*h = TEEHardwareInvalid
-	case teeIntelSGX:
-		*h = TEEHardwareIntelSGX
-	default:
-		return ErrInvalidTEEHardware
+	str = strings.ToLower(str)
+	if str == "" {
+		str = teeInvalid
+	}
+	for hw, desc := range teeHardwareRegistry {
+		if desc.name == str {
+			*h = hw
+			return nil
+		}
 	}
 
-	return nil
+	return ErrInvalidTEEHardware
+}
+
+func init() {
+	registerTEEHardware(TEEHardwareInvalid, &teeHardwareDescriptor{
+		name: teeInvalid,
+	})
+	registerTEEHardware(TEEHardwareIntelSGX, &teeHardwareDescriptor{
+		name:      teeIntelSGX,
+		verify:    (*CapabilityTEE).verifySGX,
+		verifyAll: (*CapabilityTEE).verifySGXAll,
+	})
 }
 
 // CapabilityTEE represents the node's TEE capability.
@@ -488,6 +1007,57 @@ type CapabilityTEE struct {
 	Attestation []byte `json:"attestation"`
 }
 
+// TEEConstraints are the TEE constraints tagged with the TEE hardware
+// implementation they are for.
+type TEEConstraints struct {
+	// Hardware is the TEE hardware implementation the constraints are for.
+	Hardware TEEHardware `json:"hardware"`
+
+	// SGX are the Intel SGX TEE constraints.
+	SGX *SGXConstraints `json:"sgx,omitempty"`
+}
+
+// DecodeTEEConstraints decodes the given serialized TEE constraints and
+// ensures that they are for the given TEE hardware implementation.
+//
+// For backwards compatibility, bare serialized SGXConstraints are also
+// accepted and treated as Intel SGX TEE constraints.
+func DecodeTEEConstraints(raw []byte, hw TEEHardware) (*TEEConstraints, error) {
+	var fields map[string]cbor.RawMessage
+	if err := cbor.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("node: malformed TEE constraints: %w", err)
+	}
+
+	var tc TEEConstraints
+	if _, ok := fields["hardware"]; ok {
+		if err := cbor.Unmarshal(raw, &tc); err != nil {
+			return nil, fmt.Errorf("node: malformed TEE constraints: %w", err)
+		}
+	} else {
+		// Bare SGX constraints.
+		var cs SGXConstraints
+		if err := cbor.Unmarshal(raw, &cs); err != nil {
+			return nil, fmt.Errorf("node: malformed SGX constraints: %w", err)
+		}
+		tc.Hardware = TEEHardwareIntelSGX
+		tc.SGX = &cs
+	}
+
+	if tc.Hardware != hw {
+		return nil, fmt.Errorf("%w: constraints are for '%s', expected '%s'", ErrTEEHardwareMismatch, tc.Hardware, hw)
+	}
+	switch tc.Hardware {
+	case TEEHardwareIntelSGX:
+		if tc.SGX == nil {
+			return nil, fmt.Errorf("node: malformed TEE constraints: missing SGX constraints")
+		}
+	default:
+		return nil, ErrInvalidTEEHardware
+	}
+
+	return &tc, nil
+}
+
 // SGXConstraints are the Intel SGX TEE constraints.
 type SGXConstraints struct {
 	// Enclaves is the allowed MRENCLAVE/MRSIGNER pairs.
@@ -498,6 +1068,53 @@ type SGXConstraints struct {
 	//
 	// Note: QuoteOK and QuoteSwHardeningNeeded are ALWAYS allowed, and do not need to be specified.
 	AllowedQuoteStatuses []ias.ISVEnclaveQuoteStatus `json:"allowed_quote_statuses,omitempty"`
+
+	// ReportDataExtra is the optional expected value of the last 32 bytes of the enclave report
+	// data. If not set, these bytes are ignored.
+	ReportDataExtra *hash.Hash `json:"report_data_extra,omitempty"`
+}
+
+// clone returns a deep copy of the constraints.
+func (constraints *SGXConstraints) clone() *SGXConstraints {
+	cs := &SGXConstraints{
+		Enclaves:             append([]sgx.EnclaveIdentity(nil), constraints.Enclaves...),
+		AllowedQuoteStatuses: append([]ias.ISVEnclaveQuoteStatus(nil), constraints.AllowedQuoteStatuses...),
+	}
+	if constraints.ReportDataExtra != nil {
+		extra := *constraints.ReportDataExtra
+		cs.ReportDataExtra = &extra
+	}
+	return cs
+}
+
+// WithAddedEnclave returns a copy of the constraints which additionally allow the given enclave
+// identity. The receiver is not modified.
+//
+// In case the enclave identity is already allowed, an unmodified copy is returned.
+func (constraints *SGXConstraints) WithAddedEnclave(e sgx.EnclaveIdentity) *SGXConstraints {
+	cs := constraints.clone()
+	for _, eid := range cs.Enclaves {
+		if eid == e {
+			return cs
+		}
+	}
+	cs.Enclaves = append(cs.Enclaves, e)
+	return cs
+}
+
+// WithRemovedEnclave returns a copy of the constraints which no longer allow the given enclave
+// identity. The receiver is not modified.
+func (constraints *SGXConstraints) WithRemovedEnclave(e sgx.EnclaveIdentity) *SGXConstraints {
+	cs := constraints.clone()
+	enclaves := cs.Enclaves[:0]
+	for _, eid := range cs.Enclaves {
+		if eid == e {
+			continue
+		}
+		enclaves = append(enclaves, eid)
+	}
+	cs.Enclaves = enclaves
+	return cs
 }
 
 func (constraints *SGXConstraints) quoteStatusAllowed(avr *ias.AttestationVerificationReport) bool {
@@ -527,67 +1144,343 @@ func RAKHash(rak signature.PublicKey) hash.Hash {
 	return hash.NewFromBytes(hData)
 }
 
+// CheckReportDataLayout checks that the given enclave report data is bound to the given RAK.
+//
+// The first 32 bytes of the report data must be equal to RAKHash(rak). The last 32 bytes are
+// caller-defined and are deliberately ignored.
+func CheckReportDataLayout(reportData [64]byte, rak signature.PublicKey) error {
+	return CheckReportDataLayoutWithExtra(reportData, rak, nil)
+}
+
+// CheckReportDataLayoutWithExtra checks that the given enclave report data is bound to the given
+// RAK and, in case extra is non-nil, that the last 32 bytes of the report data are equal to it.
+func CheckReportDataLayoutWithExtra(reportData [64]byte, rak signature.PublicKey, extra *hash.Hash) error {
+	var reportRAKHash hash.Hash
+	_ = reportRAKHash.UnmarshalBinary(reportData[:hash.Size])
+	rakHash := RAKHash(rak)
+	if !rakHash.Equal(&reportRAKHash) {
+		return ErrRAKHashMismatch
+	}
+	return checkReportDataExtra(reportData, extra)
+}
+
+// checkReportDataExtra checks that, in case extra is non-nil, the last 32 bytes of the given
+// report data are equal to it.
+func checkReportDataExtra(reportData [64]byte, extra *hash.Hash) error {
+	if extra == nil {
+		return nil
+	}
+
+	var reportExtra hash.Hash
+	_ = reportExtra.UnmarshalBinary(reportData[hash.Size:])
+	if !extra.Equal(&reportExtra) {
+		return ErrReportDataMismatch
+	}
+	return nil
+}
+
 // Verify verifies the node's TEE capabilities, at the provided timestamp.
+//
+// All time-dependent checks (e.g., attestation certificate validity) are performed as of the
+// provided timestamp and never against the local clock. Production callers must pass a timestamp
+// derived from consensus (e.g., the current block time) and not wall-clock time so that all nodes
+// reach the same verification result.
 func (c *CapabilityTEE) Verify(ts time.Time, constraints []byte) error {
-	rakHash := RAKHash(c.RAK)
+	return c.VerifyWithTrustRoots(ts, constraints, ias.IntelTrustRoots)
+}
 
-	switch c.Hardware {
-	case TEEHardwareIntelSGX:
-		var avrBundle ias.AVRBundle
-		if err := cbor.Unmarshal(c.Attestation, &avrBundle); err != nil {
-			return err
-		}
+// VerifyWithTrustRoots verifies the node's TEE capabilities, at the provided timestamp, using the
+// given IAS trust roots instead of Intel's IAS signing root certificates.
+//
+// This is only meant for networks using a test IAS, production verification must use Verify. The
+// trust roots must be non-nil as otherwise the system trust roots would be used.
+func (c *CapabilityTEE) VerifyWithTrustRoots(ts time.Time, constraints []byte, trustRoots *x509.CertPool) error {
+	if trustRoots == nil {
+		return fmt.Errorf("node: no IAS trust roots")
+	}
 
-		avr, err := avrBundle.Open(ias.IntelTrustRoots, ts)
-		if err != nil {
-			return err
+	desc, ok := teeHardwareRegistry[c.Hardware]
+	if !ok || desc.verify == nil {
+		return ErrInvalidTEEHardware
+	}
+	return desc.verify(c, ts, constraints, trustRoots)
+}
+
+// VerifyWithNonce verifies the node's TEE capabilities, at the provided timestamp, like Verify and
+// additionally checks that the last 32 bytes of the attestation report data are equal to the given
+// nonce, binding the attestation to a live challenge.
+//
+// In case the nonce is all zeros, it is not checked.
+func (c *CapabilityTEE) VerifyWithNonce(ts time.Time, constraints []byte, expectedNonce [32]byte) error {
+	if expectedNonce == [32]byte{} {
+		return c.Verify(ts, constraints)
+	}
+
+	tc, err := DecodeTEEConstraints(constraints, c.Hardware)
+	if err != nil {
+		return err
+	}
+	nonce := hash.Hash(expectedNonce)
+	if tc.SGX.ReportDataExtra != nil && !tc.SGX.ReportDataExtra.Equal(&nonce) {
+		// The constraints require different report data so no nonce can ever match.
+		return fmt.Errorf("%w: report data nonce mismatch", ErrConstraintViolation)
+	}
+	tc.SGX.ReportDataExtra = &nonce
+
+	err = c.Verify(ts, cbor.Marshal(tc))
+	if errors.Is(err, ErrReportDataMismatch) {
+		return fmt.Errorf("%w: report data nonce mismatch", ErrConstraintViolation)
+	}
+	return err
+}
+
+// VerifyAll verifies the node's TEE capabilities, at the provided timestamp, like Verify but
+// instead of stopping at the first failure it runs all of the constraint checks and returns all
+// failures. An empty result means that verification succeeded.
+//
+// In case the attestation or the constraints cannot be parsed, only that failure is returned as
+// the remaining checks cannot be performed. This is meant for diagnosing misconfigured runtimes,
+// use Verify for actual verification.
+func (c *CapabilityTEE) VerifyAll(ts time.Time, constraints []byte) []error {
+	desc, ok := teeHardwareRegistry[c.Hardware]
+	if !ok || desc.verify == nil {
+		return []error{ErrInvalidTEEHardware}
+	}
+	if desc.verifyAll == nil {
+		if err := desc.verify(c, ts, constraints, ias.IntelTrustRoots); err != nil {
+			return []error{err}
 		}
+		return nil
+	}
+	return desc.verifyAll(c, ts, constraints, ias.IntelTrustRoots)
+}
 
-		// Extract the original ISV quote.
-		q, err := avr.Quote()
-		if err != nil {
-			return err
+// firstError returns the first error of the given errors or nil if there are none.
+func firstError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
+}
+
+// verifySGX verifies the node's Intel SGX TEE capabilities.
+func (c *CapabilityTEE) verifySGX(ts time.Time, constraints []byte, trustRoots *x509.CertPool) error {
+	return firstError(c.verifySGXAll(ts, constraints, trustRoots))
+}
+
+// verifySGXAll verifies the node's Intel SGX TEE capabilities, returning all failures.
+func (c *CapabilityTEE) verifySGXAll(ts time.Time, constraints []byte, trustRoots *x509.CertPool) []error {
+	avr, q, err := c.openSGXAttestation(ts, trustRoots)
+	if err != nil {
+		return []error{err}
+	}
+
+	// Ensure that the MRENCLAVE/MRSIGNER match what is specified
+	// in the TEE-specific constraints field.
+	tc, err := DecodeTEEConstraints(constraints, c.Hardware)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	cs := tc.SGX
+	var eidValid bool
+	for _, eid := range cs.Enclaves {
+		eidMrenclave := eid.MrEnclave
+		eidMrsigner := eid.MrSigner
+		if bytes.Equal(eidMrenclave[:], q.Report.MRENCLAVE[:]) && bytes.Equal(eidMrsigner[:], q.Report.MRSIGNER[:]) {
+			eidValid = true
+			break
 		}
+	}
+	if !eidValid {
+		errs = append(errs, ErrBadEnclaveIdentity)
+	}
 
-		// Ensure that the MRENCLAVE/MRSIGNER match what is specified
-		// in the TEE-specific constraints field.
-		var cs SGXConstraints
-		if err := cbor.Unmarshal(constraints, &cs); err != nil {
-			return fmt.Errorf("node: malformed SGX constraints: %w", err)
-		}
-		var eidValid bool
-		for _, eid := range cs.Enclaves {
-			eidMrenclave := eid.MrEnclave
-			eidMrsigner := eid.MrSigner
-			if bytes.Equal(eidMrenclave[:], q.Report.MRENCLAVE[:]) && bytes.Equal(eidMrsigner[:], q.Report.MRSIGNER[:]) {
-				eidValid = true
-				break
-			}
+	// Ensure that the ISV quote includes the hash of the node's
+	// RAK and any additional data required by the constraints.
+	if err = CheckReportDataLayout(q.Report.ReportData, c.RAK); err != nil {
+		errs = append(errs, err)
+	}
+	if err = checkReportDataExtra(q.Report.ReportData, cs.ReportDataExtra); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Ensure that the quote status is acceptable.
+	if !cs.quoteStatusAllowed(avr) {
+		errs = append(errs, ErrConstraintViolation)
+	}
+
+	return errs
+}
+
+// openSGXAttestation opens the SGX attestation, verifying the AVR at the provided timestamp against
+// the given trust roots, and returns the AVR together with the original ISV quote.
+func (c *CapabilityTEE) openSGXAttestation(ts time.Time, trustRoots *x509.CertPool) (*ias.AttestationVerificationReport, *ias.Quote, error) {
+	var avrBundle ias.AVRBundle
+	if err := cbor.Unmarshal(c.Attestation, &avrBundle); err != nil {
+		return nil, nil, err
+	}
+
+	avr, err := avrBundle.Open(trustRoots, ts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Extract the original ISV quote.
+	q, err := avr.Quote()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return avr, q, nil
+}
+
+// Hash returns a domain-separated hash of the TEE capability that is stable across serialization
+// round-trips and can be used to identify an already verified attestation.
+//
+// The hash is computed over the context, the hardware identifier, the RAK and the raw attestation,
+// in that order. As only the attestation is variable-length, the encoding is unambiguous.
+func (c *CapabilityTEE) Hash() hash.Hash {
+	return hash.NewFromBytes(
+		teeCapabilityHashContext,
+		[]byte{byte(c.Hardware)},
+		c.RAK[:],
+		c.Attestation,
+	)
+}
+
+// EnclaveIdentity returns the enclave identity contained in the node's TEE capability without
+// applying any constraints.
+//
+// The AVR signature and certificate chain are still verified, but as of the time when the AVR was
+// issued so that old AVRs are not rejected. This makes it unsuitable for deciding whether a node
+// should be trusted; use Verify for that.
+func (c *CapabilityTEE) EnclaveIdentity() (*sgx.EnclaveIdentity, error) {
+	if c.Hardware != TEEHardwareIntelSGX {
+		return nil, ErrInvalidTEEHardware
+	}
+
+	// Extract the (untrusted) AVR timestamp so we can verify the AVR as of its issue time.
+	var avrBundle ias.AVRBundle
+	if err := cbor.Unmarshal(c.Attestation, &avrBundle); err != nil {
+		return nil, err
+	}
+	var untrustedAVR ias.AttestationVerificationReport
+	if err := json.Unmarshal(avrBundle.Body, &untrustedAVR); err != nil {
+		return nil, fmt.Errorf("node: malformed AVR: %w", err)
+	}
+	ts, err := time.Parse(ias.TimestampFormat, untrustedAVR.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("node: malformed AVR timestamp: %w", err)
+	}
+
+	_, q, err := c.openSGXAttestation(ts, ias.IntelTrustRoots)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sgx.EnclaveIdentity{
+		MrEnclave: q.Report.MRENCLAVE,
+		MrSigner:  q.Report.MRSIGNER,
+	}, nil
+}
+
+// SGXConstraintsFromCapability builds SGX constraints that allow exactly the enclave identity
+// contained in the given TEE capability, verified at the provided timestamp.
+//
+// This is useful during initial runtime deployment, where the allowed enclave identities can be
+// derived from the attestation of a single trusted node.
+func SGXConstraintsFromCapability(c *CapabilityTEE, ts time.Time, allowedStatuses []ias.ISVEnclaveQuoteStatus) (*SGXConstraints, error) {
+	if c.Hardware != TEEHardwareIntelSGX {
+		return nil, ErrInvalidTEEHardware
+	}
+
+	avr, q, err := c.openSGXAttestation(ts, ias.IntelTrustRoots)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure that the ISV quote includes the hash of the node's RAK.
+	if err = CheckReportDataLayout(q.Report.ReportData, c.RAK); err != nil {
+		return nil, err
+	}
+
+	cs := &SGXConstraints{
+		Enclaves: []sgx.EnclaveIdentity{
+			{
+				MrEnclave: q.Report.MRENCLAVE,
+				MrSigner:  q.Report.MRSIGNER,
+			},
+		},
+		AllowedQuoteStatuses: append([]ias.ISVEnclaveQuoteStatus{}, allowedStatuses...),
+	}
+
+	// Ensure that the resulting constraints actually allow the capability.
+	if !cs.quoteStatusAllowed(avr) {
+		return nil, ErrConstraintViolation
+	}
+
+	return cs, nil
+}
+
+// NewSGXConstraints builds canonical serialized SGX constraints allowing the given enclave
+// identities and additional quote statuses, suitable for passing to CapabilityTEE.Verify.
+//
+// Duplicate enclave identities and quote statuses are removed and both are sorted. Since QuoteOK
+// and QuoteSwHardeningNeeded are always allowed, they are omitted from the allowed statuses.
+func NewSGXConstraints(enclaves []sgx.EnclaveIdentity, allowedStatuses []ias.ISVEnclaveQuoteStatus) ([]byte, error) {
+	if len(enclaves) == 0 {
+		return nil, fmt.Errorf("node: no enclave identities")
+	}
+
+	var (
+		cs             SGXConstraints
+		seenEnclaves   = make(map[sgx.EnclaveIdentity]bool)
+		seenStatuses   = make(map[ias.ISVEnclaveQuoteStatus]bool)
+		emptyEnclaveID sgx.EnclaveIdentity
+	)
+	for _, eid := range enclaves {
+		if eid == emptyEnclaveID {
+			return nil, fmt.Errorf("node: empty enclave identity")
 		}
-		if !eidValid {
-			return ErrBadEnclaveIdentity
+		if seenEnclaves[eid] {
+			continue
 		}
-
-		// Ensure that the ISV quote includes the hash of the node's
-		// RAK.
-		var avrRAKHash hash.Hash
-		_ = avrRAKHash.UnmarshalBinary(q.Report.ReportData[:hash.Size])
-		if !rakHash.Equal(&avrRAKHash) {
-			return ErrRAKHashMismatch
+		seenEnclaves[eid] = true
+		cs.Enclaves = append(cs.Enclaves, eid)
+	}
+	sort.Slice(cs.Enclaves, func(i, j int) bool {
+		a, b := cs.Enclaves[i], cs.Enclaves[j]
+		if c := bytes.Compare(a.MrEnclave[:], b.MrEnclave[:]); c != 0 {
+			return c < 0
 		}
+		return bytes.Compare(a.MrSigner[:], b.MrSigner[:]) < 0
+	})
 
-		// Ensure that the quote status is acceptable.
-		if !cs.quoteStatusAllowed(avr) {
-			return ErrConstraintViolation
+	for _, status := range allowedStatuses {
+		if status.String() == "" {
+			return nil, fmt.Errorf("node: invalid quote status: %d", int(status))
 		}
+		if status == ias.QuoteOK || status == ias.QuoteSwHardeningNeeded || seenStatuses[status] {
+			continue
+		}
+		seenStatuses[status] = true
+		cs.AllowedQuoteStatuses = append(cs.AllowedQuoteStatuses, status)
+	}
+	sort.Slice(cs.AllowedQuoteStatuses, func(i, j int) bool {
+		return cs.AllowedQuoteStatuses[i] < cs.AllowedQuoteStatuses[j]
+	})
 
-		// The last 32 bytes of the quote ReportData are deliberately
-		// ignored.
+	return cbor.Marshal(&cs), nil
+}
 
-		return nil
-	default:
-		return ErrInvalidTEEHardware
+// ParseSGXConstraints parses serialized SGX constraints, either bare or wrapped in TEEConstraints.
+func ParseSGXConstraints(raw []byte) (*SGXConstraints, error) {
+	tc, err := DecodeTEEConstraints(raw, TEEHardwareIntelSGX)
+	if err != nil {
+		return nil, err
 	}
+	return tc.SGX, nil
 }
 
 // String returns a string representation of itself.
@@ -595,16 +1488,255 @@ func (n *Node) String() string {
 	return "<Node id=" + n.ID.String() + ">"
 }
 
+// prettyRAKLength is the number of characters of the RAK shown when pretty printing.
+const prettyRAKLength = 8
+
+// PrettyPrint writes a pretty-printed representation of the node descriptor
+// to the given writer.
+func (n *Node) PrettyPrint(ctx context.Context, prefix string, w io.Writer) {
+	fmt.Fprintf(w, "%sID:         %s\n", prefix, n.ID)
+	fmt.Fprintf(w, "%sEntity ID:  %s\n", prefix, n.EntityID)
+	fmt.Fprintf(w, "%sExpiration: epoch %d\n", prefix, n.Expiration)
+	fmt.Fprintf(w, "%sRoles:      %s\n", prefix, n.Roles)
+	if n.SoftwareVersion != "" {
+		fmt.Fprintf(w, "%sSoftware:   %s\n", prefix, n.SoftwareVersion)
+	}
+
+	if len(n.Runtimes) == 0 {
+		fmt.Fprintf(w, "%sRuntimes:   (none)\n", prefix)
+		return
+	}
+	fmt.Fprintf(w, "%sRuntimes:\n", prefix)
+	for _, rt := range n.Runtimes {
+		fmt.Fprintf(w, "%s  - ID:      %s\n", prefix, rt.ID)
+		fmt.Fprintf(w, "%s    Version: %s\n", prefix, rt.Version)
+		if tee := rt.Capabilities.TEE; tee != nil {
+			rak := tee.RAK.String()
+			if len(rak) > prettyRAKLength {
+				rak = rak[:prettyRAKLength] + "..."
+			}
+			fmt.Fprintf(w, "%s    TEE:     %s (RAK: %s)\n", prefix, tee.Hardware, rak)
+		}
+	}
+}
+
+// PrettyType returns a representation of the node descriptor that can be
+// used for pretty printing.
+func (n *Node) PrettyType() (interface{}, error) {
+	return n, nil
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func cloneAddresses(addrs []Address) []Address {
+	if addrs == nil {
+		return nil
+	}
+	c := make([]Address, 0, len(addrs))
+	for i := range addrs {
+		c = append(c, addrs[i].clone())
+	}
+	return c
+}
+
+func cloneTLSAddresses(addrs []TLSAddress) []TLSAddress {
+	if addrs == nil {
+		return nil
+	}
+	c := make([]TLSAddress, 0, len(addrs))
+	for _, addr := range addrs {
+		addr.Address = addr.Address.clone()
+		c = append(c, addr)
+	}
+	return c
+}
+
+func cloneConsensusAddresses(addrs []ConsensusAddress) []ConsensusAddress {
+	if addrs == nil {
+		return nil
+	}
+	c := make([]ConsensusAddress, 0, len(addrs))
+	for _, addr := range addrs {
+		addr.Address = addr.Address.clone()
+		c = append(c, addr)
+	}
+	return c
+}
+
 // MultiSignedNode is a multi-signed blob containing a CBOR-serialized Node.
 type MultiSignedNode struct {
 	signature.MultiSigned
 }
 
 // Open first verifies the blob signatures and then unmarshals the blob.
+//
+// NOTE: As this is used when processing node registrations in consensus, blobs that are not in
+// canonical CBOR form are still accepted as rejecting them would change consensus rules.
 func (s *MultiSignedNode) Open(context signature.Context, node *Node) error {
 	return s.MultiSigned.Open(context, node)
 }
 
+// OpenWithThreshold first verifies the blob signatures, then checks that
+// at least threshold distinct signers are in the allowed set and then
+// unmarshals the blob. Blobs that are not in canonical CBOR form are
+// rejected.
+//
+// Signature verification failures are reported as signature.ErrVerifyFailed
+// while an insufficient number of allowed signers is reported as
+// ErrSignerThresholdNotMet.
+func (s *MultiSignedNode) OpenWithThreshold(context signature.Context, allowed []signature.PublicKey, threshold int, node *Node) error {
+	if !signature.VerifyManyToOne(context, s.MultiSigned.Blob, s.MultiSigned.Signatures) {
+		return signature.ErrVerifyFailed
+	}
+
+	allowedSet := make(map[signature.PublicKey]bool, len(allowed))
+	for _, pk := range allowed {
+		allowedSet[pk] = true
+	}
+	signers := make(map[signature.PublicKey]bool)
+	for _, sig := range s.MultiSigned.Signatures {
+		if allowedSet[sig.PublicKey] {
+			signers[sig.PublicKey] = true
+		}
+	}
+	if len(signers) < threshold {
+		return fmt.Errorf("%w: %d of %d required signers", ErrSignerThresholdNotMet, len(signers), threshold)
+	}
+
+	return cbor.UnmarshalCanonical(s.MultiSigned.Blob, node)
+}
+
+// Signers returns the public keys of all signatures present on the
+// descriptor, in order.
+//
+// NOTE: This does NOT verify the signatures nor decode the blob, so the
+// returned keys must only be used as untrusted metadata (e.g., to pre-filter
+// descriptors before doing full verification via Open).
+func (s *MultiSignedNode) Signers() []signature.PublicKey {
+	signers := make([]signature.PublicKey, 0, len(s.MultiSigned.Signatures))
+	for _, sig := range s.MultiSigned.Signatures {
+		signers = append(signers, sig.PublicKey)
+	}
+	return signers
+}
+
+// OpenBatch verifies the signatures of multiple multi-signed node
+// descriptors using a single batch verification and then unmarshals them.
+//
+// In case batch verification fails, the signatures are verified one by one
+// so that the returned error identifies the offending descriptor and signer.
+// Blobs that are not in canonical CBOR form are rejected.
+func OpenBatch(context signature.Context, signed []*MultiSignedNode) ([]*Node, error) {
+	if len(signed) == 0 {
+		return nil, nil
+	}
+
+	var (
+		messages [][]byte
+		sigs     []signature.Signature
+	)
+	for i, s := range signed {
+		if len(s.MultiSigned.Signatures) == 0 {
+			return nil, fmt.Errorf("%w: descriptor %d is not signed", signature.ErrVerifyFailed, i)
+		}
+		for _, sig := range s.MultiSigned.Signatures {
+			messages = append(messages, s.MultiSigned.Blob)
+			sigs = append(sigs, sig)
+		}
+	}
+
+	if !signature.VerifyBatch(context, messages, sigs) {
+		// Identify the bad signature.
+		for i, s := range signed {
+			for _, sig := range s.MultiSigned.Signatures {
+				if !sig.PublicKey.Verify(context, s.MultiSigned.Blob, sig.Signature[:]) {
+					return nil, fmt.Errorf("%w: descriptor %d signed by %s", signature.ErrVerifyFailed, i, sig.PublicKey)
+				}
+			}
+		}
+		return nil, signature.ErrVerifyFailed
+	}
+
+	nodes := make([]*Node, 0, len(signed))
+	for i, s := range signed {
+		var n Node
+		if err := cbor.UnmarshalCanonical(s.MultiSigned.Blob, &n); err != nil {
+			return nil, fmt.Errorf("node: malformed descriptor %d: %w", i, err)
+		}
+		nodes = append(nodes, &n)
+	}
+	return nodes, nil
+}
+
+// VerifyNodes opens multiple multi-signed node descriptors and verifies
+// the TEE capabilities of their runtimes against the given per-runtime
+// constraints, at the provided timestamp.
+//
+// The returned slices are parallel to the given descriptors. For each
+// descriptor either the decoded node or the verification error is set so
+// that a single invalid descriptor does not affect the others. The
+// descriptors are verified in parallel using a bounded number of workers.
+func VerifyNodes(
+	context signature.Context,
+	ts time.Time,
+	constraints map[common.Namespace][]byte,
+	signed []*MultiSignedNode,
+) ([]*Node, []error) {
+	nodes := make([]*Node, len(signed))
+	errs := make([]error, len(signed))
+
+	verifyNode := func(i int) {
+		var n Node
+		if err := signed[i].Open(context, &n); err != nil {
+			errs[i] = err
+			return
+		}
+		for _, rt := range n.Runtimes {
+			if rt.Capabilities.TEE == nil {
+				continue
+			}
+			cs, ok := constraints[rt.ID]
+			if !ok {
+				errs[i] = fmt.Errorf("node: no TEE constraints for runtime %s", rt.ID)
+				return
+			}
+			if err := rt.Capabilities.TEE.Verify(ts, cs); err != nil {
+				errs[i] = fmt.Errorf("node: failed to verify TEE capability for runtime %s: %w", rt.ID, err)
+				return
+			}
+		}
+		nodes[i] = &n
+	}
+
+	numWorkers := runtime.GOMAXPROCS(0)
+	if numWorkers > len(signed) {
+		numWorkers = len(signed)
+	}
+	indexCh := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				verifyNode(i)
+			}
+		}()
+	}
+	for i := range signed {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	return nodes, errs
+}
+
 // PrettyPrint writes a pretty-printed representation of the type
 // to the given writer.
 func (s MultiSignedNode) PrettyPrint(ctx context.Context, prefix string, w io.Writer) {
@@ -620,15 +1752,20 @@ func (s MultiSignedNode) PrettyPrint(ctx context.Context, prefix string, w io.Wr
 // PrettyType returns a representation of the type that can be used for pretty printing.
 func (s MultiSignedNode) PrettyType() (interface{}, error) {
 	var n Node
-	if err := cbor.Unmarshal(s.MultiSigned.Blob, &n); err != nil {
+	if err := cbor.UnmarshalCanonical(s.MultiSigned.Blob, &n); err != nil {
 		return nil, fmt.Errorf("malformed signed blob: %w", err)
 	}
 	return signature.NewPrettyMultiSigned(s.MultiSigned, n)
 }
 
-// MultiSignNode serializes the Node and multi-signs the result.
+// MultiSignNode normalizes and serializes the Node and multi-signs the result.
+//
+// Normalization is performed on a copy so the given node is not modified.
 func MultiSignNode(signers []signature.Signer, context signature.Context, node *Node) (*MultiSignedNode, error) {
-	multiSigned, err := signature.SignMultiSigned(signers, context, node)
+	normalized := node.Clone()
+	normalized.Normalize()
+
+	multiSigned, err := signature.SignMultiSigned(signers, context, normalized)
 	if err != nil {
 		return nil, err
 	}
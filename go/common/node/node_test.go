@@ -1,15 +1,79 @@
 package node
 
 import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/sgx"
+	"github.com/oasisprotocol/oasis-core/go/common/sgx/ias"
 	"github.com/oasisprotocol/oasis-core/go/common/version"
 )
 
+// newTestCapabilityTEE generates a mock SGX TEE capability for the given enclave identity with the
+// given report data.
+//
+// Note: This disables AVR signature verification for the remainder of the test process.
+func newTestCapabilityTEE(t *testing.T, rak signature.PublicKey, eid sgx.EnclaveIdentity, reportData [64]byte) *CapabilityTEE {
+	require := require.New(t)
+
+	ias.SetSkipVerify()
+
+	q := ias.Quote{
+		Body: ias.Body{
+			Version: 2,
+		},
+		Report: ias.Report{
+			MRENCLAVE:  eid.MrEnclave,
+			MRSIGNER:   eid.MrSigner,
+			ReportData: reportData,
+		},
+	}
+	rawQuote, err := q.MarshalBinary()
+	require.NoError(err, "Quote.MarshalBinary")
+	avr, err := ias.NewMockAVR(rawQuote, "")
+	require.NoError(err, "NewMockAVR")
+
+	return &CapabilityTEE{
+		Hardware: TEEHardwareIntelSGX,
+		RAK:      rak,
+		Attestation: cbor.Marshal(ias.AVRBundle{
+			Body: avr,
+		}),
+	}
+}
+
+// newTestRAKReportData generates report data that binds the given RAK.
+func newTestRAKReportData(rak signature.PublicKey) (reportData [64]byte) {
+	rakHash := RAKHash(rak)
+	copy(reportData[:], rakHash[:])
+	return
+}
+
+// newTestEnclaveIdentity generates a deterministic enclave identity from the given seed.
+func newTestEnclaveIdentity(seed byte) (eid sgx.EnclaveIdentity) {
+	for i := range eid.MrEnclave {
+		eid.MrEnclave[i] = seed
+	}
+	for i := range eid.MrSigner {
+		eid.MrSigner[i] = seed + 1
+	}
+	return
+}
+
 func TestRolesMask(t *testing.T) {
 	require := require.New(t)
 
@@ -81,6 +145,73 @@ func TestRolesMask(t *testing.T) {
 	}
 }
 
+func TestRolesMaskValidate(t *testing.T) {
+	require := require.New(t)
+
+	for _, m := range []RolesMask{
+		0,
+		RoleComputeWorker,
+		RoleComputeWorker | RoleKeyManager | RoleValidator | RoleConsensusRPC | RoleStorageRPC,
+	} {
+		require.NoError(m.Validate(), "roles mask without reserved roles should be valid")
+	}
+
+	for _, m := range []RolesMask{
+		1 << 31,
+		RoleComputeWorker | 1<<31,
+		roleReserved2,
+		RoleReserved,
+	} {
+		require.ErrorIs(m.Validate(), ErrInvalidRole, "roles mask with reserved roles should be invalid")
+	}
+}
+
+func TestRolesMaskArray(t *testing.T) {
+	require := require.New(t)
+
+	testVectors := []struct {
+		json      string
+		rolesMask RolesMask
+		canonical string
+		errMsg    string
+	}{
+		// Valid.
+		{`[]`, 0, `[]`, ""},
+		{`["compute"]`, RoleComputeWorker, `["compute"]`, ""},
+		{`["compute","validator"]`, RoleComputeWorker | RoleValidator, `["compute","validator"]`, ""},
+		// Valid - non-canonical order.
+		{`["storage-rpc","compute"]`, RoleComputeWorker | RoleStorageRPC, `["compute","storage-rpc"]`, ""},
+		// Invalid.
+		{`["master"]`, 0, "", "node: invalid role: 'master'"},
+		{`["compute,validator"]`, 0, "", "node: invalid role: 'compute,validator'"},
+		{`["compute","compute"]`, 0, "", "node: duplicate role: 'compute'"},
+		{`"compute"`, 0, "", "json: cannot unmarshal string into Go value of type []string"},
+	}
+
+	for _, v := range testVectors {
+		var m RolesMaskArray
+		err := json.Unmarshal([]byte(v.json), &m)
+		if v.errMsg != "" {
+			require.EqualError(err, v.errMsg, "unmarshaling invalid roles array: %s", v.json)
+			continue
+		}
+		require.NoError(err, "unmarshaling valid roles array: %s", v.json)
+		require.Equal(v.rolesMask, RolesMask(m))
+
+		data, err := json.Marshal(m)
+		require.NoError(err, "marshaling roles array")
+		require.Equal(v.canonical, string(data), "marshaled roles array should be canonical")
+	}
+
+	_, err := json.Marshal(RolesMaskArray(RoleReserved))
+	require.Error(err, "marshaling reserved roles should fail")
+
+	// The text representation should be unaffected.
+	data, err := json.Marshal(RoleComputeWorker | RoleValidator)
+	require.NoError(err, "marshaling roles mask")
+	require.Equal(`"compute,validator"`, string(data))
+}
+
 func TestNodeDescriptor(t *testing.T) {
 	require := require.New(t)
 
@@ -129,6 +260,26 @@ func TestReservedRoles(t *testing.T) {
 	require.Error(err, "ValidateBasic should fail for empty roles")
 }
 
+func TestBuildRoleIndex(t *testing.T) {
+	require := require.New(t)
+
+	compute := &Node{Roles: RoleComputeWorker}
+	multi := &Node{Roles: RoleComputeWorker | RoleValidator}
+	reserved := &Node{Roles: RoleKeyManager | roleReserved2 | 1<<31}
+	onlyReserved := &Node{Roles: RoleReserved}
+
+	index := BuildRoleIndex([]*Node{compute, multi, reserved, onlyReserved})
+	require.Equal([]*Node{compute, multi}, index[RoleComputeWorker])
+	require.Equal([]*Node{multi}, index[RoleValidator], "multi-role nodes should appear under each role")
+	require.Equal([]*Node{reserved}, index[RoleKeyManager])
+	require.Len(index, 3, "only roles with nodes should be indexed")
+	for role := range index {
+		require.True(role.IsSingleRole(), "index should only contain valid single roles")
+	}
+
+	require.Empty(BuildRoleIndex(nil), "empty node list should produce an empty index")
+}
+
 func TestNodeDescriptorV1(t *testing.T) {
 	require := require.New(t)
 
@@ -161,3 +312,1087 @@ func TestNodeDescriptorV1(t *testing.T) {
 	require.True(v2.HasRoles(RoleComputeWorker))
 	require.False(v2.HasRoles(roleReserved2))
 }
+
+func TestSGXConstraintsFromCapability(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("sgx constraints from capability").Public()
+	eid := newTestEnclaveIdentity(42)
+	capTEE := newTestCapabilityTEE(t, rak, eid, newTestRAKReportData(rak))
+
+	cs, err := SGXConstraintsFromCapability(capTEE, time.Now(), []ias.ISVEnclaveQuoteStatus{ias.QuoteGroupOutOfDate})
+	require.NoError(err, "SGXConstraintsFromCapability")
+	require.Len(cs.Enclaves, 1)
+	require.EqualValues(eid, cs.Enclaves[0])
+	require.EqualValues([]ias.ISVEnclaveQuoteStatus{ias.QuoteGroupOutOfDate}, cs.AllowedQuoteStatuses)
+
+	// The derived constraints should allow the capability.
+	err = capTEE.Verify(time.Now(), cbor.Marshal(cs))
+	require.NoError(err, "Verify with derived constraints")
+
+	// Invalid hardware.
+	invalidTEE := *capTEE
+	invalidTEE.Hardware = TEEHardwareInvalid
+	_, err = SGXConstraintsFromCapability(&invalidTEE, time.Now(), nil)
+	require.ErrorIs(err, ErrInvalidTEEHardware)
+
+	// RAK not bound by the attestation.
+	otherTEE := *capTEE
+	otherTEE.RAK = memorySigner.NewTestSigner("sgx constraints from capability: other").Public()
+	_, err = SGXConstraintsFromCapability(&otherTEE, time.Now(), nil)
+	require.ErrorIs(err, ErrRAKHashMismatch)
+}
+
+func TestNewSGXConstraints(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("new sgx constraints").Public()
+	eid1, eid2 := newTestEnclaveIdentity(42), newTestEnclaveIdentity(1)
+	capTEE := newTestCapabilityTEE(t, rak, eid1, newTestRAKReportData(rak))
+
+	raw, err := NewSGXConstraints(
+		[]sgx.EnclaveIdentity{eid1, eid2, eid1},
+		[]ias.ISVEnclaveQuoteStatus{ias.QuoteGroupOutOfDate, ias.QuoteOK, ias.QuoteGroupOutOfDate, ias.QuoteSwHardeningNeeded},
+	)
+	require.NoError(err, "NewSGXConstraints")
+
+	cs, err := ParseSGXConstraints(raw)
+	require.NoError(err, "ParseSGXConstraints")
+	require.EqualValues([]sgx.EnclaveIdentity{eid2, eid1}, cs.Enclaves, "enclaves should be deduplicated and sorted")
+	require.EqualValues([]ias.ISVEnclaveQuoteStatus{ias.QuoteGroupOutOfDate}, cs.AllowedQuoteStatuses, "implicitly allowed statuses should be omitted")
+	require.Nil(cs.ReportDataExtra)
+
+	// The encoding should not depend on the input order.
+	raw2, err := NewSGXConstraints([]sgx.EnclaveIdentity{eid2, eid1}, []ias.ISVEnclaveQuoteStatus{ias.QuoteGroupOutOfDate})
+	require.NoError(err, "NewSGXConstraints")
+	require.EqualValues(raw, raw2, "encoding should be canonical")
+
+	// The constraints should allow the capability.
+	err = capTEE.Verify(time.Now(), raw)
+	require.NoError(err, "Verify with built constraints")
+
+	// Wrapped constraints should also be accepted.
+	cs, err = ParseSGXConstraints(cbor.Marshal(&TEEConstraints{Hardware: TEEHardwareIntelSGX, SGX: cs}))
+	require.NoError(err, "ParseSGXConstraints wrapped")
+	require.EqualValues([]sgx.EnclaveIdentity{eid2, eid1}, cs.Enclaves)
+
+	_, err = ParseSGXConstraints(cbor.Marshal(&TEEConstraints{Hardware: TEEHardwareInvalid}))
+	require.ErrorIs(err, ErrTEEHardwareMismatch, "ParseSGXConstraints should reject non-SGX constraints")
+	_, err = ParseSGXConstraints([]byte("invalid"))
+	require.Error(err, "ParseSGXConstraints should reject malformed constraints")
+
+	// Invalid inputs.
+	_, err = NewSGXConstraints(nil, nil)
+	require.Error(err, "NewSGXConstraints should require enclave identities")
+	_, err = NewSGXConstraints([]sgx.EnclaveIdentity{eid1, {}}, nil)
+	require.Error(err, "NewSGXConstraints should reject empty enclave identities")
+	_, err = NewSGXConstraints([]sgx.EnclaveIdentity{eid1}, []ias.ISVEnclaveQuoteStatus{ias.ISVEnclaveQuoteStatus(0)})
+	require.Error(err, "NewSGXConstraints should reject invalid quote statuses")
+}
+
+func TestCheckReportDataLayout(t *testing.T) {
+	require := require.New(t)
+
+	signer := memorySigner.NewTestSigner("node test: CheckReportDataLayout")
+	rak := signer.Public()
+
+	// Correct report data.
+	reportData := newTestRAKReportData(rak)
+	require.NoError(CheckReportDataLayout(reportData, rak), "correct report data should be accepted")
+
+	// The last 32 bytes are ignored.
+	for i := 32; i < len(reportData); i++ {
+		reportData[i] = 0xff
+	}
+	require.NoError(CheckReportDataLayout(reportData, rak), "caller-defined bytes should be ignored")
+
+	// Tampered report data.
+	reportData[0] ^= 0xff
+	require.ErrorIs(CheckReportDataLayout(reportData, rak), ErrRAKHashMismatch, "tampered report data should be rejected")
+
+	// Report data bound to a different RAK.
+	otherRAK := memorySigner.NewTestSigner("node test: CheckReportDataLayout other").Public()
+	require.ErrorIs(CheckReportDataLayout(newTestRAKReportData(otherRAK), rak), ErrRAKHashMismatch, "report data for other RAK should be rejected")
+}
+
+func TestCheckReportDataLayoutWithExtra(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: CheckReportDataLayoutWithExtra").Public()
+	extra := hash.NewFromBytes([]byte("node test: CheckReportDataLayoutWithExtra extra"))
+	otherExtra := hash.NewFromBytes([]byte("node test: CheckReportDataLayoutWithExtra other extra"))
+
+	reportData := newTestRAKReportData(rak)
+	copy(reportData[hash.Size:], extra[:])
+
+	// Ignore the trailing bytes by default.
+	require.NoError(CheckReportDataLayoutWithExtra(reportData, rak, nil), "trailing bytes should be ignored")
+
+	// Enforce the trailing bytes when required.
+	require.NoError(CheckReportDataLayoutWithExtra(reportData, rak, &extra), "matching trailing bytes should be accepted")
+	require.ErrorIs(CheckReportDataLayoutWithExtra(reportData, rak, &otherExtra), ErrReportDataMismatch, "mismatched trailing bytes should be rejected")
+
+	// The RAK binding is always enforced.
+	otherRAK := memorySigner.NewTestSigner("node test: CheckReportDataLayoutWithExtra other").Public()
+	require.ErrorIs(CheckReportDataLayoutWithExtra(reportData, otherRAK, &extra), ErrRAKHashMismatch)
+
+	// Verification should enforce the trailing bytes iff they are set in the constraints.
+	eid := newTestEnclaveIdentity(44)
+	capTEE := newTestCapabilityTEE(t, rak, eid, reportData)
+	now := time.Now()
+	cs := SGXConstraints{Enclaves: []sgx.EnclaveIdentity{eid}}
+	require.NoError(capTEE.Verify(now, cbor.Marshal(cs)), "trailing bytes should be ignored by default")
+	cs.ReportDataExtra = &extra
+	require.NoError(capTEE.Verify(now, cbor.Marshal(cs)), "matching trailing bytes should be accepted")
+	cs.ReportDataExtra = &otherExtra
+	require.ErrorIs(capTEE.Verify(now, cbor.Marshal(cs)), ErrReportDataMismatch, "mismatched trailing bytes should be rejected")
+}
+
+func TestCanBackupResolve(t *testing.T) {
+	require := require.New(t)
+
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("node test: CanBackupResolve"), 0)
+	otherRuntimeID := common.NewTestNamespaceFromSeed([]byte("node test: CanBackupResolve other"), 0)
+	rak := memorySigner.NewTestSigner("node test: CanBackupResolve").Public()
+	eid := newTestEnclaveIdentity(42)
+	capTEE := newTestCapabilityTEE(t, rak, eid, newTestRAKReportData(rak))
+	constraints := cbor.Marshal(SGXConstraints{
+		Enclaves:             []sgx.EnclaveIdentity{eid},
+		AllowedQuoteStatuses: []ias.ISVEnclaveQuoteStatus{ias.QuoteGroupOutOfDate},
+	})
+	otherConstraints := cbor.Marshal(SGXConstraints{
+		Enclaves: []sgx.EnclaveIdentity{newTestEnclaveIdentity(43)},
+	})
+	now := time.Now()
+
+	// Eligible compute node without a TEE.
+	n := &Node{Roles: RoleComputeWorker}
+	n.AddOrUpdateRuntime(runtimeID, version.Version{Major: 1})
+	require.True(n.CanBackupResolve(runtimeID, now, nil), "compute node should be eligible")
+	require.False(n.CanBackupResolve(otherRuntimeID, now, nil), "unsupported runtime should not be eligible")
+	require.False(n.CanBackupResolve(runtimeID, now, constraints), "node without a TEE should not be eligible")
+
+	// Eligible compute node with a TEE.
+	rt := n.AddOrUpdateRuntime(runtimeID, version.Version{Major: 2})
+	rt.Capabilities.TEE = capTEE
+	require.True(n.CanBackupResolve(runtimeID, now, constraints), "node with a valid TEE should be eligible")
+	require.False(n.CanBackupResolve(runtimeID, now, otherConstraints), "node with a different enclave should not be eligible")
+
+	// Nodes that are not compute workers are never eligible.
+	n.Roles = RoleKeyManager | RoleStorageRPC
+	require.False(n.CanBackupResolve(runtimeID, now, nil), "non-compute node should not be eligible")
+	require.False(n.CanBackupResolve(runtimeID, now, constraints), "non-compute node should not be eligible")
+}
+
+func TestSGXConstraintsWithEnclave(t *testing.T) {
+	require := require.New(t)
+
+	eid1, eid2, eid3 := newTestEnclaveIdentity(1), newTestEnclaveIdentity(2), newTestEnclaveIdentity(3)
+	cs := &SGXConstraints{
+		Enclaves:             []sgx.EnclaveIdentity{eid1, eid2},
+		AllowedQuoteStatuses: []ias.ISVEnclaveQuoteStatus{ias.QuoteGroupOutOfDate},
+	}
+	orig := cbor.Marshal(cs)
+
+	added := cs.WithAddedEnclave(eid3)
+	require.EqualValues([]sgx.EnclaveIdentity{eid1, eid2, eid3}, added.Enclaves)
+	require.EqualValues(cs.AllowedQuoteStatuses, added.AllowedQuoteStatuses)
+	require.EqualValues(orig, cbor.Marshal(cs), "original constraints should be unchanged")
+
+	// Adding an already allowed enclave should not duplicate it.
+	require.EqualValues(cs.Enclaves, cs.WithAddedEnclave(eid1).Enclaves)
+
+	removed := cs.WithRemovedEnclave(eid1)
+	require.EqualValues([]sgx.EnclaveIdentity{eid2}, removed.Enclaves)
+	require.EqualValues(cs.AllowedQuoteStatuses, removed.AllowedQuoteStatuses)
+	require.EqualValues(orig, cbor.Marshal(cs), "original constraints should be unchanged")
+
+	// Removing an enclave that is not allowed should be a no-op.
+	require.EqualValues(cs.Enclaves, cs.WithRemovedEnclave(eid3).Enclaves)
+
+	// Results should not share state with the original.
+	added.Enclaves[0] = eid3
+	added.AllowedQuoteStatuses[0] = ias.QuoteConfigurationNeeded
+	removed.Enclaves[0] = eid3
+	require.EqualValues(orig, cbor.Marshal(cs), "original constraints should be unchanged")
+}
+
+func TestIdentityFingerprint(t *testing.T) {
+	require := require.New(t)
+
+	newKey := func(seed string) signature.PublicKey {
+		return memorySigner.NewTestSigner("node test: IdentityFingerprint " + seed).Public()
+	}
+	n := &Node{
+		ID:        newKey("node"),
+		TLS:       TLSInfo{PubKey: newKey("tls")},
+		P2P:       P2PInfo{ID: newKey("p2p")},
+		Consensus: ConsensusInfo{ID: newKey("consensus")},
+	}
+	fp := n.IdentityFingerprint()
+	require.Len(fp, 2*identityFingerprintSize, "fingerprint should be short")
+	require.Equal(fp, n.IdentityFingerprint(), "fingerprint should be deterministic")
+
+	// Non-identity updates should preserve the fingerprint.
+	n.Expiration = 42
+	n.P2P.Addresses = []Address{{net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000}}}
+	n.TLS.NextPubKey = newKey("tls next")
+	n.Roles = RoleComputeWorker
+	require.Equal(fp, n.IdentityFingerprint(), "non-identity updates should preserve the fingerprint")
+
+	// Identity updates should alter the fingerprint.
+	for _, update := range []func(n *Node){
+		func(n *Node) { n.ID = newKey("other node") },
+		func(n *Node) { n.TLS.PubKey = newKey("other tls") },
+		func(n *Node) { n.P2P.ID = newKey("other p2p") },
+		func(n *Node) { n.Consensus.ID = newKey("other consensus") },
+	} {
+		updated := *n
+		update(&updated)
+		require.NotEqual(fp, updated.IdentityFingerprint(), "identity updates should alter the fingerprint")
+	}
+}
+
+func TestMultiSignedNodeOpenWithThreshold(t *testing.T) {
+	require := require.New(t)
+
+	ctx := signature.NewContext("oasis-core/node: test multi-signed node threshold")
+	signerA := memorySigner.NewTestSigner("node test: OpenWithThreshold A")
+	signerB := memorySigner.NewTestSigner("node test: OpenWithThreshold B")
+	signerC := memorySigner.NewTestSigner("node test: OpenWithThreshold C")
+	allowed := []signature.PublicKey{signerA.Public(), signerB.Public()}
+
+	n := &Node{
+		Versioned: cbor.NewVersioned(LatestNodeDescriptorVersion),
+		ID:        signerA.Public(),
+	}
+	signed, err := MultiSignNode([]signature.Signer{signerA, signerB, signerC}, ctx, n)
+	require.NoError(err, "MultiSignNode")
+
+	var opened Node
+	err = signed.OpenWithThreshold(ctx, allowed, 2, &opened)
+	require.NoError(err, "OpenWithThreshold should succeed when the threshold is met")
+	require.EqualValues(n.ID, opened.ID)
+
+	err = signed.OpenWithThreshold(ctx, allowed, 3, &opened)
+	require.ErrorIs(err, ErrSignerThresholdNotMet, "signers outside the allowed set should not count")
+
+	// Duplicate signatures by the same signer should only count once.
+	signed.Signatures = append(signed.Signatures, signed.Signatures[0])
+	err = signed.OpenWithThreshold(ctx, []signature.PublicKey{signerA.Public()}, 2, &opened)
+	require.ErrorIs(err, ErrSignerThresholdNotMet, "duplicate signers should only count once")
+
+	// Invalid signatures should be reported as such.
+	signed.Signatures[0].Signature[0] ^= 0xff
+	err = signed.OpenWithThreshold(ctx, allowed, 1, &opened)
+	require.ErrorIs(err, signature.ErrVerifyFailed, "invalid signatures should fail verification")
+}
+
+func TestMultiSignedNodeSigners(t *testing.T) {
+	require := require.New(t)
+
+	ctx := signature.NewContext("oasis-core/node: test multi-signed node signers")
+	signerA := memorySigner.NewTestSigner("node test: Signers A")
+	signerB := memorySigner.NewTestSigner("node test: Signers B")
+
+	n := &Node{
+		Versioned: cbor.NewVersioned(LatestNodeDescriptorVersion),
+		ID:        signerA.Public(),
+	}
+	signed, err := MultiSignNode([]signature.Signer{signerA, signerB}, ctx, n)
+	require.NoError(err, "MultiSignNode")
+	require.Equal([]signature.PublicKey{signerA.Public(), signerB.Public()}, signed.Signers())
+
+	// Signers should not verify signatures nor decode the blob.
+	signed.Signatures[0].Signature[0] ^= 0xff
+	signed.Blob = []byte("not a node")
+	require.Equal([]signature.PublicKey{signerA.Public(), signerB.Public()}, signed.Signers())
+}
+
+func TestNodeNormalize(t *testing.T) {
+	require := require.New(t)
+
+	ctx := signature.NewContext("oasis-core/node: test node normalize")
+	signer := memorySigner.NewTestSigner("node test: Normalize")
+	rtA := common.NewTestNamespaceFromSeed([]byte("node test: Normalize A"), 0)
+	rtB := common.NewTestNamespaceFromSeed([]byte("node test: Normalize B"), 0)
+	v1 := version.Version{Major: 1}
+	v2 := version.Version{Major: 2}
+
+	newNode := func() *Node {
+		return &Node{
+			Versioned: cbor.NewVersioned(LatestNodeDescriptorVersion),
+			ID:        signer.Public(),
+		}
+	}
+
+	// Build the same descriptor two ways.
+	n1 := newNode()
+	n1.AddOrUpdateRuntime(rtA, v1)
+	n1.AddOrUpdateRuntime(rtA, v2)
+	n1.AddOrUpdateRuntime(rtB, v1)
+
+	n2 := newNode()
+	n2.Runtimes = []*Runtime{
+		{ID: rtB, Version: v1},
+		{ID: rtA, Version: v2},
+		{ID: rtA, Version: v1},
+		{ID: rtB, Version: v1},
+	}
+
+	signed1, err := MultiSignNode([]signature.Signer{signer}, ctx, n1)
+	require.NoError(err, "MultiSignNode")
+	signed2, err := MultiSignNode([]signature.Signer{signer}, ctx, n2)
+	require.NoError(err, "MultiSignNode")
+	require.EqualValues(signed1, signed2, "signed descriptors should be identical")
+	var opened Node
+	require.NoError(signed2.Open(ctx, &opened), "Open")
+	require.Len(opened.Runtimes, 3, "duplicate runtimes should be removed")
+
+	// The caller's descriptor should not be modified.
+	require.Len(n2.Runtimes, 4, "MultiSignNode should not modify the given descriptor")
+	require.Equal(rtB, n2.Runtimes[0].ID, "MultiSignNode should not reorder the given descriptor")
+
+	// Normalization should be idempotent.
+	sorted := append([]*Runtime{}, n1.Runtimes...)
+	n1.Normalize()
+	require.EqualValues(sorted, n1.Runtimes, "sorted descriptors should be unaffected")
+}
+
+func TestNodeTEERuntimes(t *testing.T) {
+	require := require.New(t)
+
+	rtA := common.NewTestNamespaceFromSeed([]byte("node test: TEERuntimes A"), 0)
+	rtB := common.NewTestNamespaceFromSeed([]byte("node test: TEERuntimes B"), 0)
+	rtC := common.NewTestNamespaceFromSeed([]byte("node test: TEERuntimes C"), 0)
+	rtD := common.NewTestNamespaceFromSeed([]byte("node test: TEERuntimes D"), 0)
+	sorted := func(ids ...common.Namespace) []common.Namespace {
+		sortNamespaces(ids)
+		return ids
+	}
+	tee := Capabilities{TEE: &CapabilityTEE{Hardware: TEEHardwareIntelSGX}}
+
+	var n Node
+	require.Empty(n.TEERuntimes(), "TEERuntimes should be empty without runtimes")
+	require.Empty(n.NonTEERuntimes(), "NonTEERuntimes should be empty without runtimes")
+
+	n.Runtimes = []*Runtime{
+		{ID: rtD, Version: version.Version{Major: 1}, Capabilities: tee},
+		{ID: rtC, Version: version.Version{Major: 1}},
+		{ID: rtB, Version: version.Version{Major: 1}, Capabilities: tee},
+		{ID: rtA, Version: version.Version{Major: 1}},
+		// A runtime is considered to use a TEE if any of its versions does.
+		{ID: rtC, Version: version.Version{Major: 2}, Capabilities: tee},
+		{ID: rtA, Version: version.Version{Major: 2}},
+	}
+	require.EqualValues(sorted(rtB, rtC, rtD), n.TEERuntimes())
+	require.EqualValues(sorted(rtA), n.NonTEERuntimes())
+}
+
+func TestNodeRuntimeIDs(t *testing.T) {
+	require := require.New(t)
+
+	rtA := common.NewTestNamespaceFromSeed([]byte("node test: RuntimeIDs A"), 0)
+	rtB := common.NewTestNamespaceFromSeed([]byte("node test: RuntimeIDs B"), 0)
+	rtC := common.NewTestNamespaceFromSeed([]byte("node test: RuntimeIDs C"), 0)
+
+	var n Node
+	require.Empty(n.RuntimeIDs(), "RuntimeIDs should be empty without runtimes")
+	require.False(n.SupportsRuntime(rtA, version.Version{Major: 1}))
+
+	n.AddOrUpdateRuntime(rtB, version.Version{Major: 1})
+	n.AddOrUpdateRuntime(rtA, version.Version{Major: 1})
+	n.AddOrUpdateRuntime(rtB, version.Version{Major: 2})
+
+	expected := []common.Namespace{rtA, rtB}
+	sortNamespaces(expected)
+	require.EqualValues(expected, n.RuntimeIDs(), "RuntimeIDs should be sorted and deduplicated")
+	require.True(n.SupportsRuntime(rtA, version.Version{Major: 1}))
+	require.False(n.SupportsRuntime(rtA, version.Version{Major: 2}), "other versions should not be supported")
+	require.True(n.SupportsRuntime(rtB, version.Version{Major: 1}))
+	require.True(n.SupportsRuntime(rtB, version.Version{Major: 2}))
+	require.False(n.SupportsRuntime(rtC, version.Version{Major: 1}))
+}
+
+func TestTLSInfoRotation(t *testing.T) {
+	require := require.New(t)
+
+	current := memorySigner.NewTestSigner("node test: TLS current").Public()
+	next := memorySigner.NewTestSigner("node test: TLS next").Public()
+	other := memorySigner.NewTestSigner("node test: TLS other").Public()
+
+	// Not rotating.
+	tls := TLSInfo{PubKey: current}
+	require.False(tls.IsRotating(), "empty next public key should not be rotating")
+	require.True(tls.IsValidPubKey(current))
+	require.False(tls.IsValidPubKey(next))
+	require.False(tls.IsValidPubKey(signature.PublicKey{}), "all-zero key should not be accepted")
+
+	// Rotating.
+	tls.NextPubKey = next
+	require.True(tls.IsRotating())
+	require.True(tls.IsValidPubKey(current))
+	require.True(tls.IsValidPubKey(next))
+	require.False(tls.IsValidPubKey(other))
+}
+
+func TestIsExpiredWithGrace(t *testing.T) {
+	require := require.New(t)
+
+	n := &Node{Expiration: 10}
+	require.False(n.IsExpired(10))
+	require.True(n.IsExpired(11))
+
+	require.False(n.IsExpiredWithGrace(11, 1), "node should not be expired within the grace period")
+	require.True(n.IsExpiredWithGrace(12, 1), "node should be expired after the grace period")
+	require.Equal(n.IsExpired(11), n.IsExpiredWithGrace(11, 0), "zero grace should be strict")
+
+	// Overflow.
+	n.Expiration = math.MaxUint64 - 1
+	require.False(n.IsExpiredWithGrace(math.MaxUint64, 2), "overflowing expiration should never expire")
+}
+
+func TestNodeClone(t *testing.T) {
+	require := require.New(t)
+
+	rtID := common.NewTestNamespaceFromSeed([]byte("node test: Clone"), 0)
+	n := &Node{
+		Versioned: cbor.NewVersioned(LatestNodeDescriptorVersion),
+		Roles:     RoleComputeWorker,
+		TLS: TLSInfo{
+			Addresses: []TLSAddress{{Address: Address{net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000}}}},
+		},
+		P2P: P2PInfo{
+			Addresses: []Address{{net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9001}}},
+		},
+		Consensus: ConsensusInfo{
+			Addresses: []ConsensusAddress{{Address: Address{net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9002}}}},
+		},
+		VRF:              &VRFInfo{},
+		DeprecatedBeacon: cbor.RawMessage{0x01},
+		Runtimes: []*Runtime{
+			{
+				ID: rtID,
+				Capabilities: Capabilities{
+					TEE: &CapabilityTEE{Hardware: TEEHardwareIntelSGX, Attestation: []byte("attestation")},
+				},
+				ExtraInfo: []byte("extra"),
+			},
+		},
+	}
+	orig := cbor.Marshal(n)
+
+	c := n.Clone()
+	require.EqualValues(n, c, "clone should be equal to the original")
+
+	// Mutating the clone should leave the original unchanged.
+	c.Roles |= RoleValidator
+	c.Runtimes = append(c.Runtimes, &Runtime{ID: rtID, Version: version.Version{Major: 1}})
+	c.Runtimes[0].Capabilities.TEE.Attestation[0] = 'A'
+	c.Runtimes[0].ExtraInfo[0] = 'E'
+	c.TLS.Addresses[0].Address.IP[0] = 10
+	c.P2P.Addresses[0].IP[0] = 10
+	c.Consensus.Addresses[0].Address.IP[0] = 10
+	c.VRF.ID = memorySigner.NewTestSigner("node test: Clone VRF").Public()
+	c.DeprecatedBeacon[0] = 0x02
+	require.EqualValues(orig, cbor.Marshal(n), "mutating the clone should not affect the original")
+}
+
+func TestCheckSoftwareVersion(t *testing.T) {
+	require := require.New(t)
+
+	min := version.Version{Major: 22, Minor: 2}
+	n := &Node{}
+	require.NoError(n.CheckSoftwareVersion(version.Version{}), "zero minimum should disable the check")
+	require.ErrorIs(n.CheckSoftwareVersion(min), ErrBadSoftwareVersion, "empty software version should fail the check")
+
+	for _, v := range []string{"22.2", "22.2.0", "22.10", "22.2.1-rc1", "23.0.0+abcdef"} {
+		n.SoftwareVersion = v
+		require.NoError(n.CheckSoftwareVersion(min), "software version %s should satisfy the minimum", v)
+	}
+	for _, v := range []string{"22.1.9", "21.12", "3.0", "invalid"} {
+		n.SoftwareVersion = v
+		require.ErrorIs(n.CheckSoftwareVersion(min), ErrBadSoftwareVersion, "software version %s should not satisfy the minimum", v)
+	}
+}
+
+func TestMultiSignedNodeNonCanonical(t *testing.T) {
+	require := require.New(t)
+
+	ctx := signature.NewContext("oasis-core/node: test multi-signed node non-canonical")
+	signer := memorySigner.NewTestSigner("node test: non-canonical")
+	n := &Node{
+		Versioned: cbor.NewVersioned(LatestNodeDescriptorVersion),
+		ID:        signer.Public(),
+		Roles:     RoleComputeWorker,
+	}
+	signed, err := MultiSignNode([]signature.Signer{signer}, ctx, n)
+	require.NoError(err, "MultiSignNode")
+
+	var opened Node
+	require.NoError(signed.Open(ctx, &opened), "canonical descriptors should be accepted")
+	_, err = signed.PrettyType()
+	require.NoError(err, "PrettyType")
+
+	// Re-encode the top-level map header using a non-shortest length encoding, which decodes to
+	// the same descriptor.
+	blob := signed.Blob
+	require.True(blob[0] >= 0xa0 && blob[0] < 0xb8, "descriptor should be a small map")
+	nonCanonical := append([]byte{0xb8, blob[0] & 0x1f}, blob[1:]...)
+	require.NoError(cbor.Unmarshal(nonCanonical, &opened), "non-canonical descriptor should decode")
+
+	sig, err := signature.Sign(signer, ctx, nonCanonical)
+	require.NoError(err, "Sign")
+	nonCanonicalSigned := &MultiSignedNode{
+		MultiSigned: signature.MultiSigned{
+			Blob:       nonCanonical,
+			Signatures: []signature.Signature{*sig},
+		},
+	}
+
+	err = nonCanonicalSigned.Open(ctx, &opened)
+	require.NoError(err, "non-canonical descriptors should be accepted by Open")
+	err = nonCanonicalSigned.OpenWithThreshold(ctx, []signature.PublicKey{signer.Public()}, 1, &opened)
+	require.ErrorIs(err, cbor.ErrNonCanonical, "non-canonical descriptors should be rejected")
+	_, err = nonCanonicalSigned.PrettyType()
+	require.ErrorIs(err, cbor.ErrNonCanonical, "non-canonical descriptors should be rejected")
+}
+
+func newTestMultiSignedNodes(t testing.TB, ctx signature.Context, count int) []*MultiSignedNode {
+	signed := make([]*MultiSignedNode, 0, count)
+	for i := 0; i < count; i++ {
+		nodeSigner := memorySigner.NewTestSigner(fmt.Sprintf("node test: OpenBatch node %d", i))
+		entitySigner := memorySigner.NewTestSigner(fmt.Sprintf("node test: OpenBatch entity %d", i))
+		n := &Node{
+			Versioned: cbor.NewVersioned(LatestNodeDescriptorVersion),
+			ID:        nodeSigner.Public(),
+			EntityID:  entitySigner.Public(),
+			Roles:     RoleComputeWorker,
+		}
+		s, err := MultiSignNode([]signature.Signer{nodeSigner, entitySigner}, ctx, n)
+		require.NoError(t, err, "MultiSignNode")
+		signed = append(signed, s)
+	}
+	return signed
+}
+
+func TestOpenBatch(t *testing.T) {
+	require := require.New(t)
+
+	ctx := signature.NewContext("oasis-core/node: test open batch")
+	signed := newTestMultiSignedNodes(t, ctx, 10)
+
+	nodes, err := OpenBatch(ctx, signed)
+	require.NoError(err, "OpenBatch")
+	require.Len(nodes, len(signed))
+	for i, n := range nodes {
+		var expected Node
+		require.NoError(signed[i].Open(ctx, &expected), "Open")
+		require.EqualValues(&expected, n)
+	}
+
+	nodes, err = OpenBatch(ctx, nil)
+	require.NoError(err, "OpenBatch should accept an empty batch")
+	require.Empty(nodes)
+
+	// Unsigned descriptors should be rejected.
+	_, err = OpenBatch(ctx, []*MultiSignedNode{{MultiSigned: signature.MultiSigned{Blob: signed[0].Blob}}})
+	require.ErrorIs(err, signature.ErrVerifyFailed, "OpenBatch should fail on unsigned descriptors")
+
+	// A bad signature should be identified.
+	signed[3].Signatures[1].Signature[0] ^= 0xff
+	_, err = OpenBatch(ctx, signed)
+	require.ErrorIs(err, signature.ErrVerifyFailed, "OpenBatch should fail on bad signatures")
+	require.Contains(err.Error(), fmt.Sprintf("descriptor 3 signed by %s", signed[3].Signatures[1].PublicKey))
+}
+
+func BenchmarkOpenBatch(b *testing.B) {
+	ctx := signature.NewContext("oasis-core/node: benchmark open batch")
+	signed := newTestMultiSignedNodes(b, ctx, 100)
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, s := range signed {
+				var n Node
+				if err := s.Open(ctx, &n); err != nil {
+					b.Fatalf("Open: %s", err)
+				}
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := OpenBatch(ctx, signed); err != nil {
+				b.Fatalf("OpenBatch: %s", err)
+			}
+		}
+	})
+}
+
+func TestTEEConstraints(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: TEEConstraints").Public()
+	eid := newTestEnclaveIdentity(45)
+	capTEE := newTestCapabilityTEE(t, rak, eid, newTestRAKReportData(rak))
+	now := time.Now()
+	cs := SGXConstraints{Enclaves: []sgx.EnclaveIdentity{eid}}
+
+	// Both bare SGX constraints and tagged constraints should be accepted.
+	require.NoError(capTEE.Verify(now, cbor.Marshal(cs)), "bare SGX constraints should be accepted")
+	tc := TEEConstraints{Hardware: TEEHardwareIntelSGX, SGX: &cs}
+	require.NoError(capTEE.Verify(now, cbor.Marshal(tc)), "tagged SGX constraints should be accepted")
+
+	decoded, err := DecodeTEEConstraints(cbor.Marshal(cs), TEEHardwareIntelSGX)
+	require.NoError(err, "DecodeTEEConstraints")
+	require.Equal(TEEHardwareIntelSGX, decoded.Hardware)
+	require.EqualValues(&cs, decoded.SGX)
+
+	// Constraints for other hardware should be rejected.
+	tc = TEEConstraints{Hardware: TEEHardwareReserved}
+	err = capTEE.Verify(now, cbor.Marshal(tc))
+	require.ErrorIs(err, ErrTEEHardwareMismatch, "constraints for other hardware should be rejected")
+	_, err = DecodeTEEConstraints(cbor.Marshal(cs), TEEHardwareReserved)
+	require.ErrorIs(err, ErrTEEHardwareMismatch, "bare SGX constraints should only be accepted for SGX")
+
+	// Tagged constraints without the hardware-specific constraints should be rejected.
+	tc = TEEConstraints{Hardware: TEEHardwareIntelSGX}
+	require.Error(capTEE.Verify(now, cbor.Marshal(tc)), "missing SGX constraints should be rejected")
+}
+
+func TestNodePrettyPrint(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: PrettyPrint rak").Public()
+	n := &Node{
+		ID:         memorySigner.NewTestSigner("node test: PrettyPrint").Public(),
+		Expiration: 42,
+		Roles:      RoleComputeWorker | RoleValidator,
+		Runtimes: []*Runtime{
+			{
+				ID:      common.NewTestNamespaceFromSeed([]byte("node test: PrettyPrint"), 0),
+				Version: version.Version{Major: 1, Minor: 2, Patch: 3},
+				Capabilities: Capabilities{
+					TEE: &CapabilityTEE{
+						Hardware: TEEHardwareIntelSGX,
+						RAK:      rak,
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	n.PrettyPrint(context.Background(), "  ", &buf)
+	out := buf.String()
+	require.Contains(out, "  ID:         "+n.ID.String()+"\n")
+	require.Contains(out, "  Expiration: epoch 42\n")
+	require.Contains(out, "  Roles:      "+n.Roles.String()+"\n")
+	require.Contains(out, "  Runtimes:\n")
+	require.Contains(out, "      Version: 1.2.3\n")
+	require.Contains(out, "      TEE:     intel-sgx (RAK: "+rak.String()[:prettyRAKLength]+"...)\n")
+	require.NotContains(out, rak.String(), "RAK should be truncated")
+
+	pt, err := n.PrettyType()
+	require.NoError(err, "PrettyType")
+	require.Equal(n, pt)
+
+	// Nodes without runtimes.
+	n.Runtimes = nil
+	buf.Reset()
+	n.PrettyPrint(context.Background(), "", &buf)
+	require.Contains(buf.String(), "Runtimes:   (none)\n")
+}
+
+func TestNodeCheckVRF(t *testing.T) {
+	require := require.New(t)
+
+	n := &Node{Roles: RoleKeyManager}
+	require.NoError(n.CheckVRF(), "VRF info should not be required for non-VRF roles")
+
+	// Required but missing.
+	for _, role := range []RolesMask{RoleComputeWorker, RoleValidator, RoleValidator | RoleKeyManager} {
+		n.Roles = role
+		require.ErrorIs(n.CheckVRF(), ErrInvalidVRFInfo, "VRF info should be required for role %s", role)
+	}
+
+	// Present but zero.
+	n.VRF = &VRFInfo{}
+	require.ErrorIs(n.CheckVRF(), ErrInvalidVRFInfo, "zero VRF ID should be rejected")
+	n.Roles = RoleKeyManager
+	require.ErrorIs(n.CheckVRF(), ErrInvalidVRFInfo, "zero VRF ID should be rejected for non-VRF roles")
+
+	// Valid.
+	n.Roles = RoleValidator
+	n.VRF.ID = memorySigner.NewTestSigner("node test: CheckVRF").Public()
+	require.NoError(n.CheckVRF(), "valid VRF info should be accepted")
+
+	// The deprecated PVSS beacon field should not be considered.
+	n.VRF = nil
+	n.DeprecatedBeacon = cbor.RawMessage{0xa0}
+	require.ErrorIs(n.CheckVRF(), ErrInvalidVRFInfo, "deprecated beacon should not replace VRF info")
+}
+
+func TestNodeCheckConnectivity(t *testing.T) {
+	require := require.New(t)
+
+	var n Node
+	require.NoError(n.CheckConnectivity(), "no connectivity info should be required without roles")
+
+	addr := Address{TCPAddr: net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234}}
+	consensusID := memorySigner.NewTestSigner("node test: CheckConnectivity consensus").Public()
+	p2pID := memorySigner.NewTestSigner("node test: CheckConnectivity P2P").Public()
+	tlsPubKey := memorySigner.NewTestSigner("node test: CheckConnectivity TLS").Public()
+
+	// Validators require consensus info.
+	n.Roles = RoleValidator
+	require.ErrorIs(n.CheckConnectivity(), ErrMissingConsensusInfo, "missing consensus ID")
+	n.Consensus.ID = consensusID
+	require.ErrorIs(n.CheckConnectivity(), ErrMissingConsensusInfo, "missing consensus addresses")
+	n.Consensus.Addresses = []ConsensusAddress{{ID: consensusID, Address: addr}}
+	require.NoError(n.CheckConnectivity(), "complete consensus info")
+
+	// Compute workers require P2P info.
+	n.AddRoles(RoleComputeWorker)
+	require.ErrorIs(n.CheckConnectivity(), ErrMissingP2PInfo, "missing P2P ID")
+	n.P2P.ID = p2pID
+	require.ErrorIs(n.CheckConnectivity(), ErrMissingP2PInfo, "missing P2P addresses")
+	n.P2P.Addresses = []Address{addr}
+	require.NoError(n.CheckConnectivity(), "complete P2P info")
+
+	// Consensus RPC nodes require TLS info.
+	n.AddRoles(RoleConsensusRPC)
+	require.ErrorIs(n.CheckConnectivity(), ErrMissingTLSInfo, "missing TLS public key")
+	n.TLS.PubKey = tlsPubKey
+	require.ErrorIs(n.CheckConnectivity(), ErrMissingTLSInfo, "missing TLS addresses")
+	n.TLS.Addresses = []TLSAddress{{PubKey: tlsPubKey, Address: addr}}
+	require.NoError(n.CheckConnectivity(), "complete TLS info")
+
+	// Info not required by the roles should not be checked.
+	n = Node{Roles: RoleKeyManager, P2P: P2PInfo{ID: p2pID, Addresses: []Address{addr}}}
+	require.NoError(n.CheckConnectivity(), "consensus and TLS info should not be required")
+}
+
+func TestNodeAllAddresses(t *testing.T) {
+	require := require.New(t)
+
+	newAddr := func(ip string, port int) Address {
+		return Address{TCPAddr: net.TCPAddr{IP: net.ParseIP(ip), Port: port}}
+	}
+	pk := memorySigner.NewTestSigner("node test: AllAddresses").Public()
+
+	var n Node
+	require.Empty(n.AllAddresses(), "AllAddresses should be empty without addresses")
+	require.Empty(n.AllTransportAddresses(), "AllTransportAddresses should be empty without addresses")
+
+	n.Consensus.Addresses = []ConsensusAddress{
+		{ID: pk, Address: newAddr("192.0.2.2", 26656)},
+		{ID: pk, Address: newAddr("192.0.2.1", 26656)},
+	}
+	n.P2P.Addresses = []Address{
+		newAddr("192.0.2.1", 9200),
+		newAddr("192.0.2.1", 26656),
+		newAddr("192.0.2.1", 9200),
+	}
+	n.TLS.Addresses = []TLSAddress{
+		{PubKey: pk, Address: newAddr("192.0.2.1", 9100)},
+	}
+
+	require.EqualValues([]TransportAddress{
+		{AddressTransportConsensus, newAddr("192.0.2.1", 26656)},
+		{AddressTransportConsensus, newAddr("192.0.2.2", 26656)},
+		{AddressTransportP2P, newAddr("192.0.2.1", 26656)},
+		{AddressTransportP2P, newAddr("192.0.2.1", 9200)},
+		{AddressTransportTLS, newAddr("192.0.2.1", 9100)},
+	}, n.AllTransportAddresses())
+	require.Equal("tls://192.0.2.1:9100", n.AllTransportAddresses()[4].String())
+
+	require.EqualValues([]string{
+		"192.0.2.1:26656",
+		"192.0.2.1:9100",
+		"192.0.2.1:9200",
+		"192.0.2.2:26656",
+	}, n.AllAddresses())
+}
+
+func TestCapabilityTEEEnclaveIdentity(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: EnclaveIdentity").Public()
+	eid := newTestEnclaveIdentity(46)
+	capTEE := newTestCapabilityTEE(t, rak, eid, newTestRAKReportData(rak))
+
+	id, err := capTEE.EnclaveIdentity()
+	require.NoError(err, "EnclaveIdentity")
+	require.EqualValues(eid, *id)
+
+	// Constraints should not be applied.
+	otherTEE := newTestCapabilityTEE(t, rak, eid, [64]byte{})
+	id, err = otherTEE.EnclaveIdentity()
+	require.NoError(err, "EnclaveIdentity should not check the report data")
+	require.EqualValues(eid, *id)
+
+	// Invalid hardware.
+	invalidTEE := *capTEE
+	invalidTEE.Hardware = TEEHardwareInvalid
+	_, err = invalidTEE.EnclaveIdentity()
+	require.ErrorIs(err, ErrInvalidTEEHardware)
+
+	// Malformed attestation.
+	malformedTEE := *capTEE
+	malformedTEE.Attestation = cbor.Marshal(ias.AVRBundle{Body: []byte("{}")})
+	_, err = malformedTEE.EnclaveIdentity()
+	require.Error(err, "EnclaveIdentity should fail for malformed attestations")
+}
+
+func TestVerifyNodes(t *testing.T) {
+	require := require.New(t)
+
+	ctx := signature.NewContext("oasis-core/node: test verify nodes")
+	signed := newTestMultiSignedNodes(t, ctx, 10)
+
+	rak := memorySigner.NewTestSigner("node test: VerifyNodes rak").Public()
+	eid := newTestEnclaveIdentity(47)
+	capTEE := newTestCapabilityTEE(t, rak, eid, newTestRAKReportData(rak))
+	rtID := common.NewTestNamespaceFromSeed([]byte("node test: VerifyNodes"), 0)
+	otherRtID := common.NewTestNamespaceFromSeed([]byte("node test: VerifyNodes other"), 0)
+	constraints := map[common.Namespace][]byte{
+		rtID:      cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{eid}}),
+		otherRtID: cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{newTestEnclaveIdentity(48)}}),
+	}
+
+	signTEENode := func(i int, id common.Namespace) *MultiSignedNode {
+		nodeSigner := memorySigner.NewTestSigner(fmt.Sprintf("node test: VerifyNodes node %d", i))
+		n := &Node{
+			Versioned: cbor.NewVersioned(LatestNodeDescriptorVersion),
+			ID:        nodeSigner.Public(),
+			Roles:     RoleComputeWorker,
+			Runtimes: []*Runtime{
+				{ID: id, Capabilities: Capabilities{TEE: capTEE}},
+			},
+		}
+		s, err := MultiSignNode([]signature.Signer{nodeSigner}, ctx, n)
+		require.NoError(err, "MultiSignNode")
+		return s
+	}
+	signed[2] = signTEENode(2, rtID)
+	signed[5] = signTEENode(5, otherRtID)
+	signed[7] = signTEENode(7, common.NewTestNamespaceFromSeed([]byte("node test: VerifyNodes unknown"), 0))
+	signed[3].Signatures[1].Signature[0] ^= 0xff
+
+	nodes, errs := VerifyNodes(ctx, time.Now(), constraints, signed)
+	require.Len(nodes, len(signed))
+	require.Len(errs, len(signed))
+	for i := range signed {
+		switch i {
+		case 3:
+			require.ErrorIs(errs[i], signature.ErrVerifyFailed, "bad signatures should be rejected")
+		case 5:
+			require.ErrorIs(errs[i], ErrBadEnclaveIdentity, "TEE capabilities should be verified")
+		case 7:
+			require.Error(errs[i], "runtimes without constraints should be rejected")
+		default:
+			require.NoError(errs[i], "VerifyNodes")
+			var expected Node
+			require.NoError(signed[i].Open(ctx, &expected), "Open")
+			require.EqualValues(&expected, nodes[i])
+			continue
+		}
+		require.Nil(nodes[i], "invalid descriptors should not be returned")
+	}
+
+	nodes, errs = VerifyNodes(ctx, time.Now(), constraints, nil)
+	require.Empty(nodes)
+	require.Empty(errs)
+}
+
+func TestCapabilityTEEVerifyWithTrustRoots(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: VerifyWithTrustRoots").Public()
+	eid := newTestEnclaveIdentity(49)
+	capTEE := newTestCapabilityTEE(t, rak, eid, newTestRAKReportData(rak))
+	cs := cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{eid}})
+	now := time.Now()
+
+	require.NoError(capTEE.VerifyWithTrustRoots(now, cs, ias.IntelTrustRoots), "VerifyWithTrustRoots")
+	require.NoError(capTEE.VerifyWithTrustRoots(now, cs, x509.NewCertPool()), "VerifyWithTrustRoots with custom roots")
+
+	// Missing trust roots must not fall back to the system trust roots.
+	require.Error(capTEE.VerifyWithTrustRoots(now, cs, nil), "VerifyWithTrustRoots should reject nil trust roots")
+
+	// Constraints should still be enforced.
+	otherCs := cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{newTestEnclaveIdentity(50)}})
+	require.ErrorIs(capTEE.VerifyWithTrustRoots(now, otherCs, x509.NewCertPool()), ErrBadEnclaveIdentity)
+}
+
+func TestCapabilityTEEVerifyWithNonce(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: VerifyWithNonce").Public()
+	eid := newTestEnclaveIdentity(71)
+	var nonce [32]byte
+	copy(nonce[:], "node test: VerifyWithNonce nonce")
+	reportData := newTestRAKReportData(rak)
+	copy(reportData[hash.Size:], nonce[:])
+	capTEE := newTestCapabilityTEE(t, rak, eid, reportData)
+	now := time.Now()
+	cs := cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{eid}})
+
+	require.NoError(capTEE.VerifyWithNonce(now, cs, nonce), "VerifyWithNonce")
+
+	// A zero nonce should not be checked.
+	require.NoError(capTEE.VerifyWithNonce(now, cs, [32]byte{}), "VerifyWithNonce with zero nonce")
+
+	// Other nonces should be rejected.
+	otherNonce := nonce
+	otherNonce[0] ^= 0xff
+	require.ErrorIs(capTEE.VerifyWithNonce(now, cs, otherNonce), ErrConstraintViolation)
+
+	// Constraints requiring different report data should be rejected.
+	otherExtra := hash.Hash(otherNonce)
+	csExtra := cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{eid}, ReportDataExtra: &otherExtra})
+	require.ErrorIs(capTEE.VerifyWithNonce(now, csExtra, nonce), ErrConstraintViolation)
+
+	// Existing checks should still be performed.
+	cs = cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{newTestEnclaveIdentity(72)}})
+	require.ErrorIs(capTEE.VerifyWithNonce(now, cs, nonce), ErrBadEnclaveIdentity)
+}
+
+func TestCapabilityTEEVerifyAll(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: VerifyAll").Public()
+	eid := newTestEnclaveIdentity(61)
+	extra := hash.NewFromBytes([]byte("node test: VerifyAll extra"))
+	reportData := newTestRAKReportData(rak)
+	copy(reportData[hash.Size:], extra[:])
+	capTEE := newTestCapabilityTEE(t, rak, eid, reportData)
+	now := time.Now()
+
+	// Valid constraints should not report any failures.
+	cs := cbor.Marshal(SGXConstraints{Enclaves: []sgx.EnclaveIdentity{eid}, ReportDataExtra: &extra})
+	require.NoError(capTEE.Verify(now, cs), "Verify")
+	require.Empty(capTEE.VerifyAll(now, cs), "VerifyAll")
+
+	// Multiple simultaneous violations should all be reported.
+	otherExtra := hash.NewFromBytes([]byte("node test: VerifyAll other extra"))
+	cs = cbor.Marshal(SGXConstraints{
+		Enclaves:        []sgx.EnclaveIdentity{newTestEnclaveIdentity(62)},
+		ReportDataExtra: &otherExtra,
+	})
+	otherTEE := *capTEE
+	otherTEE.RAK = memorySigner.NewTestSigner("node test: VerifyAll other").Public()
+	errs := otherTEE.VerifyAll(now, cs)
+	require.Len(errs, 3, "VerifyAll should report all failures")
+	require.ErrorIs(errs[0], ErrBadEnclaveIdentity)
+	require.ErrorIs(errs[1], ErrRAKHashMismatch)
+	require.ErrorIs(errs[2], ErrReportDataMismatch)
+
+	// Verify should report the first failure.
+	require.ErrorIs(otherTEE.Verify(now, cs), ErrBadEnclaveIdentity)
+
+	// Unparseable attestations and constraints should short-circuit.
+	badTEE := *capTEE
+	badTEE.Attestation = []byte("not an attestation")
+	require.Len(badTEE.VerifyAll(now, cs), 1)
+	errs = capTEE.VerifyAll(now, []byte("not constraints"))
+	require.Len(errs, 1)
+	require.Error(errs[0])
+
+	// Invalid hardware.
+	badTEE = *capTEE
+	badTEE.Hardware = TEEHardwareInvalid
+	errs = badTEE.VerifyAll(now, cs)
+	require.Len(errs, 1)
+	require.ErrorIs(errs[0], ErrInvalidTEEHardware)
+}
+
+func TestTEEHardwareRegistry(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		hw   TEEHardware
+		name string
+	}{
+		{TEEHardwareInvalid, "invalid"},
+		{TEEHardwareIntelSGX, "intel-sgx"},
+	} {
+		require.Equal(tc.name, tc.hw.String())
+
+		var hw TEEHardware
+		require.NoError(hw.FromString(tc.name), "FromString")
+		require.Equal(tc.hw, hw)
+	}
+
+	var hw TEEHardware
+	require.NoError(hw.FromString(""), "FromString empty")
+	require.Equal(TEEHardwareInvalid, hw)
+	require.ErrorIs(hw.FromString("experimental"), ErrInvalidTEEHardware)
+	require.Equal("[unsupported TEEHardware]", TEEHardwareReserved.String())
+
+	// Register experimental hardware.
+	var verifyCalled bool
+	registerTEEHardware(TEEHardwareReserved, &teeHardwareDescriptor{
+		name: "experimental",
+		verify: func(c *CapabilityTEE, ts time.Time, constraints []byte, trustRoots *x509.CertPool) error {
+			verifyCalled = true
+			return nil
+		},
+	})
+	defer delete(teeHardwareRegistry, TEEHardwareReserved)
+
+	require.Equal("experimental", TEEHardwareReserved.String())
+	require.NoError(hw.FromString("Experimental"), "FromString")
+	require.Equal(TEEHardwareReserved, hw)
+
+	capTEE := &CapabilityTEE{Hardware: TEEHardwareReserved}
+	require.NoError(capTEE.Verify(time.Now(), nil), "Verify")
+	require.True(verifyCalled, "registered verifier should be used")
+	require.Empty(capTEE.VerifyAll(time.Now(), nil), "VerifyAll should fall back to the registered verifier")
+
+	// Hardware without a verifier cannot be verified.
+	capTEE.Hardware = TEEHardwareInvalid
+	require.ErrorIs(capTEE.Verify(time.Now(), nil), ErrInvalidTEEHardware)
+
+	// Duplicate registrations should panic.
+	require.Panics(func() {
+		registerTEEHardware(TEEHardwareIntelSGX, &teeHardwareDescriptor{name: "other"})
+	})
+	require.Panics(func() {
+		registerTEEHardware(TEEHardwareReserved+1, &teeHardwareDescriptor{name: "intel-sgx"})
+	})
+}
+
+func TestCapabilityTEEHash(t *testing.T) {
+	require := require.New(t)
+
+	rak := memorySigner.NewTestSigner("node test: CapabilityTEE Hash").Public()
+	capTEE := &CapabilityTEE{
+		Hardware:    TEEHardwareIntelSGX,
+		RAK:         rak,
+		Attestation: []byte("attestation"),
+	}
+	h := capTEE.Hash()
+
+	// The hash should be stable across serialization round-trips.
+	var decoded CapabilityTEE
+	require.NoError(cbor.Unmarshal(cbor.Marshal(capTEE), &decoded), "cbor.Unmarshal")
+	require.Equal(h, decoded.Hash(), "hash should survive CBOR round-trips")
+	raw, err := json.Marshal(capTEE)
+	require.NoError(err, "json.Marshal")
+	decoded = CapabilityTEE{}
+	require.NoError(json.Unmarshal(raw, &decoded), "json.Unmarshal")
+	require.Equal(h, decoded.Hash(), "hash should survive JSON round-trips")
+
+	// Every field should be covered.
+	other := *capTEE
+	other.Hardware = TEEHardwareInvalid
+	require.NotEqual(h, other.Hash(), "hardware should be covered")
+	other = *capTEE
+	other.RAK = memorySigner.NewTestSigner("node test: CapabilityTEE Hash other").Public()
+	require.NotEqual(h, other.Hash(), "RAK should be covered")
+	other = *capTEE
+	other.Attestation = []byte("other attestation")
+	require.NotEqual(h, other.Hash(), "attestation should be covered")
+}
@@ -16,6 +16,7 @@ type cmdCtx struct {
 	ch              channels.Channel
 	errCh           chan error
 	onSubscribeHook OnSubscribeHook
+	filter          FilterFunc
 
 	isSubscribe bool
 }
@@ -77,7 +78,7 @@ func (s *Subscription) Close() {
 
 // Broker is a pub/sub broker instance.
 type Broker struct {
-	subscribers     map[channels.Channel]bool
+	subscribers     map[channels.Channel]FilterFunc
 	cmdCh           chan *cmdCtx
 	broadcastCh     channels.Channel
 	lastBroadcasted *broadcastedValue
@@ -88,6 +89,10 @@ type Broker struct {
 // OnSubscribeHook is the on-subscribe callback hook prototype.
 type OnSubscribeHook func(channels.Channel)
 
+// FilterFunc is the per-subscription filter prototype. Only broadcasted
+// values for which the filter returns true are delivered.
+type FilterFunc func(interface{}) bool
+
 // Subscribe subscribes to the Broker's broadcasts, and returns a
 // subscription handle that can be used to receive broadcasts.
 //
@@ -115,6 +120,22 @@ func (b *Broker) SubscribeBuffered(buffer int64) *Subscription {
 // Note: If there is a Broker wide hook set, it will be called
 // after the per-subscription hook is called.
 func (b *Broker) SubscribeEx(buffer int64, onSubscribeHook OnSubscribeHook) *Subscription {
+	return b.subscribe(buffer, onSubscribeHook, nil)
+}
+
+// SubscribeFiltered subscribes to the Broker's broadcasts, and returns a
+// subscription handle that can be used to receive broadcasts for which
+// the given filter returns true.
+//
+// The filter is evaluated by the Broker so filtered out values never
+// reach the subscription's channel. It must not block.
+//
+// Note: Any Broker wide on-subscribe hook is not subject to the filter.
+func (b *Broker) SubscribeFiltered(buffer int64, filter FilterFunc) *Subscription {
+	return b.subscribe(buffer, nil, filter)
+}
+
+func (b *Broker) subscribe(buffer int64, onSubscribeHook OnSubscribeHook, filter FilterFunc) *Subscription {
 	var ch channels.Channel
 	if buffer <= 0 {
 		ch = channels.NewInfiniteChannel()
@@ -125,6 +146,7 @@ func (b *Broker) SubscribeEx(buffer int64, onSubscribeHook OnSubscribeHook) *Sub
 		ch:              ch,
 		errCh:           make(chan error),
 		onSubscribeHook: onSubscribeHook,
+		filter:          filter,
 		isSubscribe:     true,
 	}
 
@@ -156,10 +178,10 @@ func (b *Broker) worker() {
 				if b.onSubscribeHook != nil {
 					b.onSubscribeHook(ctx.ch)
 				}
-				b.subscribers[ctx.ch] = true
+				b.subscribers[ctx.ch] = ctx.filter
 				close(ctx.errCh)
 			} else {
-				if !b.subscribers[ctx.ch] {
+				if _, ok := b.subscribers[ctx.ch]; !ok {
 					ctx.errCh <- errors.New("pubsub: unsubscribed an unknown channel")
 				} else {
 					delete(b.subscribers, ctx.ch)
@@ -168,7 +190,10 @@ func (b *Broker) worker() {
 				}
 			}
 		case v := <-b.broadcastCh.Out():
-			for ch := range b.subscribers {
+			for ch, filter := range b.subscribers {
+				if filter != nil && !filter(v) {
+					continue
+				}
 				ch.In() <- v
 			}
 			b.lastBroadcasted = &broadcastedValue{v}
@@ -207,7 +232,7 @@ func NewBrokerEx(onSubscribeHook OnSubscribeHook) *Broker {
 
 func newBroker() *Broker {
 	return &Broker{
-		subscribers: make(map[channels.Channel]bool),
+		subscribers: make(map[channels.Channel]FilterFunc),
 		cmdCh:       make(chan *cmdCtx),
 		broadcastCh: channels.NewInfiniteChannel(),
 	}
@@ -19,6 +19,7 @@ func TestPubSub(t *testing.T) {
 	t.Run("PubLastOnSubscribe", testLastOnSubscribe)
 	t.Run("SubscribeEx", testSubscribeEx)
 	t.Run("NewBrokerEx", testNewBrokerEx)
+	t.Run("SubscribeFiltered", testSubscribeFiltered)
 }
 
 func testBasicInfinity(t *testing.T) {
@@ -154,3 +155,34 @@ func testNewBrokerEx(t *testing.T) {
 		require.Equal(t, sub.ch, callbackCh, "Callback channel != Subscription, inner channel")
 	}
 }
+
+func testSubscribeFiltered(t *testing.T) {
+	broker := NewBroker(false)
+
+	sub := broker.SubscribeFiltered(0, func(v interface{}) bool {
+		return v.(int)%2 == 0
+	})
+	typedCh := make(chan int)
+	sub.Unwrap(typedCh)
+
+	// Only values accepted by the filter should be received.
+	for i := 0; i < 10; i++ {
+		broker.Broadcast(i)
+	}
+	for i := 0; i < 10; i += 2 {
+		select {
+		case v := <-typedCh:
+			require.Equal(t, i, v, "Filtered Broadcast()")
+		case <-time.After(recvTimeout):
+			t.Fatalf("Failed to receive value, filtered Broadcast()")
+		}
+	}
+	select {
+	case v := <-typedCh:
+		t.Fatalf("Received filtered out value: %d", v)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NotPanics(t, func() { sub.Close() }, "Close()")
+	require.Len(t, broker.subscribers, 0, "Subscriber map, post Close()")
+}
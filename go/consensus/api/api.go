@@ -58,6 +58,9 @@ var (
 
 	// ErrInvalidArgument is the error returned when the request contains an invalid argument.
 	ErrInvalidArgument = errors.New(moduleName, 6, "consensus: invalid argument")
+
+	// ErrTransactionNotFound is the error returned when the given transaction cannot be found.
+	ErrTransactionNotFound = errors.New(moduleName, 7, "consensus: transaction not found")
 )
 
 // FeatureMask is the consensus backend feature bitmask.
@@ -313,3 +316,13 @@ type TransactionsWithResults struct {
 	Transactions [][]byte          `json:"transactions"`
 	Results      []*results.Result `json:"results"`
 }
+
+// TxResult is the result of a transaction that has been included in a block.
+type TxResult struct {
+	// Height is the height of the block that includes the transaction.
+	Height int64 `json:"height"`
+	// Index is the index of the transaction within the block.
+	Index uint32 `json:"index"`
+	// Result is the transaction execution result.
+	Result *results.Result `json:"result"`
+}
@@ -37,6 +37,14 @@ type LightBlock struct {
 	Meta []byte `json:"meta"`
 }
 
+// ValidatorSet is a consensus validator set.
+type ValidatorSet struct {
+	// Height contains the block height this validator set is for.
+	Height int64 `json:"height"`
+	// Meta contains the consensus backend specific validator set.
+	Meta []byte `json:"meta"`
+}
+
 // Parameters are the consensus backend parameters.
 type Parameters struct {
 	// Height contains the block height these consensus parameters are for.
@@ -184,6 +184,30 @@ func NewBlock(blk *tmtypes.Block) *consensus.Block {
 	}
 }
 
+// CheckTxError is the error returned when a transaction is rejected by the local mempool CheckTx.
+//
+// The error message is the same as the message of the underlying error so that it can be
+// transported across the wire, while in-process callers can use the module and code to
+// distinguish between different rejection reasons.
+type CheckTxError struct {
+	// Module is the module that rejected the transaction.
+	Module string
+	// Code is the CheckTx response code.
+	Code uint32
+	// Err is the error reconstructed from the CheckTx response.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *CheckTxError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error reconstructed from the CheckTx response.
+func (e *CheckTxError) Unwrap() error {
+	return e.Err
+}
+
 // Backend is a Tendermint consensus backend.
 type Backend interface {
 	consensus.Backend
@@ -208,6 +232,47 @@ type Backend interface {
 	// returned via the `EventDataNewBlock` query.
 	WatchTendermintBlocks() (<-chan *tmtypes.Block, *pubsub.Subscription)
 
+	// SubmitTxChecked runs a local mempool CheckTx for the given signed
+	// consensus transaction and only broadcasts it in case the check
+	// succeeds. It does not wait for the transaction to be included in a
+	// block.
+	//
+	// In case the transaction is rejected, a *CheckTxError is returned.
+	SubmitTxChecked(ctx context.Context, tx *transaction.SignedTransaction) error
+
+	// SubmitTxWaitInclusion submits a signed consensus transaction and
+	// waits for it to be included in a block, returning its result.
+	//
+	// In case the transaction is rejected by CheckTx, an error is returned
+	// immediately. Note that a transaction that has been included in a
+	// block may still have failed, see TxResult.Result.
+	SubmitTxWaitInclusion(ctx context.Context, tx *transaction.SignedTransaction) (*consensus.TxResult, error)
+
+	// GetLightBlockForTx returns the light block for the height at which
+	// the transaction with the given hash has been included.
+	//
+	// The hash is the Tendermint transaction hash, i.e. the SHA-256 hash of
+	// the raw (CBOR-serialized) signed transaction.
+	//
+	// This requires the transaction indexer to be enabled. In case the
+	// transaction has not been indexed, ErrTransactionNotFound is returned.
+	GetLightBlockForTx(ctx context.Context, txHash hash.Hash) (*consensus.LightBlock, error)
+
+	// GetValidatorSet returns the validator set for the given height.
+	//
+	// In case the height is not (or no longer) available, ErrVersionNotFound
+	// is returned.
+	GetValidatorSet(ctx context.Context, height int64) (*consensus.ValidatorSet, error)
+
+	// GetLightBlocks returns the light blocks for all heights in the given
+	// inclusive range.
+	GetLightBlocks(ctx context.Context, start, end int64) ([]*consensus.LightBlock, error)
+
+	// WatchLightBlocks returns a stream of light blocks starting at the
+	// given height, first catching up to the latest height and then
+	// following new heights as they are committed.
+	WatchLightBlocks(ctx context.Context, startHeight int64) (<-chan *consensus.LightBlock, error)
+
 	// GetLastRetainedVersion returns the earliest retained version the ABCI
 	// state.
 	GetLastRetainedVersion(ctx context.Context) (int64, error)
@@ -126,6 +126,10 @@ func (app *schedulerApplication) BeginBlock(ctx *api.Context, request types.Requ
 		filterCommitteeNodes := beaconParameters.Backend == beacon.BackendVRF && !params.DebugAllowWeakAlpha
 
 		regState := registryState.NewMutableState(ctx.State())
+		regParams, err := regState.ConsensusParameters(ctx)
+		if err != nil {
+			return fmt.Errorf("tendermint/scheduler: couldn't get registry parameters: %w", err)
+		}
 		runtimes, err := regState.Runtimes(ctx)
 		if err != nil {
 			return fmt.Errorf("tendermint/scheduler: couldn't get runtimes: %w", err)
@@ -204,6 +208,7 @@ func (app *schedulerApplication) BeginBlock(ctx *api.Context, request types.Requ
 				ctx,
 				app.state,
 				params,
+				regParams,
 				beaconState,
 				beaconParameters,
 				stakeAcc,
@@ -383,6 +388,7 @@ func (app *schedulerApplication) electAllCommittees(
 	ctx *api.Context,
 	appState api.ApplicationQueryState,
 	schedulerParameters *scheduler.ConsensusParameters,
+	registryParameters *registry.ConsensusParameters,
 	beaconState *beaconState.MutableState,
 	beaconParameters *beacon.ConsensusParameters,
 	stakeAcc *stakingState.StakeAccumulatorCache,
@@ -397,6 +403,7 @@ func (app *schedulerApplication) electAllCommittees(
 			ctx,
 			appState,
 			schedulerParameters,
+			registryParameters,
 			beaconState,
 			beaconParameters,
 			stakeAcc,
@@ -89,6 +89,9 @@ func TestElectCommittee(t *testing.T) {
 	}
 
 	schedulerParameters := &scheduler.ConsensusParameters{}
+	registryParameters := &registry.ConsensusParameters{
+		EnableMinSoftwareVersionConstraint: true,
+	}
 
 	schedulerState := schedulerState.NewMutableState(ctx.State())
 
@@ -566,6 +569,110 @@ func TestElectCommittee(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"executor: satisfied min software version constraint",
+			scheduler.KindComputeExecutor,
+			[]*node.Node{
+				{
+					ID: nodeID1,
+					Runtimes: []*node.Runtime{
+						{ID: rtID1}, // Matching runtime ID.
+					},
+					Roles:           node.RoleComputeWorker,
+					SoftwareVersion: "22.10.1",
+				},
+				{
+					ID: nodeID2,
+					Runtimes: []*node.Runtime{
+						{ID: rtID1}, // Matching runtime ID.
+					},
+					Roles:           node.RoleComputeWorker,
+					SoftwareVersion: "22.2", // Outdated.
+				},
+				{
+					ID: nodeID3,
+					Runtimes: []*node.Runtime{
+						{ID: rtID1}, // Matching runtime ID.
+					},
+					Roles: node.RoleComputeWorker, // No software version.
+				},
+			},
+			map[signature.PublicKey]*registry.NodeStatus{},
+			map[staking.Address]bool{},
+			registry.Runtime{
+				ID:   rtID1,
+				Kind: registry.KindCompute,
+				Executor: registry.ExecutorParameters{
+					GroupSize:       1,
+					GroupBackupSize: 0,
+				},
+				Constraints: map[scheduler.CommitteeKind]map[scheduler.Role]registry.SchedulingConstraints{
+					scheduler.KindComputeExecutor: {
+						scheduler.RoleWorker: {
+							MinSoftwareVersion: &registry.MinSoftwareVersionConstraint{
+								Version: version.Version{Major: 22, Minor: 10},
+							},
+						},
+					},
+				},
+				Deployments: []*registry.VersionInfo{
+					{},
+				},
+			},
+			true,
+		},
+		{
+			"executor: unsatisfied min software version constraint",
+			scheduler.KindComputeExecutor,
+			[]*node.Node{
+				{
+					ID: nodeID1,
+					Runtimes: []*node.Runtime{
+						{ID: rtID1}, // Matching runtime ID.
+					},
+					Roles:           node.RoleComputeWorker,
+					SoftwareVersion: "22.10.1",
+				},
+				{
+					ID: nodeID2,
+					Runtimes: []*node.Runtime{
+						{ID: rtID1}, // Matching runtime ID.
+					},
+					Roles:           node.RoleComputeWorker,
+					SoftwareVersion: "22.2", // Outdated.
+				},
+				{
+					ID: nodeID3,
+					Runtimes: []*node.Runtime{
+						{ID: rtID1}, // Matching runtime ID.
+					},
+					Roles: node.RoleComputeWorker, // No software version.
+				},
+			},
+			map[signature.PublicKey]*registry.NodeStatus{},
+			map[staking.Address]bool{},
+			registry.Runtime{
+				ID:   rtID1,
+				Kind: registry.KindCompute,
+				Executor: registry.ExecutorParameters{
+					GroupSize:       2,
+					GroupBackupSize: 0,
+				},
+				Constraints: map[scheduler.CommitteeKind]map[scheduler.Role]registry.SchedulingConstraints{
+					scheduler.KindComputeExecutor: {
+						scheduler.RoleWorker: {
+							MinSoftwareVersion: &registry.MinSoftwareVersionConstraint{
+								Version: version.Version{Major: 22, Minor: 10},
+							},
+						},
+					},
+				},
+				Deployments: []*registry.VersionInfo{
+					{},
+				},
+			},
+			false,
+		},
 		{
 			"executor: frozen nodes are ineligible",
 			scheduler.KindComputeExecutor,
@@ -786,6 +893,7 @@ func TestElectCommittee(t *testing.T) {
 			ctx,
 			app.state,
 			schedulerParameters,
+			registryParameters,
 			beaconState,
 			beaconParameters,
 			nil,
@@ -806,4 +914,63 @@ func TestElectCommittee(t *testing.T) {
 
 		require.NotNil(c, "Committee should have been elected (%s)", tc.msg)
 	}
+
+	// The min software version constraint should be ignored unless enabled.
+	rt := registry.Runtime{
+		ID:   rtID2,
+		Kind: registry.KindCompute,
+		Executor: registry.ExecutorParameters{
+			GroupSize: 1,
+		},
+		Constraints: map[scheduler.CommitteeKind]map[scheduler.Role]registry.SchedulingConstraints{
+			scheduler.KindComputeExecutor: {
+				scheduler.RoleWorker: {
+					MinSoftwareVersion: &registry.MinSoftwareVersionConstraint{
+						Version: version.Version{Major: 22, Minor: 10},
+					},
+				},
+			},
+		},
+		Deployments: []*registry.VersionInfo{
+			{},
+		},
+	}
+	nodes := []*nodeWithStatus{
+		{
+			&node.Node{
+				ID: nodeID1,
+				Runtimes: []*node.Runtime{
+					{ID: rtID2},
+				},
+				Roles:           node.RoleComputeWorker,
+				SoftwareVersion: "22.2", // Outdated.
+			},
+			&registry.NodeStatus{},
+		},
+	}
+	for _, enabled := range []bool{true, false} {
+		err := app.electCommittee(
+			ctx,
+			app.state,
+			schedulerParameters,
+			&registry.ConsensusParameters{EnableMinSoftwareVersionConstraint: enabled},
+			beaconState,
+			beaconParameters,
+			nil,
+			nil,
+			map[staking.Address]bool{},
+			&rt,
+			nodes,
+			scheduler.KindComputeExecutor,
+		)
+		require.NoError(err, "committee election should not fail")
+
+		c, err := schedulerState.Committee(ctx, scheduler.KindComputeExecutor, rt.ID)
+		require.NoError(err, "Committee")
+		if enabled {
+			require.Nil(c, "Committee should not have been elected when the constraint is enabled")
+			continue
+		}
+		require.NotNil(c, "Committee should have been elected when the constraint is disabled")
+	}
 }
@@ -150,6 +150,7 @@ func (app *schedulerApplication) electCommittee( //nolint: gocyclo
 	ctx *api.Context,
 	appState api.ApplicationQueryState,
 	schedulerParameters *scheduler.ConsensusParameters,
+	registryParameters *registry.ConsensusParameters,
 	beaconState *beaconState.MutableState,
 	beaconParameters *beacon.ConsensusParameters,
 	stakeAcc *stakingState.StakeAccumulatorCache,
@@ -277,6 +278,13 @@ func (app *schedulerApplication) electCommittee( //nolint: gocyclo
 					continue
 				}
 			}
+			// Minimum software version constraint.
+			if registryParameters.EnableMinSoftwareVersionConstraint && cs[role].MinSoftwareVersion != nil {
+				if n.node.CheckSoftwareVersion(cs[role].MinSoftwareVersion.Version) != nil {
+					// Not eligible if the node's software is too old.
+					continue
+				}
+			}
 
 			nodeLists[role] = append(nodeLists[role], n.node)
 			eligible = true
@@ -27,11 +27,14 @@ import (
 	tmcli "github.com/tendermint/tendermint/rpc/client/local"
 	tmrpctypes "github.com/tendermint/tendermint/rpc/core/types"
 	tmstate "github.com/tendermint/tendermint/state"
+	tmtxindex "github.com/tendermint/tendermint/state/txindex"
+	tmtxindexkv "github.com/tendermint/tendermint/state/txindex/kv"
 	tmstatesync "github.com/tendermint/tendermint/statesync"
 	tmtypes "github.com/tendermint/tendermint/types"
 	tmdb "github.com/tendermint/tm-db"
 
 	beaconAPI "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/cache/lru"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
@@ -101,6 +104,9 @@ const (
 	// CfgP2PUnconditionalPeerIDs configures tendermint's unconditional peer(s).
 	CfgP2PUnconditionalPeerIDs = "consensus.tendermint.p2p.unconditional_peer_ids"
 
+	// CfgTxIndexEnabled enables tendermint's transaction indexer.
+	CfgTxIndexEnabled = "consensus.tendermint.tx_index.enabled"
+
 	// CfgDebugUnsafeReplayRecoverCorruptedWAL enables the debug and unsafe
 	// automatic corrupted WAL recovery during replay.
 	CfgDebugUnsafeReplayRecoverCorruptedWAL = "consensus.tendermint.debug.unsafe_replay_recover_corrupted_wal"
@@ -127,6 +133,10 @@ const (
 
 	// CfgUpgradeStopDelay is the average amount of time to delay shutting down the node on upgrade.
 	CfgUpgradeStopDelay = "consensus.tendermint.upgrade.stop_delay"
+
+	// CfgParametersCacheSize configures the number of heights for which consensus parameters are
+	// cached (zero disables the cache).
+	CfgParametersCacheSize = "consensus.tendermint.parameters_cache_size"
 )
 
 const (
@@ -166,7 +176,9 @@ type fullService struct { // nolint: maligned
 	blockNotifier *pubsub.Broker
 	failMonitor   *failMonitor
 
-	stateStore tmstate.Store
+	stateStore  tmstate.Store
+	txIndexer   tmtxindex.TxIndexer
+	paramsCache *lru.Cache
 
 	beacon        beaconAPI.Backend
 	governance    governanceAPI.Backend
@@ -445,18 +457,49 @@ func (t *fullService) RegisterHaltHook(hook consensusAPI.HaltHook) {
 }
 
 func (t *fullService) SubmitTx(ctx context.Context, tx *transaction.SignedTransaction) error {
+	ev, err := t.submitTxAndWait(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if result := ev.Result; !result.IsOK() {
+		return errors.FromCode(result.GetCodespace(), result.GetCode(), result.GetLog())
+	}
+	return nil
+}
+
+func (t *fullService) SubmitTxWaitInclusion(ctx context.Context, tx *transaction.SignedTransaction) (*consensusAPI.TxResult, error) {
+	ev, err := t.submitTxAndWait(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := txResultFromTendermint(ev.Tx, ev.Height, &ev.Result)
+	if err != nil {
+		return nil, err
+	}
+	return &consensusAPI.TxResult{
+		Height: ev.Height,
+		Index:  ev.Index,
+		Result: result,
+	}, nil
+}
+
+// submitTxAndWait broadcasts the given transaction and waits for it to be included in a block.
+//
+// In case the transaction is rejected by CheckTx, an error is returned immediately.
+func (t *fullService) submitTxAndWait(ctx context.Context, tx *transaction.SignedTransaction) (*tmtypes.EventDataTx, error) {
 	// Subscribe to the transaction being included in a block.
 	data := cbor.Marshal(tx)
 	query := tmtypes.EventQueryTxFor(data)
 	subID := t.newSubscriberID()
 	txSub, err := t.subscribe(subID, query)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if ptrSub, ok := txSub.(*tendermintPubsubBuffer).tmSubscription.(*tmpubsub.Subscription); ok && ptrSub == nil {
 		t.Logger.Debug("broadcastTx: service has shut down. Cancel our context to recover")
 		<-ctx.Done()
-		return ctx.Err()
+		return nil, ctx.Err()
 	}
 
 	defer t.unsubscribe(subID, query) // nolint: errcheck
@@ -466,32 +509,46 @@ func (t *fullService) SubmitTx(ctx context.Context, tx *transaction.SignedTransa
 
 	recheckCh, recheckSub, err := t.mux.WatchInvalidatedTx(txHash)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer recheckSub.Close()
 
 	// First try to broadcast.
 	if err := t.broadcastTxRaw(data); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Wait for the transaction to be included in a block.
 	select {
 	case v := <-recheckCh:
-		return v
+		return nil, v
 	case v := <-txSub.Out():
-		if result := v.Data().(tmtypes.EventDataTx).Result; !result.IsOK() {
-			return errors.FromCode(result.GetCodespace(), result.GetCode(), result.GetLog())
-		}
-		return nil
+		ev := v.Data().(tmtypes.EventDataTx)
+		return &ev, nil
 	case <-txSub.Cancelled():
-		return context.Canceled
+		return nil, context.Canceled
 	case <-ctx.Done():
-		return ctx.Err()
+		return nil, ctx.Err()
 	}
 }
 
+func (t *fullService) SubmitTxChecked(ctx context.Context, tx *transaction.SignedTransaction) error {
+	// Submitting to the local mempool runs CheckTx and the transaction is only gossiped to peers
+	// after it has been accepted into the mempool.
+	return t.checkAndBroadcastTxRaw(cbor.Marshal(tx))
+}
+
+// broadcastTxRaw is like checkAndBroadcastTxRaw, but in case the transaction is rejected it
+// returns the error reconstructed from the CheckTx response directly.
 func (t *fullService) broadcastTxRaw(data []byte) error {
+	err := t.checkAndBroadcastTxRaw(data)
+	if checkErr, ok := err.(*api.CheckTxError); ok {
+		return checkErr.Err
+	}
+	return err
+}
+
+func (t *fullService) checkAndBroadcastTxRaw(data []byte) error {
 	// We could use t.client.BroadcastTxSync but that is annoying as it
 	// doesn't give you the right fields when CheckTx fails.
 	mp := t.node.Mempool()
@@ -513,7 +570,11 @@ func (t *fullService) broadcastTxRaw(data []byte) error {
 
 	rsp := <-ch
 	if result := rsp.GetCheckTx(); !result.IsOK() {
-		return errors.FromCode(result.GetCodespace(), result.GetCode(), result.GetLog())
+		return &api.CheckTxError{
+			Module: result.GetCodespace(),
+			Code:   result.GetCode(),
+			Err:    errors.FromCode(result.GetCodespace(), result.GetCode(), result.GetLog()),
+		}
 	}
 
 	return nil
@@ -688,70 +749,64 @@ func (t *fullService) GetTransactionsWithResults(ctx context.Context, height int
 		return nil, err
 	}
 	for txIdx, rs := range res.TxsResults {
-		// Transaction result.
-		result := &results.Result{
-			Error: results.Error{
-				Module:  rs.GetCodespace(),
-				Code:    rs.GetCode(),
-				Message: rs.GetLog(),
-			},
-		}
-
-		// Transaction staking events.
-		stakingEvents, err := tmstaking.EventsFromTendermint(
-			txsWithResults.Transactions[txIdx],
-			blk.Height,
-			rs.Events,
-		)
+		result, err := txResultFromTendermint(txsWithResults.Transactions[txIdx], blk.Height, rs)
 		if err != nil {
 			return nil, err
 		}
-		for _, e := range stakingEvents {
-			result.Events = append(result.Events, &results.Event{Staking: e})
-		}
+		txsWithResults.Results = append(txsWithResults.Results, result)
+	}
+	return &txsWithResults, nil
+}
 
-		// Transaction registry events.
-		registryEvents, _, err := tmregistry.EventsFromTendermint(
-			txsWithResults.Transactions[txIdx],
-			blk.Height,
-			rs.Events,
-		)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range registryEvents {
-			result.Events = append(result.Events, &results.Event{Registry: e})
-		}
+// txResultFromTendermint converts a Tendermint transaction execution result into a consensus
+// transaction result, extracting all events emitted during transaction processing.
+func txResultFromTendermint(tx []byte, height int64, rs *tmabcitypes.ResponseDeliverTx) (*results.Result, error) {
+	// Transaction result.
+	result := &results.Result{
+		Error: results.Error{
+			Module:  rs.GetCodespace(),
+			Code:    rs.GetCode(),
+			Message: rs.GetLog(),
+		},
+	}
 
-		// Transaction roothash events.
-		roothashEvents, err := tmroothash.EventsFromTendermint(
-			txsWithResults.Transactions[txIdx],
-			blk.Height,
-			rs.Events,
-		)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range roothashEvents {
-			result.Events = append(result.Events, &results.Event{RootHash: e})
-		}
+	// Transaction staking events.
+	stakingEvents, err := tmstaking.EventsFromTendermint(tx, height, rs.Events)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range stakingEvents {
+		result.Events = append(result.Events, &results.Event{Staking: e})
+	}
 
-		// Transaction governance events.
-		governanceEvents, err := tmgovernance.EventsFromTendermint(
-			txsWithResults.Transactions[txIdx],
-			blk.Height,
-			rs.Events,
-		)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range governanceEvents {
-			result.Events = append(result.Events, &results.Event{Governance: e})
-		}
+	// Transaction registry events.
+	registryEvents, _, err := tmregistry.EventsFromTendermint(tx, height, rs.Events)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range registryEvents {
+		result.Events = append(result.Events, &results.Event{Registry: e})
+	}
 
-		txsWithResults.Results = append(txsWithResults.Results, result)
+	// Transaction roothash events.
+	roothashEvents, err := tmroothash.EventsFromTendermint(tx, height, rs.Events)
+	if err != nil {
+		return nil, err
 	}
-	return &txsWithResults, nil
+	for _, e := range roothashEvents {
+		result.Events = append(result.Events, &results.Event{RootHash: e})
+	}
+
+	// Transaction governance events.
+	governanceEvents, err := tmgovernance.EventsFromTendermint(tx, height, rs.Events)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range governanceEvents {
+		result.Events = append(result.Events, &results.Event{Governance: e})
+	}
+
+	return result, nil
 }
 
 func (t *fullService) GetUnconfirmedTransactions(ctx context.Context) ([][]byte, error) {
@@ -1189,6 +1244,9 @@ func (t *fullService) lazyInit() error {
 	tenderConfig.Instrumentation.Prometheus = true
 	tenderConfig.Instrumentation.PrometheusListenAddr = ""
 	tenderConfig.TxIndex.Indexer = "null"
+	if viper.GetBool(CfgTxIndexEnabled) {
+		tenderConfig.TxIndex.Indexer = "kv"
+	}
 	tenderConfig.P2P.ListenAddress = viper.GetString(tmcommon.CfgCoreListenAddress)
 	tenderConfig.P2P.ExternalAddress = viper.GetString(tmcommon.CfgCoreExternalAddress)
 	tenderConfig.P2P.PexReactor = !viper.GetBool(CfgP2PDisablePeerExchange)
@@ -1270,9 +1328,9 @@ func (t *fullService) lazyInit() error {
 		return err
 	}
 
-	// HACK: Wrap the provider so we can extract the state database handle. This is required because
-	// Tendermint does not expose a way to access the state database and we need it to bypass some
-	// stupid things like pagination on the in-process "client".
+	// HACK: Wrap the provider so we can extract the state and transaction index database handles.
+	// This is required because Tendermint does not expose a way to access these databases and we
+	// need them to bypass some stupid things like pagination on the in-process "client".
 	wrapDbProvider := func(dbCtx *tmnode.DBContext) (tmdb.DB, error) {
 		db, derr := dbProvider(dbCtx)
 		if derr != nil {
@@ -1283,6 +1341,9 @@ func (t *fullService) lazyInit() error {
 		case "state":
 			// Tendermint state database.
 			t.stateStore = tmstate.NewStore(db)
+		case "tx_index":
+			// Tendermint transaction index database, only used if the indexer is enabled.
+			t.txIndexer = tmtxindexkv.NewTxIndex(db)
 		default:
 		}
 
@@ -1567,6 +1628,13 @@ func New(
 
 	t.Logger.Info("starting a full consensus node")
 
+	// Create the consensus parameters cache.
+	if size := viper.GetUint64(CfgParametersCacheSize); size > 0 {
+		if t.paramsCache, err = lru.New(lru.Capacity(size, false)); err != nil {
+			return nil, fmt.Errorf("tendermint: failed to create consensus parameters cache: %w", err)
+		}
+	}
+
 	// Create the submission manager.
 	pd, err := consensusAPI.NewStaticPriceDiscovery(viper.GetUint64(tmcommon.CfgSubmissionGasPrice))
 	if err != nil {
@@ -1587,6 +1655,7 @@ func init() {
 	Flags.StringSlice(CfgP2PPersistentPeer, []string{}, "Tendermint persistent peer(s) of the form ID@ip:port")
 	Flags.StringSlice(CfgP2PUnconditionalPeerIDs, []string{}, "Tendermint unconditional peer IDs")
 	Flags.Bool(CfgP2PDisablePeerExchange, false, "Disable Tendermint's peer-exchange reactor")
+	Flags.Bool(CfgTxIndexEnabled, false, "Enable Tendermint's transaction indexer")
 	Flags.Duration(CfgP2PPersistenPeersMaxDialPeriod, 0*time.Second, "Tendermint max timeout when redialing a persistent peer (default: unlimited)")
 	Flags.Uint64(CfgMinGasPrice, 0, "minimum gas price")
 	Flags.Bool(CfgDebugUnsafeReplayRecoverCorruptedWAL, false, "Enable automatic recovery from corrupted WAL during replay (UNSAFE).")
@@ -1603,6 +1672,8 @@ func init() {
 
 	Flags.Duration(CfgUpgradeStopDelay, 60*time.Second, "average amount of time to delay shutting down the node on upgrade")
 
+	Flags.Uint64(CfgParametersCacheSize, 128, "number of heights for which consensus parameters are cached (0 disables the cache)")
+
 	_ = Flags.MarkHidden(CfgDebugUnsafeReplayRecoverCorruptedWAL)
 
 	_ = Flags.MarkHidden(CfgSupplementarySanityEnabled)
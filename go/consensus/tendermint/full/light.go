@@ -1,18 +1,24 @@
 package full
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
 	tmtypes "github.com/tendermint/tendermint/types"
 
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	consensusAPI "github.com/oasisprotocol/oasis-core/go/consensus/api"
 	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
 	coreState "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/abci/state"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
 )
 
+// maxLightBlocksRange is the maximum number of light blocks that can be requested at once using
+// GetLightBlocks.
+const maxLightBlocksRange = 100
+
 // Implements LightClientBackend.
 func (t *fullService) GetLightBlock(ctx context.Context, height int64) (*consensusAPI.LightBlock, error) {
 	if err := t.ensureStarted(ctx); err != nil {
@@ -36,6 +42,208 @@ func (t *fullService) GetLightBlock(ctx context.Context, height int64) (*consens
 		lb.SignedHeader = &commit.SignedHeader
 		tmHeight = commit.Header.Height
 	}
+
+	return newLightBlock(tmHeight, &lb)
+}
+
+// GetLightBlockForTx returns the light block for the height at which the transaction with the
+// given hash has been included.
+//
+// This requires the transaction indexer to be enabled via CfgTxIndexEnabled. In case the
+// transaction has not been indexed, ErrTransactionNotFound is returned.
+func (t *fullService) GetLightBlockForTx(ctx context.Context, txHash hash.Hash) (*consensusAPI.LightBlock, error) {
+	if err := t.ensureStarted(ctx); err != nil {
+		return nil, err
+	}
+	if t.txIndexer == nil {
+		return nil, fmt.Errorf("%w: transaction indexer is disabled", consensusAPI.ErrUnsupported)
+	}
+
+	// Don't use the client as that does not distinguish missing transactions from other errors.
+	// Query the transaction index directly.
+	res, err := t.txIndexer.Get(txHash[:])
+	if err != nil {
+		return nil, fmt.Errorf("tendermint: transaction query failed: %w", err)
+	}
+	if res == nil {
+		return nil, consensusAPI.ErrTransactionNotFound
+	}
+
+	return t.GetLightBlock(ctx, res.Height)
+}
+
+// GetValidatorSet returns the validator set for the given height.
+func (t *fullService) GetValidatorSet(ctx context.Context, height int64) (*consensusAPI.ValidatorSet, error) {
+	if err := t.ensureStarted(ctx); err != nil {
+		return nil, err
+	}
+
+	tmHeight, err := t.heightToTendermintHeight(height)
+	if err != nil {
+		return nil, err
+	}
+
+	vals, err := t.stateStore.LoadValidators(tmHeight)
+	if err != nil {
+		return nil, consensusAPI.ErrVersionNotFound
+	}
+
+	return newValidatorSet(tmHeight, vals)
+}
+
+// GetLightBlocks returns the light blocks for all heights in the given inclusive range.
+//
+// The range may contain at most maxLightBlocksRange heights. In case any of the heights in the
+// range is not available, ErrVersionNotFound is returned for the whole call.
+func (t *fullService) GetLightBlocks(ctx context.Context, start, end int64) ([]*consensusAPI.LightBlock, error) {
+	if err := t.ensureStarted(ctx); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case start <= 0 || end < start:
+		return nil, fmt.Errorf("%w: invalid light block range [%d, %d]", consensusAPI.ErrInvalidArgument, start, end)
+	case end-start >= maxLightBlocksRange:
+		return nil, fmt.Errorf("%w: light block range too large (max: %d)", consensusAPI.ErrInvalidArgument, maxLightBlocksRange)
+	case end > t.mux.State().BlockHeight():
+		// Do not return blocks for which local state does not yet exist.
+		return nil, consensusAPI.ErrVersionNotFound
+	}
+
+	// Don't use the client as that would query each commit separately. Access the block and state
+	// databases directly.
+	blockStore := t.node.BlockStore()
+	lastHeight := blockStore.Height()
+
+	lbs := make([]*consensusAPI.LightBlock, 0, end-start+1)
+	for height := start; height <= end; height++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		vals, err := t.stateStore.LoadValidators(height)
+		if err != nil {
+			return nil, consensusAPI.ErrVersionNotFound
+		}
+		meta := blockStore.LoadBlockMeta(height)
+		if meta == nil {
+			return nil, consensusAPI.ErrVersionNotFound
+		}
+
+		// If the next block has not been committed yet, use the non-canonical seen commit.
+		var commit *tmtypes.Commit
+		if height == lastHeight {
+			commit = blockStore.LoadSeenCommit(height)
+		} else {
+			commit = blockStore.LoadBlockCommit(height)
+		}
+		if commit == nil {
+			return nil, consensusAPI.ErrVersionNotFound
+		}
+
+		lb, err := newLightBlock(height, &tmtypes.LightBlock{
+			SignedHeader: &tmtypes.SignedHeader{
+				Header: &meta.Header,
+				Commit: commit,
+			},
+			ValidatorSet: vals,
+		})
+		if err != nil {
+			return nil, err
+		}
+		lbs = append(lbs, lb)
+	}
+
+	return lbs, nil
+}
+
+// WatchLightBlocks returns a channel that streams light blocks starting at the given height.
+//
+// All light blocks from startHeight up to the latest height are emitted first, followed by light
+// blocks for new heights as they are committed. Heights are emitted in order without gaps. The
+// channel is closed when the context is canceled or in case a light block cannot be retrieved.
+func (t *fullService) WatchLightBlocks(ctx context.Context, startHeight int64) (<-chan *consensusAPI.LightBlock, error) {
+	if err := t.ensureStarted(ctx); err != nil {
+		return nil, err
+	}
+	if startHeight <= 0 {
+		return nil, fmt.Errorf("%w: invalid start height %d", consensusAPI.ErrInvalidArgument, startHeight)
+	}
+
+	// Subscribe to new blocks before catching up so that no heights are missed.
+	blkCh, sub := t.WatchTendermintBlocks()
+
+	ch := make(chan *consensusAPI.LightBlock)
+	go func() {
+		defer close(ch)
+		defer sub.Close()
+
+		next := startHeight
+		catchUp := func(height int64) error {
+			for next <= height {
+				end := next + maxLightBlocksRange - 1
+				if end > height {
+					end = height
+				}
+
+				lbs, err := t.GetLightBlocks(ctx, next, end)
+				if err != nil {
+					return err
+				}
+				for _, lb := range lbs {
+					select {
+					case ch <- lb:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				next = end + 1
+			}
+			return nil
+		}
+
+		height := t.mux.State().BlockHeight()
+		for {
+			if err := catchUp(height); err != nil {
+				if ctx.Err() == nil {
+					t.Logger.Error("failed to fetch light blocks",
+						"err", err,
+						"height", next,
+					)
+				}
+				return
+			}
+
+			select {
+			case blk, ok := <-blkCh:
+				if !ok {
+					return
+				}
+				height = blk.Height
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func newLightBlock(height int64, lb *tmtypes.LightBlock) (*consensusAPI.LightBlock, error) {
+	// Make sure that the validator set and the header are consistent so that any store
+	// inconsistency is detected early instead of producing an unverifiable light block.
+	if lb.SignedHeader != nil && lb.SignedHeader.Header != nil && lb.ValidatorSet != nil {
+		if valsHash := lb.ValidatorSet.Hash(); !bytes.Equal(valsHash, lb.SignedHeader.Header.ValidatorsHash) {
+			return nil, fmt.Errorf("tendermint: light block validator set hash mismatch at height %d (expected: %X got: %X)",
+				height,
+				lb.SignedHeader.Header.ValidatorsHash,
+				valsHash,
+			)
+		}
+	}
+
 	protoLb, err := lb.ToProto()
 	if err != nil {
 		return nil, fmt.Errorf("tendermint: failed to convert light block: %w", err)
@@ -53,7 +261,26 @@ func (t *fullService) GetLightBlock(ctx context.Context, height int64) (*consens
 	}
 
 	return &consensusAPI.LightBlock{
-		Height: tmHeight,
+		Height: height,
+		Meta:   meta,
+	}, nil
+}
+
+func newValidatorSet(height int64, vals *tmtypes.ValidatorSet) (*consensusAPI.ValidatorSet, error) {
+	protoVals, err := vals.ToProto()
+	if err != nil {
+		return nil, fmt.Errorf("tendermint: failed to convert validator set: %w", err)
+	}
+	// ToProto sets the TotalVotingPower to 0, see newLightBlock.
+	protoVals.TotalVotingPower = vals.TotalVotingPower()
+
+	meta, err := protoVals.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("tendermint: failed to marshal validator set: %w", err)
+	}
+
+	return &consensusAPI.ValidatorSet{
+		Height: height,
 		Meta:   meta,
 	}, nil
 }
@@ -68,6 +295,18 @@ func (t *fullService) GetParameters(ctx context.Context, height int64) (*consens
 	if err != nil {
 		return nil, err
 	}
+
+	if t.paramsCache != nil {
+		if cached, ok := t.paramsCache.Get(tmHeight); ok {
+			// Make sure the height has not been pruned in the meantime.
+			if !t.isHeightRetained(tmHeight) {
+				t.paramsCache.Remove(tmHeight)
+				return nil, consensusAPI.ErrVersionNotFound
+			}
+			return cached.(*consensusAPI.Parameters), nil
+		}
+	}
+
 	params, err := t.client.ConsensusParams(ctx, &tmHeight)
 	if err != nil {
 		return nil, fmt.Errorf("%w: tendermint: consensus params query failed: %s", consensusAPI.ErrVersionNotFound, err.Error())
@@ -77,7 +316,7 @@ func (t *fullService) GetParameters(ctx context.Context, height int64) (*consens
 		return nil, fmt.Errorf("tendermint: failed to marshal consensus params: %w", err)
 	}
 
-	cs, err := coreState.NewImmutableState(ctx, t.mux.State(), height)
+	cs, err := coreState.NewImmutableState(ctx, t.mux.State(), tmHeight)
 	if err != nil {
 		return nil, fmt.Errorf("tendermint: failed to initialize core consensus state: %w", err)
 	}
@@ -86,11 +325,28 @@ func (t *fullService) GetParameters(ctx context.Context, height int64) (*consens
 		return nil, fmt.Errorf("tendermint: failed to fetch core consensus parameters: %w", err)
 	}
 
-	return &consensusAPI.Parameters{
+	rsp := &consensusAPI.Parameters{
 		Height:     params.BlockHeight,
 		Parameters: *cp,
 		Meta:       meta,
-	}, nil
+	}
+	if t.paramsCache != nil {
+		_ = t.paramsCache.Put(tmHeight, rsp)
+	}
+	return rsp, nil
+}
+
+// isHeightRetained returns true iff both the Tendermint block store and the ABCI state still
+// contain the given height.
+func (t *fullService) isHeightRetained(height int64) bool {
+	if height < t.node.BlockStore().Base() {
+		return false
+	}
+	lastRetained, err := t.mux.State().LastRetainedVersion()
+	if err != nil {
+		return false
+	}
+	return height >= lastRetained
 }
 
 // Implements LightClientBackend.
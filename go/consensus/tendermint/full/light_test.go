@@ -0,0 +1,116 @@
+package full
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmstate "github.com/tendermint/tendermint/state"
+	tmtypes "github.com/tendermint/tendermint/types"
+	tmdb "github.com/tendermint/tm-db"
+
+	consensusAPI "github.com/oasisprotocol/oasis-core/go/consensus/api"
+)
+
+func TestNewLightBlock(t *testing.T) {
+	require := require.New(t)
+
+	newValidatorSet := func() *tmtypes.ValidatorSet {
+		return tmtypes.NewValidatorSet([]*tmtypes.Validator{
+			tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 10),
+		})
+	}
+	vals := newValidatorSet()
+	lb := &tmtypes.LightBlock{
+		SignedHeader: &tmtypes.SignedHeader{
+			Header: &tmtypes.Header{
+				Height:         42,
+				ValidatorsHash: vals.Hash(),
+			},
+			Commit: &tmtypes.Commit{Height: 42},
+		},
+		ValidatorSet: vals,
+	}
+
+	clb, err := newLightBlock(42, lb)
+	require.NoError(err, "newLightBlock")
+	require.EqualValues(42, clb.Height)
+	require.NotEmpty(clb.Meta)
+
+	// A mismatched validator set should be rejected.
+	lb.ValidatorSet = newValidatorSet()
+	_, err = newLightBlock(42, lb)
+	require.Error(err, "newLightBlock should fail with a mismatched validator set")
+	require.Contains(err.Error(), "validator set hash mismatch")
+
+	// Light blocks without a signed header should not be checked.
+	lb.SignedHeader = nil
+	_, err = newLightBlock(42, lb)
+	require.NoError(err, "newLightBlock without a signed header")
+}
+
+func TestNewValidatorSet(t *testing.T) {
+	require := require.New(t)
+
+	vals := tmtypes.NewValidatorSet([]*tmtypes.Validator{
+		tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 10),
+		tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 20),
+	})
+
+	vs, err := newValidatorSet(42, vals)
+	require.NoError(err, "newValidatorSet")
+	require.EqualValues(42, vs.Height)
+
+	var protoVals tmproto.ValidatorSet
+	require.NoError(protoVals.Unmarshal(vs.Meta), "Unmarshal")
+	require.EqualValues(30, protoVals.TotalVotingPower, "total voting power should be set")
+	require.Len(protoVals.Validators, 2)
+}
+
+func TestGetValidatorSet(t *testing.T) {
+	require := require.New(t)
+
+	vals := tmtypes.NewValidatorSet([]*tmtypes.Validator{
+		tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 10),
+	})
+	stateStore := tmstate.NewStore(tmdb.NewMemDB())
+	for height := int64(0); height < 10; height++ {
+		err := stateStore.Save(tmstate.State{
+			InitialHeight:               1,
+			LastBlockHeight:             height,
+			Validators:                  vals,
+			NextValidators:              vals,
+			LastHeightValidatorsChanged: 1,
+		})
+		require.NoError(err, "Save")
+	}
+
+	startedCh := make(chan struct{})
+	close(startedCh)
+	srv := &fullService{
+		ctx:        context.Background(),
+		stateStore: stateStore,
+		startedCh:  startedCh,
+	}
+	ctx := context.Background()
+
+	vs, err := srv.GetValidatorSet(ctx, 3)
+	require.NoError(err, "GetValidatorSet")
+	require.EqualValues(3, vs.Height)
+	var protoVals tmproto.ValidatorSet
+	require.NoError(protoVals.Unmarshal(vs.Meta), "Unmarshal")
+	require.EqualValues(10, protoVals.TotalVotingPower)
+
+	_, err = srv.GetValidatorSet(ctx, 100)
+	require.ErrorIs(err, consensusAPI.ErrVersionNotFound, "GetValidatorSet should fail for missing heights")
+
+	// Pruned heights should no longer be available.
+	require.NoError(stateStore.PruneStates(1, 5), "PruneStates")
+	_, err = srv.GetValidatorSet(ctx, 3)
+	require.ErrorIs(err, consensusAPI.ErrVersionNotFound, "GetValidatorSet should fail for pruned heights")
+	vs, err = srv.GetValidatorSet(ctx, 5)
+	require.NoError(err, "GetValidatorSet should succeed for retained heights")
+	require.EqualValues(5, vs.Height)
+}
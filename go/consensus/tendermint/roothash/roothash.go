@@ -19,6 +19,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	consensusAPI "github.com/oasisprotocol/oasis-core/go/consensus/api"
 	eventsAPI "github.com/oasisprotocol/oasis-core/go/consensus/api/events"
 	tmapi "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/api"
 	app "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/apps/roothash"
@@ -28,7 +29,12 @@ import (
 	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
 )
 
-const crashPointBlockBeforeIndex = "roothash.before_index"
+const (
+	crashPointBlockBeforeIndex = "roothash.before_index"
+
+	// maxRecentEvents is the number of recent events retained per runtime for WatchEventsSince.
+	maxRecentEvents = 1024
+)
 
 // ServiceClient is the roothash service client interface.
 type ServiceClient interface {
@@ -44,6 +50,35 @@ type runtimeBrokers struct {
 
 	lastBlockHeight int64
 	lastBlock       *block.Block
+
+	lastEventSeq uint64
+	recentEvents []*api.Event
+}
+
+// publishEvent assigns the next sequence number to the event, retains it and broadcasts it.
+func (rb *runtimeBrokers) publishEvent(ev *api.Event) {
+	rb.Lock()
+	rb.lastEventSeq++
+	ev.Sequence = rb.lastEventSeq
+	rb.recentEvents = append(rb.recentEvents, ev)
+	if len(rb.recentEvents) > maxRecentEvents {
+		rb.recentEvents = append([]*api.Event(nil), rb.recentEvents[len(rb.recentEvents)-maxRecentEvents:]...)
+	}
+	rb.Unlock()
+
+	rb.eventNotifier.Broadcast(ev)
+}
+
+// checkEventsSinceLocked checks whether all events following the given sequence number are
+// still retained.
+func (rb *runtimeBrokers) checkEventsSinceLocked(seq uint64) error {
+	if seq > rb.lastEventSeq {
+		return api.ErrInvalidArgument
+	}
+	if len(rb.recentEvents) > 0 && seq+1 < rb.recentEvents[0].Sequence {
+		return api.ErrEventsPruned
+	}
+	return nil
 }
 
 type trackedRuntime struct {
@@ -100,6 +135,10 @@ func (sc *serviceClient) GetGenesisBlock(ctx context.Context, request *api.Runti
 	if err != nil {
 		return nil, err
 	}
+	if blk == nil {
+		// The runtime does not have any blocks yet.
+		return nil, api.ErrNotFound
+	}
 
 	// Update the genesis block cache.
 	sc.Lock()
@@ -123,6 +162,42 @@ func (sc *serviceClient) getLatestBlockAt(ctx context.Context, runtimeID common.
 	return q.LatestBlock(ctx, runtimeID)
 }
 
+// Implements api.Backend.
+func (sc *serviceClient) GetEarliestRound(ctx context.Context, request *api.RuntimeRequest) (uint64, error) {
+	// This also makes sure that the runtime exists.
+	latestBlk, err := sc.getLatestBlockAt(ctx, request.RuntimeID, request.Height)
+	if err != nil {
+		return 0, err
+	}
+
+	bh := sc.getBlockHistory(request.RuntimeID)
+	if bh == nil {
+		// Only the latest block is retained in consensus state.
+		return latestBlk.Header.Round, nil
+	}
+
+	blk, err := bh.GetEarliestBlock(ctx)
+	switch err {
+	case nil:
+		return blk.Header.Round, nil
+	case api.ErrNotFound:
+		// Block history is still empty.
+		return latestBlk.Header.Round, nil
+	default:
+		return 0, err
+	}
+}
+
+func (sc *serviceClient) getBlockHistory(runtimeID common.Namespace) api.BlockHistory {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	if tr := sc.trackedRuntime[runtimeID]; tr != nil {
+		return tr.blockHistory
+	}
+	return nil
+}
+
 // Implements api.Backend.
 func (sc *serviceClient) GetRuntimeState(ctx context.Context, request *api.RuntimeRequest) (*api.RuntimeState, error) {
 	q, err := sc.querier.QueryAt(ctx, request.Height)
@@ -212,6 +287,137 @@ func (sc *serviceClient) WatchBlocks(ctx context.Context, id common.Namespace) (
 	return monotonicCh, sub, nil
 }
 
+// Implements api.Backend.
+func (sc *serviceClient) WatchBlocksRange(ctx context.Context, request *api.WatchBlocksRangeRequest) (<-chan *api.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	if request.End < request.Start {
+		return nil, nil, api.ErrInvalidArgument
+	}
+
+	return sc.watchBlocksRange(ctx, request.RuntimeID, request.Start, request.End, api.ErrNotFound)
+}
+
+// Implements api.Backend.
+func (sc *serviceClient) WatchBlocksSinceChecked(ctx context.Context, request *api.WatchBlocksSinceRequest) (<-chan *api.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	return sc.watchBlocksRange(ctx, request.RuntimeID, request.Round, math.MaxUint64, api.ErrRoundPruned)
+}
+
+// watchBlocksRange watches blocks for rounds in the range [start, end] (inclusive). In case the
+// start round is no longer retained, errPruned is returned.
+func (sc *serviceClient) watchBlocksRange(
+	ctx context.Context,
+	runtimeID common.Namespace,
+	start, end uint64,
+	errPruned error,
+) (<-chan *api.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	// Make sure that finalized blocks in the range are available in block history.
+	latestBlk, err := sc.getLatestBlockAt(ctx, runtimeID, consensusAPI.HeightLatest)
+	if err != nil {
+		return nil, nil, err
+	}
+	bh := sc.getBlockHistory(runtimeID)
+	if start < latestBlk.Header.Round {
+		if bh == nil {
+			return nil, nil, errPruned
+		}
+		earliestBlk, err := bh.GetEarliestBlock(ctx)
+		switch err {
+		case nil:
+		case api.ErrNotFound:
+			// Block history is still empty.
+			return nil, nil, errPruned
+		default:
+			return nil, nil, err
+		}
+		if start < earliestBlk.Header.Round {
+			return nil, nil, errPruned
+		}
+	}
+
+	blkCh, blkSub, err := sc.WatchBlocks(ctx, runtimeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+	ch := make(chan *api.AnnotatedBlock)
+	go func() {
+		defer close(ch)
+		defer func() {
+			blkSub.Close()
+			// Drain the channel so that the block watcher can terminate.
+			for range blkCh {
+			}
+		}()
+
+		send := func(blk *api.AnnotatedBlock) bool {
+			select {
+			case ch <- blk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		next := start
+		for {
+			var blk *api.AnnotatedBlock
+			select {
+			case blk = <-blkCh:
+				if blk == nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			round := blk.Block.Header.Round
+			if round < next {
+				continue
+			}
+
+			// Deliver any missing blocks from block history.
+			for ; next < round && next <= end; next++ {
+				if bh == nil {
+					bh = sc.getBlockHistory(runtimeID)
+				}
+				if bh == nil {
+					sc.logger.Error("block history not available",
+						"runtime_id", runtimeID,
+						"round", next,
+					)
+					return
+				}
+
+				histBlk, err := bh.GetAnnotatedBlock(ctx, next)
+				if err != nil {
+					sc.logger.Error("failed to get block from history",
+						"err", err,
+						"runtime_id", runtimeID,
+						"round", next,
+					)
+					return
+				}
+				if !send(histBlk) {
+					return
+				}
+			}
+			if next > end {
+				return
+			}
+
+			if !send(blk) {
+				return
+			}
+			if round == end {
+				return
+			}
+			next = round + 1
+		}
+	}()
+
+	return ch, sub, nil
+}
+
 func (sc *serviceClient) WatchAllBlocks() (<-chan *block.Block, *pubsub.Subscription) {
 	sub := sc.allBlockNotifier.Subscribe()
 	ch := make(chan *block.Block)
@@ -220,15 +426,43 @@ func (sc *serviceClient) WatchAllBlocks() (<-chan *block.Block, *pubsub.Subscrip
 	return ch, sub
 }
 
+func (sc *serviceClient) WatchAllBlocksForRuntimes(ids []common.Namespace) (<-chan *block.Block, *pubsub.Subscription) {
+	if len(ids) == 0 {
+		return sc.WatchAllBlocks()
+	}
+
+	runtimes := make(map[common.Namespace]bool, len(ids))
+	for _, id := range ids {
+		runtimes[id] = true
+	}
+	sub := sc.allBlockNotifier.SubscribeFiltered(0, func(v interface{}) bool {
+		return runtimes[v.(*block.Block).Header.Namespace]
+	})
+	ch := make(chan *block.Block)
+	sub.Unwrap(ch)
+
+	return ch, sub
+}
+
 // Implements api.Backend.
 func (sc *serviceClient) WatchEvents(ctx context.Context, id common.Namespace) (<-chan *api.Event, pubsub.ClosableSubscription, error) {
-	notifiers := sc.getRuntimeNotifiers(id)
-	sub := notifiers.eventNotifier.Subscribe()
+	return sc.WatchEventsFiltered(ctx, &api.WatchEventsFilteredRequest{
+		RuntimeID: id,
+		Mask:      api.EventKindAll,
+	})
+}
+
+// Implements api.Backend.
+func (sc *serviceClient) WatchEventsFiltered(ctx context.Context, request *api.WatchEventsFilteredRequest) (<-chan *api.Event, pubsub.ClosableSubscription, error) {
+	notifiers := sc.getRuntimeNotifiers(request.RuntimeID)
+	sub := notifiers.eventNotifier.SubscribeFiltered(0, func(v interface{}) bool {
+		return request.Mask.Matches(v.(*api.Event))
+	})
 	ch := make(chan *api.Event)
 	sub.Unwrap(ch)
 
 	// Start tracking this runtime if we are not tracking it yet.
-	if err := sc.trackRuntime(sc.ctx, id, nil); err != nil {
+	if err := sc.trackRuntime(sc.ctx, request.RuntimeID, nil); err != nil {
 		sub.Close()
 		return nil, nil, err
 	}
@@ -236,6 +470,56 @@ func (sc *serviceClient) WatchEvents(ctx context.Context, id common.Namespace) (
 	return ch, sub, nil
 }
 
+// Implements api.Backend.
+func (sc *serviceClient) WatchEventsSince(ctx context.Context, request *api.WatchEventsSinceRequest) (<-chan *api.Event, pubsub.ClosableSubscription, error) {
+	notifiers := sc.getRuntimeNotifiers(request.RuntimeID)
+
+	var err error
+	sub := notifiers.eventNotifier.SubscribeEx(-1, func(ch channels.Channel) {
+		// Replay retained events following the given sequence number.
+		notifiers.Lock()
+		defer notifiers.Unlock()
+		if err = notifiers.checkEventsSinceLocked(request.Sequence); err != nil {
+			return
+		}
+		for _, ev := range notifiers.recentEvents {
+			if ev.Sequence > request.Sequence {
+				ch.In() <- ev
+			}
+		}
+	})
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+	ch := make(chan *api.Event)
+	sub.Unwrap(ch)
+
+	// Make sure that we only ever emit each event once. Without special handling this can happen
+	// for events that were broadcast while being replayed (see above).
+	lastSeq := request.Sequence
+	seqCh := make(chan *api.Event)
+	go func() {
+		defer close(seqCh)
+
+		for ev := range ch {
+			if ev.Sequence <= lastSeq {
+				continue
+			}
+			lastSeq = ev.Sequence
+			seqCh <- ev
+		}
+	}()
+
+	// Start tracking this runtime if we are not tracking it yet.
+	if err = sc.trackRuntime(sc.ctx, request.RuntimeID, nil); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	return seqCh, sub, nil
+}
+
 // Implements api.Backend.
 func (sc *serviceClient) TrackRuntime(ctx context.Context, history api.BlockHistory) error {
 	sc.pruneHandler.trackRuntime(history)
@@ -520,7 +804,9 @@ func (sc *serviceClient) DeliverCommand(ctx context.Context, height int64, cmd i
 			runtimeID:    c.runtimeID,
 			blockHistory: c.blockHistory,
 		}
+		sc.Lock()
 		sc.trackedRuntime[c.runtimeID] = tr
+		sc.Unlock()
 		// Request subscription to events for this runtime.
 		sc.queryCh <- app.QueryForRuntime(tr.runtimeID)
 
@@ -551,7 +837,7 @@ func (sc *serviceClient) DeliverEvent(ctx context.Context, height int64, tx tmty
 		// Notify non-finalized events.
 		if ev.Finalized == nil {
 			notifiers := sc.getRuntimeNotifiers(ev.RuntimeID)
-			notifiers.eventNotifier.Broadcast(ev)
+			notifiers.publishEvent(ev)
 			continue
 		}
 
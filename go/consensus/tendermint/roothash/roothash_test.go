@@ -0,0 +1,133 @@
+package roothash
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	tmapi "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/api"
+	app "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/apps/roothash"
+	roothashState "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/apps/roothash/state"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/history"
+)
+
+// newTestServiceClient creates a service client backed by the given mock application state.
+//
+// Queries must be performed using a context derived from the mock application state.
+func newTestServiceClient(appState tmapi.MockApplicationState) *serviceClient {
+	return &serviceClient{
+		logger:         logging.GetLogger("consensus/tendermint/roothash/test"),
+		querier:        app.NewQueryFactory(appState),
+		genesisBlocks:  make(map[common.Namespace]*block.Block),
+		trackedRuntime: make(map[common.Namespace]*trackedRuntime),
+	}
+}
+
+func TestGetGenesisBlock(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1580461674, 0)
+	appState := tmapi.NewMockApplicationState(&tmapi.MockApplicationStateConfig{})
+	ctx := appState.NewContext(tmapi.ContextInitChain, now)
+	defer ctx.Close()
+
+	sc := newTestServiceClient(appState)
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("tendermint/roothash test: genesis block"), 0)
+	request := &api.RuntimeRequest{RuntimeID: runtimeID, Height: 1}
+
+	_, err := sc.GetGenesisBlock(ctx, request)
+	require.ErrorIs(err, api.ErrInvalidRuntime, "GetGenesisBlock should fail for unknown runtimes")
+
+	// Runtimes without a genesis block should be reported as not found.
+	genesisBlock := block.NewGenesisBlock(runtimeID, 0)
+	state := roothashState.NewMutableState(ctx.State())
+	err = state.SetRuntimeState(ctx, &api.RuntimeState{
+		Runtime:      &registry.Runtime{ID: runtimeID},
+		CurrentBlock: genesisBlock,
+	})
+	require.NoError(err, "SetRuntimeState")
+
+	_, err = sc.GetGenesisBlock(ctx, request)
+	require.ErrorIs(err, api.ErrNotFound, "GetGenesisBlock should fail for runtimes without blocks")
+
+	err = state.SetRuntimeState(ctx, &api.RuntimeState{
+		Runtime:      &registry.Runtime{ID: runtimeID},
+		GenesisBlock: genesisBlock,
+		CurrentBlock: genesisBlock,
+	})
+	require.NoError(err, "SetRuntimeState")
+
+	blk, err := sc.GetGenesisBlock(ctx, request)
+	require.NoError(err, "GetGenesisBlock")
+	require.EqualValues(genesisBlock, blk, "retrieved block is genesis block")
+}
+
+func TestGetEarliestRound(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1580461674, 0)
+	appState := tmapi.NewMockApplicationState(&tmapi.MockApplicationStateConfig{})
+	ctx := appState.NewContext(tmapi.ContextInitChain, now)
+	defer ctx.Close()
+
+	sc := newTestServiceClient(appState)
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("tendermint/roothash test: earliest round"), 0)
+	request := &api.RuntimeRequest{RuntimeID: runtimeID, Height: 1}
+
+	genesisBlock := block.NewGenesisBlock(runtimeID, 0)
+	latestBlock := block.NewEmptyBlock(genesisBlock, 0, block.Normal)
+	latestBlock.Header.Round = 20
+	state := roothashState.NewMutableState(ctx.State())
+	err := state.SetRuntimeState(ctx, &api.RuntimeState{
+		Runtime:      &registry.Runtime{ID: runtimeID},
+		GenesisBlock: genesisBlock,
+		CurrentBlock: latestBlock,
+	})
+	require.NoError(err, "SetRuntimeState")
+
+	// Block history is not tracked so only the latest block should be retained.
+	round, err := sc.GetEarliestRound(ctx, request)
+	require.NoError(err, "GetEarliestRound")
+	require.EqualValues(20, round, "earliest round should be the latest round")
+
+	// Track block history.
+	dataDir, err := ioutil.TempDir("", "oasis-tendermint-roothash-test_")
+	require.NoError(err, "TempDir")
+	defer os.RemoveAll(dataDir)
+
+	bh, err := history.New(dataDir, runtimeID, history.NewDefaultConfig())
+	require.NoError(err, "history.New")
+	defer bh.Close()
+	sc.trackedRuntime[runtimeID] = &trackedRuntime{
+		runtimeID:    runtimeID,
+		blockHistory: bh,
+	}
+
+	// Block history is still empty so only the latest block should be retained.
+	round, err = sc.GetEarliestRound(ctx, request)
+	require.NoError(err, "GetEarliestRound")
+	require.EqualValues(20, round, "earliest round should be the latest round")
+
+	// Earlier rounds have been pruned from block history.
+	for r := uint64(15); r <= 20; r++ {
+		blk := block.NewEmptyBlock(genesisBlock, 0, block.Normal)
+		blk.Header.Round = r
+		err = bh.Commit(&api.AnnotatedBlock{Height: int64(r), Block: blk}, &api.RoundResults{})
+		require.NoError(err, "Commit")
+	}
+
+	round, err = sc.GetEarliestRound(ctx, request)
+	require.NoError(err, "GetEarliestRound")
+	require.EqualValues(15, round, "earliest round should be the earliest round in block history")
+
+	_, err = sc.GetEarliestRound(ctx, &api.RuntimeRequest{RuntimeID: common.Namespace{}, Height: 1})
+	require.ErrorIs(err, api.ErrInvalidRuntime, "GetEarliestRound should fail for unknown runtimes")
+}
@@ -5,7 +5,6 @@ import (
 	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
-	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/common/sgx"
 	cmnIAS "github.com/oasisprotocol/oasis-core/go/common/sgx/ias"
@@ -58,12 +57,12 @@ func (st *enclaveStore) addRuntime(runtime *registry.Runtime) (int, error) {
 	// Regenerate the enclave ID list by iterating over all of the deployments.
 	var enclaveIDs []sgx.EnclaveIdentity
 	for _, deployment := range runtime.Deployments {
-		var cs node.SGXConstraints
-		if err := cbor.Unmarshal(deployment.TEE, &cs); err != nil {
+		tc, err := node.DecodeTEEConstraints(deployment.TEE, node.TEEHardwareIntelSGX)
+		if err != nil {
 			return len(st.enclaves), err
 		}
 
-		enclaveIDs = append(enclaveIDs, cs.Enclaves...)
+		enclaveIDs = append(enclaveIDs, tc.SGX.Enclaves...)
 	}
 
 	st.enclaves[runtime.ID] = enclaveIDs
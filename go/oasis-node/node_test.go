@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -21,9 +23,12 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	fileSigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/file"
 	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	cmnErrors "github.com/oasisprotocol/oasis-core/go/common/errors"
 	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
 	"github.com/oasisprotocol/oasis-core/go/common/identity"
 	consensusAPI "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
+	tendermintAPI "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/api"
 	tendermintCommon "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/common"
 	tendermintFull "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/full"
 	tmTestGenesis "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/tests/genesis"
@@ -75,6 +80,7 @@ var (
 		{tendermintCommon.CfgCoreListenAddress, "tcp://0.0.0.0:27565"},
 		{tendermintFull.CfgSupplementarySanityEnabled, true},
 		{tendermintFull.CfgSupplementarySanityInterval, 1},
+		{tendermintFull.CfgTxIndexEnabled, true},
 		{cmdCommon.CfgDebugAllowTestKeys, true},
 	}
 
@@ -258,6 +264,7 @@ func TestNode(t *testing.T) {
 
 		{"Consensus", testConsensus},
 		{"ConsensusClient", testConsensusClient},
+		{"ConsensusTendermint", testConsensusTendermint},
 
 		{"Beacon", testBeacon},
 		{"Storage", testStorage},
@@ -305,6 +312,183 @@ func testConsensus(t *testing.T, node *testNode) {
 	consensusTests.ConsensusImplementationTests(t, node.Consensus)
 }
 
+func testConsensusTendermint(t *testing.T, node *testNode) {
+	tmBackend, ok := node.Consensus.(tendermintAPI.Backend)
+	require.True(t, ok, "consensus backend should be a Tendermint backend")
+	testTendermintLightBlocks(t, tmBackend)
+	testTendermintParametersCache(t, tmBackend)
+	testTendermintSubmitTxWaitInclusion(t, tmBackend)
+	testTendermintSubmitTxChecked(t, tmBackend)
+	testTendermintLightBlockForTx(t, tmBackend)
+}
+
+// newTestEntityRegisterTx creates a signed transaction that re-registers the (already registered)
+// test entity, using the current nonce of the test entity's account increased by nonceOffset.
+func newTestEntityRegisterTx(t *testing.T, backend tendermintAPI.Backend, nonceOffset uint64) *transaction.SignedTransaction {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ent, signer, _ := entity.TestEntity()
+	signedEnt, err := entity.SignEntity(signer, registry.RegisterEntitySignatureContext, ent)
+	require.NoError(err, "SignEntity")
+	tx := registry.NewRegisterEntityTx(0, nil, signedEnt)
+	nonce, err := backend.GetSignerNonce(ctx, &consensusAPI.GetSignerNonceRequest{
+		AccountAddress: staking.NewAddress(signer.Public()),
+		Height:         consensusAPI.HeightLatest,
+	})
+	require.NoError(err, "GetSignerNonce")
+	tx.Nonce = nonce + nonceOffset
+	err = backend.SubmissionManager().EstimateGasAndSetFee(ctx, signer, tx)
+	require.NoError(err, "EstimateGasAndSetFee")
+	sigTx, err := transaction.Sign(signer, tx)
+	require.NoError(err, "Sign")
+
+	return sigTx
+}
+
+func testTendermintSubmitTxChecked(t *testing.T, backend tendermintAPI.Backend) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// Re-register the (already registered) test entity using an invalid nonce.
+	sigTx := newTestEntityRegisterTx(t, backend, 100)
+
+	err := backend.SubmitTxChecked(ctx, sigTx)
+	require.ErrorIs(err, transaction.ErrInvalidNonce, "SubmitTxChecked should fail with invalid nonce")
+	var checkErr *tendermintAPI.CheckTxError
+	require.ErrorAs(err, &checkErr, "SubmitTxChecked should return a CheckTx error")
+	module, code := cmnErrors.Code(transaction.ErrInvalidNonce)
+	require.Equal(module, checkErr.Module, "CheckTx error should include the module")
+	require.Equal(code, checkErr.Code, "CheckTx error should include the code")
+
+	unconfirmed, err := backend.GetUnconfirmedTransactions(ctx)
+	require.NoError(err, "GetUnconfirmedTransactions")
+	require.NotContains(unconfirmed, cbor.Marshal(sigTx), "rejected transaction should not be in the mempool")
+}
+
+func testTendermintSubmitTxWaitInclusion(t *testing.T, backend tendermintAPI.Backend) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// Transactions rejected by CheckTx should fail immediately.
+	_, err := backend.SubmitTxWaitInclusion(ctx, &transaction.SignedTransaction{})
+	require.Error(err, "SubmitTxWaitInclusion should fail with invalid transaction")
+
+	// Re-register the (already registered) test entity.
+	sigTx := newTestEntityRegisterTx(t, backend, 0)
+
+	result, err := backend.SubmitTxWaitInclusion(ctx, sigTx)
+	require.NoError(err, "SubmitTxWaitInclusion")
+	require.True(result.Result.IsSuccess(), "transaction should succeed")
+	require.NotEmpty(result.Result.Events, "transaction result should contain events")
+
+	txs, err := backend.GetTransactionsWithResults(ctx, result.Height)
+	require.NoError(err, "GetTransactionsWithResults")
+	require.True(int(result.Index) < len(txs.Transactions), "transaction index should be valid")
+	require.EqualValues(cbor.Marshal(sigTx), txs.Transactions[result.Index], "transaction should be included")
+	require.Equal(txs.Results[result.Index], result.Result, "transaction result should match")
+}
+
+func testTendermintLightBlockForTx(t *testing.T, backend tendermintAPI.Backend) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// Re-register the (already registered) test entity.
+	sigTx := newTestEntityRegisterTx(t, backend, 0)
+
+	result, err := backend.SubmitTxWaitInclusion(ctx, sigTx)
+	require.NoError(err, "SubmitTxWaitInclusion")
+
+	// Transactions are indexed asynchronously after the block has been committed.
+	var lb *consensusAPI.LightBlock
+	for retries := 0; ; retries++ {
+		lb, err = backend.GetLightBlockForTx(ctx, sha256.Sum256(cbor.Marshal(sigTx)))
+		if !errors.Is(err, consensusAPI.ErrTransactionNotFound) || retries >= 10 {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	require.NoError(err, "GetLightBlockForTx")
+	require.Equal(result.Height, lb.Height, "light block should be for the inclusion height")
+	single, err := backend.GetLightBlock(ctx, result.Height)
+	require.NoError(err, "GetLightBlock")
+	require.Equal(single.Meta, lb.Meta, "light block should match GetLightBlock")
+
+	_, err = backend.GetLightBlockForTx(ctx, sha256.Sum256([]byte("not a transaction")))
+	require.ErrorIs(err, consensusAPI.ErrTransactionNotFound, "GetLightBlockForTx should fail for unknown transactions")
+}
+
+func testTendermintParametersCache(t *testing.T, backend tendermintAPI.Backend) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	blk, err := backend.GetBlock(ctx, consensusAPI.HeightLatest)
+	require.NoError(err, "GetBlock")
+
+	// Repeated queries for the same height should return the same (cached) parameters.
+	params, err := backend.GetParameters(ctx, blk.Height)
+	require.NoError(err, "GetParameters")
+	cached, err := backend.GetParameters(ctx, blk.Height)
+	require.NoError(err, "GetParameters (cached)")
+	require.Equal(params, cached, "cached parameters should be the same")
+
+	_, err = backend.GetParameters(ctx, blk.Height+100)
+	require.ErrorIs(err, consensusAPI.ErrVersionNotFound, "GetParameters should fail for missing heights")
+}
+
+func testTendermintLightBlocks(t *testing.T, backend tendermintAPI.Backend) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	blk, err := backend.GetBlock(ctx, consensusAPI.HeightLatest)
+	require.NoError(err, "GetBlock")
+	require.True(blk.Height > 1, "test requires multiple blocks")
+
+	lbs, err := backend.GetLightBlocks(ctx, blk.Height-1, blk.Height)
+	require.NoError(err, "GetLightBlocks")
+	require.Len(lbs, 2, "GetLightBlocks should return all blocks in range")
+	for i, lb := range lbs {
+		require.Equal(blk.Height-1+int64(i), lb.Height, "light block height should be correct")
+		require.NotNil(lb.Meta, "light block should contain metadata")
+
+		single, err := backend.GetLightBlock(ctx, lb.Height)
+		require.NoError(err, "GetLightBlock")
+		require.Equal(single.Meta, lb.Meta, "light block should match GetLightBlock")
+	}
+
+	vs, err := backend.GetValidatorSet(ctx, blk.Height)
+	require.NoError(err, "GetValidatorSet")
+	require.Equal(blk.Height, vs.Height, "validator set height should be correct")
+	_, err = backend.GetValidatorSet(ctx, blk.Height+100)
+	require.ErrorIs(err, consensusAPI.ErrVersionNotFound, "GetValidatorSet should fail for missing heights")
+
+	_, err = backend.GetLightBlocks(ctx, blk.Height, blk.Height-1)
+	require.ErrorIs(err, consensusAPI.ErrInvalidArgument, "GetLightBlocks should fail for invalid ranges")
+	_, err = backend.GetLightBlocks(ctx, 1, 1<<20)
+	require.ErrorIs(err, consensusAPI.ErrInvalidArgument, "GetLightBlocks should fail for too large ranges")
+	_, err = backend.GetLightBlocks(ctx, blk.Height+100, blk.Height+101)
+	require.ErrorIs(err, consensusAPI.ErrVersionNotFound, "GetLightBlocks should fail for missing heights")
+
+	// Watch light blocks from a past height and make sure we follow the chain without gaps.
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, err := backend.WatchLightBlocks(watchCtx, blk.Height-1)
+	require.NoError(err, "WatchLightBlocks")
+	for height := blk.Height - 1; height <= blk.Height+2; height++ {
+		select {
+		case lb, ok := <-ch:
+			require.True(ok, "light block channel should not be closed")
+			require.Equal(height, lb.Height, "light blocks should be streamed in order without gaps")
+			require.NotNil(lb.Meta, "light block should contain metadata")
+		case <-time.After(10 * time.Second):
+			t.Fatalf("failed to receive light block at height %d", height)
+		}
+	}
+
+	_, err = backend.WatchLightBlocks(ctx, 0)
+	require.ErrorIs(err, consensusAPI.ErrInvalidArgument, "WatchLightBlocks should fail for invalid heights")
+}
+
 func testConsensusClient(t *testing.T, node *testNode) {
 	// Create a client backend connected to the local node's internal socket.
 	conn, err := cmnGrpc.Dial("unix:"+filepath.Join(node.dataDir, "internal.sock"),
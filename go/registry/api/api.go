@@ -639,6 +639,17 @@ func VerifyRegisterNodeArgs( // nolint: gocyclo
 		if n.VRF == nil {
 			return nil, nil, fmt.Errorf("%w: registration missing VRF ID", ErrInvalidArgument)
 		}
+		// Like the strict descriptor version check, only enforce this for new
+		// registrations and not for descriptors already persisted in state.
+		if !isSanityCheck {
+			if err := n.CheckVRF(); err != nil {
+				logger.Error("RegisterNode: invalid VRF info",
+					"node", n,
+					"err", err,
+				)
+				return nil, nil, fmt.Errorf("%w: %s", ErrInvalidArgument, err)
+			}
+		}
 		if n.DeprecatedBeacon != nil {
 			return nil, nil, fmt.Errorf("%w: registration contains deprecated PVSS field", ErrInvalidArgument)
 		}
@@ -1098,6 +1109,17 @@ func VerifyRuntime( // nolint: gocyclo
 		return fmt.Errorf("%w: runtime governance model is not enabled: %s", ErrForbidden, rt.GovernanceModel.String())
 	}
 
+	// Make sure the specified scheduling constraints are allowed.
+	if !params.EnableMinSoftwareVersionConstraint {
+		for _, roles := range rt.Constraints {
+			for _, cs := range roles {
+				if cs.MinSoftwareVersion != nil {
+					return fmt.Errorf("%w: min software version constraint is not enabled", ErrForbidden)
+				}
+			}
+		}
+	}
+
 	// Ensure a valid TEE hardware is specified.
 	if rt.TEEHardware >= node.TEEHardwareReserved {
 		logger.Error("RegisterRuntime: invalid TEE hardware specified",
@@ -1411,6 +1433,10 @@ type ConsensusParameters struct {
 
 	// EnableRuntimeGovernanceModels is a set of enabled runtime governance models.
 	EnableRuntimeGovernanceModels map[RuntimeGovernanceModel]bool `json:"enable_runtime_governance_models,omitempty"`
+
+	// EnableMinSoftwareVersionConstraint is true iff runtimes are allowed to specify the minimum
+	// software version scheduling constraint and the scheduler should enforce it.
+	EnableMinSoftwareVersionConstraint bool `json:"enable_min_software_version_constraint,omitempty"`
 }
 
 const (
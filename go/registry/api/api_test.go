@@ -3,14 +3,18 @@ package api
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
 )
 
 type mockRuntimeLookup struct {
@@ -167,3 +171,54 @@ func TestVerifyNodeUpdate(t *testing.T) {
 		require.Equal(t, tc.err, err, tc.msg)
 	}
 }
+
+func TestVerifyRuntimeMinSoftwareVersionConstraint(t *testing.T) {
+	require := require.New(t)
+
+	logger := logging.GetLogger("registry/api/tests")
+	rt := Runtime{
+		Versioned:   cbor.NewVersioned(LatestRuntimeDescriptorVersion),
+		ID:          common.NewTestNamespaceFromSeed([]byte("registry/api: min software version"), 0),
+		Kind:        KindCompute,
+		TEEHardware: node.TEEHardwareInvalid,
+		Executor: ExecutorParameters{
+			GroupSize:    1,
+			RoundTimeout: 5,
+		},
+		TxnScheduler: TxnSchedulerParameters{
+			BatchFlushTimeout: time.Second,
+			MaxBatchSize:      1,
+			MaxBatchSizeBytes: 1024,
+			ProposerTimeout:   2,
+		},
+		AdmissionPolicy: RuntimeAdmissionPolicy{
+			AnyNode: &AnyNodeRuntimeAdmissionPolicy{},
+		},
+		Constraints: map[scheduler.CommitteeKind]map[scheduler.Role]SchedulingConstraints{
+			scheduler.KindComputeExecutor: {
+				scheduler.RoleWorker: {
+					MinSoftwareVersion: &MinSoftwareVersionConstraint{
+						Version: version.Version{Major: 22, Minor: 10},
+					},
+				},
+			},
+		},
+		GovernanceModel: GovernanceEntity,
+		Deployments: []*VersionInfo{
+			{},
+		},
+	}
+	params := &ConsensusParameters{
+		DebugAllowTestRuntimes: true,
+		EnableRuntimeGovernanceModels: map[RuntimeGovernanceModel]bool{
+			GovernanceEntity: true,
+		},
+	}
+
+	err := VerifyRuntime(params, logger, &rt, false, false, 0)
+	require.ErrorIs(err, ErrForbidden, "min software version constraint should be rejected unless enabled")
+
+	params.EnableMinSoftwareVersionConstraint = true
+	err = VerifyRuntime(params, logger, &rt, false, false, 0)
+	require.NoError(err, "min software version constraint should be accepted when enabled")
+}
@@ -226,9 +226,10 @@ type RuntimeAdmissionPolicy struct {
 //
 // Multiple fields may be set in which case the ALL the constraints must be satisfied.
 type SchedulingConstraints struct {
-	ValidatorSet *ValidatorSetConstraint `json:"validator_set,omitempty"`
-	MaxNodes     *MaxNodesConstraint     `json:"max_nodes,omitempty"`
-	MinPoolSize  *MinPoolSizeConstraint  `json:"min_pool_size,omitempty"`
+	ValidatorSet       *ValidatorSetConstraint       `json:"validator_set,omitempty"`
+	MaxNodes           *MaxNodesConstraint           `json:"max_nodes,omitempty"`
+	MinPoolSize        *MinPoolSizeConstraint        `json:"min_pool_size,omitempty"`
+	MinSoftwareVersion *MinSoftwareVersionConstraint `json:"min_software_version,omitempty"`
 }
 
 // ValidatorSetConstraint specifies that the entity must have a node that is part of the validator
@@ -245,6 +246,12 @@ type MinPoolSizeConstraint struct {
 	Limit uint16 `json:"limit"`
 }
 
+// MinSoftwareVersionConstraint specifies that only nodes reporting at least the given software
+// version are eligible.
+type MinSoftwareVersionConstraint struct {
+	Version version.Version `json:"version"`
+}
+
 // RuntimeStakingParameters are the stake-related parameters for a runtime.
 type RuntimeStakingParameters struct {
 	// Thresholds are the minimum stake thresholds for a runtime. These per-runtime thresholds are
@@ -572,11 +579,11 @@ func (r *Runtime) ValidateDeployments(now beacon.EpochTime) error {
 				return fmt.Errorf("%w: TEE constraints when no TEE specified", ErrInvalidArgument)
 			}
 		case node.TEEHardwareIntelSGX:
-			var cs node.SGXConstraints
-			if err := cbor.Unmarshal(deployment.TEE, &cs); err != nil {
+			tc, err := node.DecodeTEEConstraints(deployment.TEE, r.TEEHardware)
+			if err != nil {
 				return fmt.Errorf("%w: invalid SGX TEE constraints", ErrInvalidArgument)
 			}
-			if len(cs.Enclaves) == 0 {
+			if len(tc.SGX.Enclaves) == 0 {
 				return fmt.Errorf("%w: invalid SGX TEE constraints", ErrNoEnclaveForRuntime)
 			}
 		default:
@@ -1097,6 +1097,8 @@ func (ent *TestEntity) NewTestNodes(nCompute int, idNonce []byte, runtimes []*no
 			},
 		}
 
+		// The registry returns normalized descriptors.
+		nod.Node.Normalize()
 		nod.SignedRegistration, err = node.MultiSignNode(nodeSigners, api.RegisterNodeSignatureContext, nod.Node)
 		if err != nil {
 			return nil, err
@@ -1378,6 +1380,7 @@ func (ent *TestEntity) NewTestNodes(nCompute int, idNonce []byte, runtimes []*no
 		nod.UpdatedNode.TLS.PubKey = nod.Node.TLS.PubKey
 		nod.UpdatedNode.TLS.Addresses = nod.Node.TLS.Addresses
 		nod.UpdatedNode.Consensus.ID = nod.Node.Consensus.ID // This should remain the same or we'll get "node update not allowed".
+		nod.UpdatedNode.Normalize()
 		nod.SignedValidReRegistration, err = node.MultiSignNode(nodeSigners, api.RegisterNodeSignatureContext, nod.UpdatedNode)
 		if err != nil {
 			return nil, err
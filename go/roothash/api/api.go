@@ -90,6 +90,12 @@ var (
 	// value larger than the MaxInRuntimeMessages specified in consensus parameters.
 	ErrMaxInMessagesTooBig = errors.New(ModuleName, 13, "roothash: max incoming runtime messages is too big")
 
+	// ErrRoundPruned is the error returned when a requested round is no longer retained.
+	ErrRoundPruned = errors.New(ModuleName, 14, "roothash: round has been pruned")
+
+	// ErrEventsPruned is the error returned when requested events are no longer retained.
+	ErrEventsPruned = errors.New(ModuleName, 15, "roothash: events have been pruned")
+
 	// MethodExecutorCommit is the method name for executor commit submission.
 	MethodExecutorCommit = transaction.NewMethodName(ModuleName, "ExecutorCommit", ExecutorCommit{})
 
@@ -120,8 +126,22 @@ type Backend interface {
 	//
 	// The metadata contained in this block can be further used to get
 	// the latest state from the storage backend.
+	//
+	// The returned block is the latest block finalized at or before the
+	// consensus height given in the request, so it is consistent with other
+	// queries made at the same height. In case the consensus state at that
+	// height has been pruned, consensus.ErrVersionNotFound is returned. In
+	// case the runtime did not exist at that height, ErrInvalidRuntime is
+	// returned.
 	GetLatestBlock(ctx context.Context, request *RuntimeRequest) (*block.Block, error)
 
+	// GetEarliestRound returns the earliest round that is still retained for the given runtime.
+	//
+	// Callers can use this to avoid requesting blocks for rounds which have already been pruned.
+	// In case block history is not being tracked for the given runtime only the latest block is
+	// retained and its round is returned.
+	GetEarliestRound(ctx context.Context, request *RuntimeRequest) (uint64, error)
+
 	// GetRuntimeState returns the given runtime's state.
 	GetRuntimeState(ctx context.Context, request *RuntimeRequest) (*RuntimeState, error)
 
@@ -142,9 +162,46 @@ type Backend interface {
 	// confirmed.
 	WatchBlocks(ctx context.Context, runtimeID common.Namespace) (<-chan *AnnotatedBlock, pubsub.ClosableSubscription, error)
 
+	// WatchBlocksRange returns a channel that produces a stream of annotated blocks for rounds in
+	// the range [start, end] (inclusive).
+	//
+	// Blocks for rounds that have already been finalized are delivered from block history which
+	// must be tracked for the given runtime. Subsequent blocks will be pushed into the stream as
+	// they are confirmed. The channel is closed after the block for the end round is delivered.
+	WatchBlocksRange(ctx context.Context, request *WatchBlocksRangeRequest) (<-chan *AnnotatedBlock, pubsub.ClosableSubscription, error)
+
+	// WatchBlocksSinceChecked returns a channel that produces a stream of annotated blocks
+	// starting at the given round, followed by blocks as they are confirmed.
+	//
+	// Blocks for rounds before the latest round are delivered from block history. In case the
+	// given round is no longer retained, ErrRoundPruned is returned instead of starting from the
+	// earliest retained round so that callers resuming from a checkpoint can detect the gap. A
+	// round is not retained when it has been pruned from block history or, in case block history
+	// is not being tracked for the runtime, when it is older than the latest round (see
+	// GetEarliestRound). Should blocks get pruned while they are still being delivered, the
+	// channel is closed without skipping any rounds.
+	WatchBlocksSinceChecked(ctx context.Context, request *WatchBlocksSinceRequest) (<-chan *AnnotatedBlock, pubsub.ClosableSubscription, error)
+
 	// WatchEvents returns a stream of protocol events.
+	//
+	// Events are assigned sequence numbers (see Event.Sequence) so that consumers can detect
+	// missed events and resume via WatchEventsSince.
 	WatchEvents(ctx context.Context, runtimeID common.Namespace) (<-chan *Event, pubsub.ClosableSubscription, error)
 
+	// WatchEventsSince returns a stream of protocol events with sequence numbers greater than the
+	// given sequence number, starting with any such recent events that are still retained.
+	//
+	// Only a limited number of recent events is retained. In case events following the given
+	// sequence number are no longer retained, ErrEventsPruned is returned. In case the given
+	// sequence number is ahead of the latest event (e.g., because the node has restarted),
+	// ErrInvalidArgument is returned.
+	WatchEventsSince(ctx context.Context, request *WatchEventsSinceRequest) (<-chan *Event, pubsub.ClosableSubscription, error)
+
+	// WatchEventsFiltered returns a stream of protocol events of the kinds given by the mask.
+	//
+	// Events of other kinds are not forwarded by the backend.
+	WatchEventsFiltered(ctx context.Context, request *WatchEventsFilteredRequest) (<-chan *Event, pubsub.ClosableSubscription, error)
+
 	// TrackRuntime adds a runtime the history of which should be tracked.
 	TrackRuntime(ctx context.Context, history BlockHistory) error
 
@@ -167,6 +224,41 @@ type RuntimeRequest struct {
 	Height    int64            `json:"height"`
 }
 
+// WatchBlocksRangeRequest is a request to watch blocks in a given range of rounds.
+type WatchBlocksRangeRequest struct {
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Start is the first round in the range.
+	Start uint64 `json:"start"`
+	// End is the last round in the range (inclusive).
+	End uint64 `json:"end"`
+}
+
+// WatchBlocksSinceRequest is a request to watch blocks starting at a given round.
+type WatchBlocksSinceRequest struct {
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Round is the first round to deliver.
+	Round uint64 `json:"round"`
+}
+
+// WatchEventsSinceRequest is a request to watch events following a given sequence number.
+type WatchEventsSinceRequest struct {
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Sequence is the sequence number of the last event seen by the caller or zero in case no
+	// events have been seen.
+	Sequence uint64 `json:"sequence"`
+}
+
+// WatchEventsFilteredRequest is a request to watch events of specific kinds.
+type WatchEventsFilteredRequest struct {
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Mask is the mask of event kinds that should be forwarded.
+	Mask EventKind `json:"mask"`
+}
+
 // InMessageQueueRequest is a request for queued incoming messages.
 type InMessageQueueRequest struct {
 	RuntimeID common.Namespace `json:"runtime_id"`
@@ -510,6 +602,12 @@ type Event struct {
 	Height int64     `json:"height,omitempty"`
 	TxHash hash.Hash `json:"tx_hash,omitempty"`
 
+	// Sequence is the per-runtime sequence number of the event as assigned by the node that
+	// delivered it via WatchEvents, starting at one. Sequence numbers are consecutive but local to
+	// the node and restart after the node restarts, so the consensus height should be used to
+	// order events across nodes. It is zero for events that are not delivered via watchers.
+	Sequence uint64 `json:"sequence,omitempty"`
+
 	RuntimeID common.Namespace `json:"runtime_id"`
 
 	ExecutorCommitted            *ExecutorCommittedEvent            `json:"executor_committed,omitempty"`
@@ -518,6 +616,44 @@ type Event struct {
 	InMsgProcessed               *InMsgProcessedEvent               `json:"in_msg_processed,omitempty"`
 }
 
+// Kind returns the kind of this event.
+func (e *Event) Kind() EventKind {
+	switch {
+	case e.ExecutorCommitted != nil:
+		return EventKindExecutorCommitted
+	case e.ExecutionDiscrepancyDetected != nil:
+		return EventKindExecutionDiscrepancyDetected
+	case e.Finalized != nil:
+		return EventKindFinalized
+	case e.InMsgProcessed != nil:
+		return EventKindInMsgProcessed
+	default:
+		return 0
+	}
+}
+
+// EventKind is a bitmask of roothash event kinds.
+type EventKind uint32
+
+const (
+	// EventKindExecutionDiscrepancyDetected is the kind of execution discrepancy detected events.
+	EventKindExecutionDiscrepancyDetected EventKind = 1 << iota
+	// EventKindExecutorCommitted is the kind of executor committed events.
+	EventKindExecutorCommitted
+	// EventKindFinalized is the kind of finalized events.
+	EventKindFinalized
+	// EventKindInMsgProcessed is the kind of incoming message processed events.
+	EventKindInMsgProcessed
+
+	// EventKindAll is a mask of all event kinds.
+	EventKindAll = EventKindExecutionDiscrepancyDetected | EventKindExecutorCommitted | EventKindFinalized | EventKindInMsgProcessed
+)
+
+// Matches returns true iff the given event is of a kind included in the mask.
+func (k EventKind) Matches(ev *Event) bool {
+	return k&ev.Kind() != 0
+}
+
 // MetricsMonitorable is the interface exposed by backends capable of
 // providing metrics data.
 type MetricsMonitorable interface {
@@ -526,6 +662,14 @@ type MetricsMonitorable interface {
 	// All blocks from all tracked runtimes will be pushed into the stream
 	// immediately as they are finalized.
 	WatchAllBlocks() (<-chan *block.Block, *pubsub.Subscription)
+
+	// WatchAllBlocksForRuntimes returns a channel that produces a stream of
+	// blocks for the given runtimes.
+	//
+	// Blocks from other runtimes are filtered out before reaching the
+	// stream. In case no runtimes are given, this is equivalent to
+	// WatchAllBlocks.
+	WatchAllBlocksForRuntimes(ids []common.Namespace) (<-chan *block.Block, *pubsub.Subscription)
 }
 
 // GenesisRuntimeState contains state for runtimes that are restored in a genesis block.
@@ -668,3 +668,24 @@ func TestRuntimeIDAttribute(t *testing.T) {
 	val2 := events.EncodeValue(&attribute)
 	require.EqualValues(t, val, val2, "events.EncodeValue should encode correctly")
 }
+
+func TestEventKind(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		ev   *Event
+		kind EventKind
+	}{
+		{&Event{ExecutorCommitted: &ExecutorCommittedEvent{}}, EventKindExecutorCommitted},
+		{&Event{ExecutionDiscrepancyDetected: &ExecutionDiscrepancyDetectedEvent{}}, EventKindExecutionDiscrepancyDetected},
+		{&Event{Finalized: &FinalizedEvent{}}, EventKindFinalized},
+		{&Event{InMsgProcessed: &InMsgProcessedEvent{}}, EventKindInMsgProcessed},
+	} {
+		require.Equal(tc.kind, tc.ev.Kind(), "event kind")
+		require.True(tc.kind.Matches(tc.ev), "event should match its own kind")
+		require.True(EventKindAll.Matches(tc.ev), "event should match all kinds")
+		require.False((EventKindAll &^ tc.kind).Matches(tc.ev), "event should not match other kinds")
+	}
+
+	require.False(EventKindAll.Matches(&Event{}), "empty event should not match")
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"fmt"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
@@ -12,6 +14,10 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/roothash/api/message"
 )
 
+// subscribedMetadataKey is the header metadata key that the server uses to signal that a
+// subscription has been established.
+const subscribedMetadataKey = "oasis-subscribed"
+
 var (
 	// serviceName is the gRPC service name.
 	serviceName = cmnGrpc.NewServiceName("RootHash")
@@ -20,6 +26,8 @@ var (
 	methodGetGenesisBlock = serviceName.NewMethod("GetGenesisBlock", RuntimeRequest{})
 	// methodGetLatestBlock is the GetLatestBlock method.
 	methodGetLatestBlock = serviceName.NewMethod("GetLatestBlock", RuntimeRequest{})
+	// methodGetEarliestRound is the GetEarliestRound method.
+	methodGetEarliestRound = serviceName.NewMethod("GetEarliestRound", RuntimeRequest{})
 	// methodGetRuntimeState is the GetRuntimeState method.
 	methodGetRuntimeState = serviceName.NewMethod("GetRuntimeState", RuntimeRequest{})
 	// methodGetLastRoundResults is the GetLastRoundResults method.
@@ -39,6 +47,14 @@ var (
 	methodWatchBlocks = serviceName.NewMethod("WatchBlocks", common.Namespace{})
 	// methodWatchEvents is the WatchEvents method.
 	methodWatchEvents = serviceName.NewMethod("WatchEvents", common.Namespace{})
+	// methodWatchBlocksRange is the WatchBlocksRange method.
+	methodWatchBlocksRange = serviceName.NewMethod("WatchBlocksRange", WatchBlocksRangeRequest{})
+	// methodWatchEventsFiltered is the WatchEventsFiltered method.
+	methodWatchEventsFiltered = serviceName.NewMethod("WatchEventsFiltered", WatchEventsFilteredRequest{})
+	// methodWatchBlocksSinceChecked is the WatchBlocksSinceChecked method.
+	methodWatchBlocksSinceChecked = serviceName.NewMethod("WatchBlocksSinceChecked", WatchBlocksSinceRequest{})
+	// methodWatchEventsSince is the WatchEventsSince method.
+	methodWatchEventsSince = serviceName.NewMethod("WatchEventsSince", WatchEventsSinceRequest{})
 
 	// serviceDesc is the gRPC service descriptor.
 	serviceDesc = grpc.ServiceDesc{
@@ -53,6 +69,10 @@ var (
 				MethodName: methodGetLatestBlock.ShortName(),
 				Handler:    handlerGetLatestBlock,
 			},
+			{
+				MethodName: methodGetEarliestRound.ShortName(),
+				Handler:    handlerGetEarliestRound,
+			},
 			{
 				MethodName: methodGetRuntimeState.ShortName(),
 				Handler:    handlerGetRuntimeState,
@@ -93,6 +113,26 @@ var (
 				Handler:       handlerWatchEvents,
 				ServerStreams: true,
 			},
+			{
+				StreamName:    methodWatchBlocksRange.ShortName(),
+				Handler:       handlerWatchBlocksRange,
+				ServerStreams: true,
+			},
+			{
+				StreamName:    methodWatchEventsFiltered.ShortName(),
+				Handler:       handlerWatchEventsFiltered,
+				ServerStreams: true,
+			},
+			{
+				StreamName:    methodWatchBlocksSinceChecked.ShortName(),
+				Handler:       handlerWatchBlocksSinceChecked,
+				ServerStreams: true,
+			},
+			{
+				StreamName:    methodWatchEventsSince.ShortName(),
+				Handler:       handlerWatchEventsSince,
+				ServerStreams: true,
+			},
 		},
 	}
 )
@@ -143,6 +183,29 @@ func handlerGetLatestBlock( // nolint: golint
 	return interceptor(ctx, &rq, info, handler)
 }
 
+func handlerGetEarliestRound( // nolint: golint
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var rq RuntimeRequest
+	if err := dec(&rq); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetEarliestRound(ctx, &rq)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetEarliestRound.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetEarliestRound(ctx, req.(*RuntimeRequest))
+	}
+	return interceptor(ctx, &rq, info, handler)
+}
+
 func handlerGetRuntimeState( // nolint: golint
 	srv interface{},
 	ctx context.Context,
@@ -333,6 +396,74 @@ func handlerWatchBlocks(srv interface{}, stream grpc.ServerStream) error {
 	}
 }
 
+func handlerWatchBlocksRange(srv interface{}, stream grpc.ServerStream) error {
+	var rq WatchBlocksRangeRequest
+	if err := stream.RecvMsg(&rq); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ch, sub, err := srv.(Backend).WatchBlocksRange(ctx, &rq)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// Signal to the client that the subscription has been established.
+	if err = stream.SendHeader(metadata.Pairs(subscribedMetadataKey, "true")); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case blk, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.SendMsg(blk); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func handlerWatchBlocksSinceChecked(srv interface{}, stream grpc.ServerStream) error {
+	var rq WatchBlocksSinceRequest
+	if err := stream.RecvMsg(&rq); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ch, sub, err := srv.(Backend).WatchBlocksSinceChecked(ctx, &rq)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// Signal to the client that the subscription has been established.
+	if err = stream.SendHeader(metadata.Pairs(subscribedMetadataKey, "true")); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case blk, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.SendMsg(blk); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 func handlerWatchEvents(srv interface{}, stream grpc.ServerStream) error {
 	var runtimeID common.Namespace
 	if err := stream.RecvMsg(&runtimeID); err != nil {
@@ -362,11 +493,98 @@ func handlerWatchEvents(srv interface{}, stream grpc.ServerStream) error {
 	}
 }
 
+func handlerWatchEventsSince(srv interface{}, stream grpc.ServerStream) error {
+	var rq WatchEventsSinceRequest
+	if err := stream.RecvMsg(&rq); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ch, sub, err := srv.(Backend).WatchEventsSince(ctx, &rq)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// Signal to the client that the subscription has been established.
+	if err = stream.SendHeader(metadata.Pairs(subscribedMetadataKey, "true")); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.SendMsg(ev); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func handlerWatchEventsFiltered(srv interface{}, stream grpc.ServerStream) error {
+	var rq WatchEventsFilteredRequest
+	if err := stream.RecvMsg(&rq); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ch, sub, err := srv.(Backend).WatchEventsFiltered(ctx, &rq)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// Signal to the client that the subscription has been established.
+	if err = stream.SendHeader(metadata.Pairs(subscribedMetadataKey, "true")); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.SendMsg(ev); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // RegisterService registers a new roothash service with the given gRPC server.
 func RegisterService(server *grpc.Server, service Backend) {
 	server.RegisterService(&serviceDesc, service)
 }
 
+// waitSubscribed waits for the server to signal that the subscription has been established so that
+// errors (e.g., pruned rounds) are reported to the caller instead of just closing the stream.
+//
+// The given message is only used to receive the stream status in case the stream has been
+// terminated without headers.
+func waitSubscribed(stream grpc.ClientStream, msg interface{}) error {
+	md, err := stream.Header()
+	if err == nil && len(md.Get(subscribedMetadataKey)) == 0 {
+		// The stream has been terminated without headers, the status is returned by RecvMsg.
+		if err = stream.RecvMsg(msg); err == nil {
+			err = fmt.Errorf("roothash: subscription not established")
+		}
+	}
+	if err != nil {
+		return cmnGrpc.ErrorFromGrpc(err)
+	}
+	return nil
+}
+
 type roothashClient struct {
 	conn *grpc.ClientConn
 }
@@ -387,6 +605,14 @@ func (c *roothashClient) GetLatestBlock(ctx context.Context, request *RuntimeReq
 	return &rsp, nil
 }
 
+func (c *roothashClient) GetEarliestRound(ctx context.Context, request *RuntimeRequest) (uint64, error) {
+	var rsp uint64
+	if err := c.conn.Invoke(ctx, methodGetEarliestRound.FullName(), request, &rsp); err != nil {
+		return 0, err
+	}
+	return rsp, nil
+}
+
 func (c *roothashClient) GetRuntimeState(ctx context.Context, request *RuntimeRequest) (*RuntimeState, error) {
 	var rsp RuntimeState
 	if err := c.conn.Invoke(ctx, methodGetRuntimeState.FullName(), request, &rsp); err != nil {
@@ -485,6 +711,88 @@ func (c *roothashClient) WatchBlocks(ctx context.Context, runtimeID common.Names
 	return ch, sub, nil
 }
 
+func (c *roothashClient) WatchBlocksRange(ctx context.Context, request *WatchBlocksRangeRequest) (<-chan *AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	if request.End < request.Start {
+		return nil, nil, ErrInvalidArgument
+	}
+
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[2], methodWatchBlocksRange.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(request); err != nil {
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+	if err = waitSubscribed(stream, &AnnotatedBlock{}); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	ch := make(chan *AnnotatedBlock)
+	go func() {
+		defer close(ch)
+
+		for {
+			var blk AnnotatedBlock
+			if serr := stream.RecvMsg(&blk); serr != nil {
+				return
+			}
+
+			select {
+			case ch <- &blk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
+
+func (c *roothashClient) WatchBlocksSinceChecked(ctx context.Context, request *WatchBlocksSinceRequest) (<-chan *AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[4], methodWatchBlocksSinceChecked.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(request); err != nil {
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+	if err = waitSubscribed(stream, &AnnotatedBlock{}); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	ch := make(chan *AnnotatedBlock)
+	go func() {
+		defer close(ch)
+
+		for {
+			var blk AnnotatedBlock
+			if serr := stream.RecvMsg(&blk); serr != nil {
+				return
+			}
+
+			select {
+			case ch <- &blk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
+
 func (c *roothashClient) WatchEvents(ctx context.Context, runtimeID common.Namespace) (<-chan *Event, pubsub.ClosableSubscription, error) {
 	ctx, sub := pubsub.NewContextSubscription(ctx)
 
@@ -520,6 +828,86 @@ func (c *roothashClient) WatchEvents(ctx context.Context, runtimeID common.Names
 	return ch, sub, nil
 }
 
+func (c *roothashClient) WatchEventsSince(ctx context.Context, request *WatchEventsSinceRequest) (<-chan *Event, pubsub.ClosableSubscription, error) {
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[5], methodWatchEventsSince.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(request); err != nil {
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+
+	if err = waitSubscribed(stream, &Event{}); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	ch := make(chan *Event)
+	go func() {
+		defer close(ch)
+
+		for {
+			var ev Event
+			if serr := stream.RecvMsg(&ev); serr != nil {
+				return
+			}
+
+			select {
+			case ch <- &ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
+
+func (c *roothashClient) WatchEventsFiltered(ctx context.Context, request *WatchEventsFilteredRequest) (<-chan *Event, pubsub.ClosableSubscription, error) {
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[3], methodWatchEventsFiltered.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(request); err != nil {
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+
+	if err = waitSubscribed(stream, &Event{}); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	ch := make(chan *Event)
+	go func() {
+		defer close(ch)
+
+		for {
+			var ev Event
+			if serr := stream.RecvMsg(&ev); serr != nil {
+				return
+			}
+
+			select {
+			case ch <- &ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
+
 // NewRootHashClient creates a new gRPC roothash client service.
 func NewRootHashClient(c *grpc.ClientConn) Backend {
 	return &roothashClient{
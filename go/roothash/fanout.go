@@ -0,0 +1,148 @@
+package roothash
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eapache/channels"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api"
+)
+
+var _ api.Backend = (*blockFanout)(nil)
+
+type blockFanout struct {
+	api.Backend
+
+	sync.Mutex
+	runtimes map[common.Namespace]*fanoutRuntime
+}
+
+type fanoutRuntime struct {
+	upstream  pubsub.ClosableSubscription
+	lastBlock *api.AnnotatedBlock
+	subs      map[*fanoutSubscription]struct{}
+}
+
+type fanoutSubscription struct {
+	f  *blockFanout
+	id common.Namespace
+	rt *fanoutRuntime
+	ch channels.Channel
+}
+
+// Implements pubsub.ClosableSubscription.
+func (s *fanoutSubscription) Close() {
+	s.f.unsubscribe(s)
+}
+
+func (f *blockFanout) WatchBlocks(ctx context.Context, id common.Namespace) (<-chan *api.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	rt := f.runtimes[id]
+	if rt == nil {
+		// The upstream subscription is shared by all local subscribers so it must not be tied to
+		// the context of the first one.
+		upstreamCh, upstreamSub, err := f.Backend.WatchBlocks(context.Background(), id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rt = &fanoutRuntime{
+			upstream: upstreamSub,
+			subs:     make(map[*fanoutSubscription]struct{}),
+		}
+		f.runtimes[id] = rt
+
+		go f.worker(id, rt, upstreamCh)
+	}
+
+	sub := &fanoutSubscription{
+		f:  f,
+		id: id,
+		rt: rt,
+		ch: channels.NewInfiniteChannel(),
+	}
+	// Replay the latest block if it exists.
+	if rt.lastBlock != nil {
+		sub.ch.In() <- rt.lastBlock
+	}
+	rt.subs[sub] = struct{}{}
+
+	ch := make(chan *api.AnnotatedBlock)
+	channels.Unwrap(sub.ch, ch)
+
+	return ch, sub, nil
+}
+
+func (f *blockFanout) unsubscribe(sub *fanoutSubscription) {
+	f.Lock()
+	rt := sub.rt
+	if _, ok := rt.subs[sub]; !ok {
+		// Already closed, either explicitly or due to the upstream subscription terminating.
+		f.Unlock()
+		return
+	}
+	delete(rt.subs, sub)
+	sub.ch.Close()
+
+	// Tear down the upstream subscription after the last local subscriber is gone.
+	var upstream pubsub.ClosableSubscription
+	if len(rt.subs) == 0 && f.runtimes[sub.id] == rt {
+		delete(f.runtimes, sub.id)
+		upstream = rt.upstream
+	}
+	f.Unlock()
+
+	if upstream != nil {
+		upstream.Close()
+	}
+}
+
+func (f *blockFanout) worker(id common.Namespace, rt *fanoutRuntime, upstreamCh <-chan *api.AnnotatedBlock) {
+	for blk := range upstreamCh {
+		f.Lock()
+		if f.runtimes[id] != rt {
+			// Upstream subscription has been torn down, skip any remaining blocks.
+			f.Unlock()
+			continue
+		}
+		rt.lastBlock = blk
+		for sub := range rt.subs {
+			sub.ch.In() <- blk
+		}
+		f.Unlock()
+	}
+
+	// The upstream channel has been closed. In case this was not due to a teardown, close all
+	// local subscriptions so that subscribers notice and a subsequent WatchBlocks call
+	// establishes a new upstream subscription.
+	f.Lock()
+	defer f.Unlock()
+
+	if f.runtimes[id] != rt {
+		return
+	}
+	delete(f.runtimes, id)
+	for sub := range rt.subs {
+		delete(rt.subs, sub)
+		sub.ch.Close()
+	}
+	rt.upstream.Close()
+}
+
+// NewBlockFanout wraps a roothash backend so that all local WatchBlocks subscribers for the same
+// runtime share a single upstream WatchBlocks subscription.
+//
+// The upstream subscription is established by the first local subscriber and closed after the
+// last local subscriber closes its subscription. New subscribers receive the latest block seen by
+// the upstream subscription immediately.
+func NewBlockFanout(backend api.Backend) api.Backend {
+	return &blockFanout{
+		Backend:  backend,
+		runtimes: make(map[common.Namespace]*fanoutRuntime),
+	}
+}
@@ -0,0 +1,129 @@
+package roothash
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+)
+
+const recvTimeout = 5 * time.Second
+
+type fanoutTestBackend struct {
+	api.Backend
+
+	sync.Mutex
+	notifier   *pubsub.Broker
+	subscribed int
+	active     int
+}
+
+func (b *fanoutTestBackend) WatchBlocks(ctx context.Context, id common.Namespace) (<-chan *api.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.subscribed++
+	b.active++
+
+	sub := b.notifier.Subscribe()
+	ch := make(chan *api.AnnotatedBlock)
+	sub.Unwrap(ch)
+
+	return ch, &fanoutTestSubscription{b: b, sub: sub}, nil
+}
+
+func (b *fanoutTestBackend) counts() (int, int) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.subscribed, b.active
+}
+
+type fanoutTestSubscription struct {
+	b   *fanoutTestBackend
+	sub *pubsub.Subscription
+}
+
+func (s *fanoutTestSubscription) Close() {
+	s.b.Lock()
+	s.b.active--
+	s.b.Unlock()
+
+	s.sub.Close()
+}
+
+func newFanoutTestBlock(round uint64) *api.AnnotatedBlock {
+	return &api.AnnotatedBlock{
+		Height: int64(round),
+		Block:  &block.Block{Header: block.Header{Round: round}},
+	}
+}
+
+func requireRecvRound(t *testing.T, ch <-chan *api.AnnotatedBlock, round uint64) {
+	select {
+	case blk := <-ch:
+		require.EqualValues(t, round, blk.Block.Header.Round, "received block round")
+	case <-time.After(recvTimeout):
+		t.Fatalf("failed to receive block for round %d", round)
+	}
+}
+
+func TestBlockFanout(t *testing.T) {
+	require := require.New(t)
+
+	backend := &fanoutTestBackend{notifier: pubsub.NewBroker(true)}
+	fanout := NewBlockFanout(backend)
+
+	var id common.Namespace
+	ch1, sub1, err := fanout.WatchBlocks(context.Background(), id)
+	require.NoError(err, "WatchBlocks")
+	ch2, sub2, err := fanout.WatchBlocks(context.Background(), id)
+	require.NoError(err, "WatchBlocks")
+
+	subscribed, active := backend.counts()
+	require.Equal(1, subscribed, "there should be a single upstream subscription")
+	require.Equal(1, active, "upstream subscription should be active")
+
+	backend.notifier.Broadcast(newFanoutTestBlock(1))
+	requireRecvRound(t, ch1, 1)
+	requireRecvRound(t, ch2, 1)
+
+	// Late subscribers should receive the latest block immediately.
+	ch3, sub3, err := fanout.WatchBlocks(context.Background(), id)
+	require.NoError(err, "WatchBlocks")
+	requireRecvRound(t, ch3, 1)
+
+	sub1.Close()
+	sub2.Close()
+	backend.notifier.Broadcast(newFanoutTestBlock(2))
+	requireRecvRound(t, ch3, 2)
+
+	subscribed, active = backend.counts()
+	require.Equal(1, subscribed, "there should be a single upstream subscription")
+	require.Equal(1, active, "upstream subscription should be active while there are subscribers")
+
+	// Closing the last subscriber should tear down the upstream subscription.
+	sub3.Close()
+	_, active = backend.counts()
+	require.Equal(0, active, "upstream subscription should be closed after the last subscriber")
+
+	// Closing a subscription again should be a no-op.
+	require.NotPanics(func() { sub3.Close() }, "Close")
+
+	// A new subscriber should establish a new upstream subscription.
+	ch4, sub4, err := fanout.WatchBlocks(context.Background(), id)
+	require.NoError(err, "WatchBlocks")
+	defer sub4.Close()
+	requireRecvRound(t, ch4, 2)
+
+	subscribed, active = backend.counts()
+	require.Equal(2, subscribed, "a new upstream subscription should be established")
+	require.Equal(1, active, "upstream subscription should be active")
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"strconv"
 	"testing"
@@ -121,6 +122,23 @@ func RootHashImplementationTests(t *testing.T, backend api.Backend, consensus co
 	t.Run("EquivocationEvidence", func(t *testing.T) {
 		testSubmitEquivocationEvidence(t, backend, consensus, identity, rtStates)
 	})
+
+	t.Run("EarliestRound", func(t *testing.T) {
+		testEarliestRound(t, backend, rtStates)
+	})
+
+	t.Run("WatchBlocksRange", func(t *testing.T) {
+		testWatchBlocksRange(t, backend, rtStates)
+	})
+
+	t.Run("WatchBlocksSinceChecked", func(t *testing.T) {
+		testWatchBlocksSinceChecked(t, backend, rtStates)
+	})
+
+	// This leaves the runtimes in discrepancy resolution so it should run last.
+	t.Run("EventSequence", func(t *testing.T) {
+		testEventSequence(t, backend, consensus, identity, rtStates)
+	})
 }
 
 func testConsensusParameters(t *testing.T, backend api.Backend) {
@@ -171,6 +189,212 @@ func testGenesisBlock(t *testing.T, backend api.Backend, state *runtimeState) {
 	})
 	require.NoError(err, "GetGenesisBlock")
 	require.EqualValues(genesisBlock, blk, "retrieved block is genesis block")
+
+	var unknownID common.Namespace
+	_, err = backend.GetGenesisBlock(context.Background(), &api.RuntimeRequest{
+		RuntimeID: unknownID,
+		Height:    consensusAPI.HeightLatest,
+	})
+	require.ErrorIs(err, api.ErrInvalidRuntime, "GetGenesisBlock should fail for unknown runtimes")
+}
+
+func testEarliestRound(t *testing.T, backend api.Backend, states []*runtimeState) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	for _, v := range states {
+		blk, err := backend.GetLatestBlock(ctx, &api.RuntimeRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Height:    consensusAPI.HeightLatest,
+		})
+		require.NoError(err, "GetLatestBlock")
+
+		// Block history is not tracked so only the latest block should be retained.
+		round, err := backend.GetEarliestRound(ctx, &api.RuntimeRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Height:    consensusAPI.HeightLatest,
+		})
+		require.NoError(err, "GetEarliestRound")
+		require.EqualValues(blk.Header.Round, round, "earliest round should be the latest round")
+		require.NotZero(round, "earliest round should not be the genesis round")
+	}
+
+	var unknownID common.Namespace
+	_, err := backend.GetEarliestRound(ctx, &api.RuntimeRequest{
+		RuntimeID: unknownID,
+		Height:    consensusAPI.HeightLatest,
+	})
+	require.ErrorIs(err, api.ErrInvalidRuntime, "GetEarliestRound should fail for unknown runtimes")
+}
+
+func testWatchBlocksRange(t *testing.T, backend api.Backend, states []*runtimeState) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	for _, v := range states {
+		blk, err := backend.GetLatestBlock(ctx, &api.RuntimeRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Height:    consensusAPI.HeightLatest,
+		})
+		require.NoError(err, "GetLatestBlock")
+		round := blk.Header.Round
+
+		// Invalid ranges should be rejected.
+		_, _, err = backend.WatchBlocksRange(ctx, &api.WatchBlocksRangeRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Start:     round,
+			End:       round - 1,
+		})
+		require.ErrorIs(err, api.ErrInvalidArgument, "WatchBlocksRange should fail for invalid ranges")
+
+		// A range containing only the latest round should deliver the latest block.
+		ch, sub, err := backend.WatchBlocksRange(ctx, &api.WatchBlocksRangeRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Start:     round,
+			End:       round,
+		})
+		require.NoError(err, "WatchBlocksRange")
+		select {
+		case annBlk, ok := <-ch:
+			require.True(ok, "channel should not be closed before the block is delivered")
+			require.EqualValues(blk, annBlk.Block, "latest block should be delivered")
+		case <-time.After(recvTimeout):
+			t.Fatalf("failed to receive block")
+		}
+		select {
+		case _, ok := <-ch:
+			require.False(ok, "channel should be closed after the end round")
+		case <-time.After(recvTimeout):
+			t.Fatalf("channel not closed after the end round")
+		}
+		sub.Close()
+
+		// Block history is not tracked so earlier rounds should not be available.
+		_, _, err = backend.WatchBlocksRange(ctx, &api.WatchBlocksRangeRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Start:     0,
+			End:       round,
+		})
+		require.ErrorIs(err, api.ErrNotFound, "WatchBlocksRange should fail for unavailable rounds")
+	}
+}
+
+func testWatchBlocksSinceChecked(t *testing.T, backend api.Backend, states []*runtimeState) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	for _, v := range states {
+		blk, err := backend.GetLatestBlock(ctx, &api.RuntimeRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Height:    consensusAPI.HeightLatest,
+		})
+		require.NoError(err, "GetLatestBlock")
+		round := blk.Header.Round
+
+		// Watching since the latest round should deliver the latest block.
+		ch, sub, err := backend.WatchBlocksSinceChecked(ctx, &api.WatchBlocksSinceRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Round:     round,
+		})
+		require.NoError(err, "WatchBlocksSinceChecked")
+		select {
+		case annBlk, ok := <-ch:
+			require.True(ok, "channel should not be closed before the block is delivered")
+			require.EqualValues(blk, annBlk.Block, "latest block should be delivered")
+		case <-time.After(recvTimeout):
+			t.Fatalf("failed to receive block")
+		}
+		sub.Close()
+
+		// Block history is not tracked so earlier rounds should be reported as pruned.
+		_, _, err = backend.WatchBlocksSinceChecked(ctx, &api.WatchBlocksSinceRequest{
+			RuntimeID: v.rt.Runtime.ID,
+			Round:     0,
+		})
+		require.ErrorIs(err, api.ErrRoundPruned, "WatchBlocksSinceChecked should fail for pruned rounds")
+	}
+}
+
+func testEventSequence(t *testing.T, backend api.Backend, consensus consensusAPI.Backend, identity *identity.Identity, states []*runtimeState) {
+	for _, state := range states {
+		state.testEventSequence(t, backend, consensus, identity)
+	}
+}
+
+func (s *runtimeState) testEventSequence(t *testing.T, backend api.Backend, consensus consensusAPI.Backend, identity *identity.Identity) {
+	require := require.New(t)
+
+	child, err := backend.GetLatestBlock(context.Background(), &api.RuntimeRequest{
+		RuntimeID: s.rt.Runtime.ID,
+		Height:    consensusAPI.HeightLatest,
+	})
+	require.NoError(err, "GetLatestBlock")
+
+	evCh, evSub, err := backend.WatchEvents(context.Background(), s.rt.Runtime.ID)
+	require.NoError(err, "WatchEvents")
+	defer evSub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), recvTimeout)
+	defer cancel()
+
+	// Submit commitments where one of them differs to cause a discrepancy.
+	_, executorCommits, executorNodes := s.generateExecutorCommitments(t, consensus, identity, child)
+	var badRoot hash.Hash
+	badRoot.FromBytes([]byte("bad state root"))
+	executorCommits[1].Header.ComputeResultsHeader.StateRoot = &badRoot
+	err = executorCommits[1].Sign(executorNodes[1].Signer, s.rt.Runtime.ID)
+	require.NoError(err, "ec.Sign")
+
+	// Submit the commitments in separate transactions so that the discrepancy is detected as soon
+	// as both the proposer and the differing commitment have been processed.
+	for _, ec := range executorCommits {
+		tx := api.NewExecutorCommitTx(0, nil, s.rt.Runtime.ID, []commitment.ExecutorCommitment{ec})
+		err = consensusAPI.SignAndSubmitTx(ctx, consensus, executorNodes[0].Signer, tx)
+		require.NoError(err, "ExecutorCommit")
+	}
+
+	// Sequence numbers should be consecutive across the discrepancy event, which is followed by the
+	// executor committed event emitted by the same transaction.
+	var events []*api.Event
+	discrepancyIdx := -1
+	for discrepancyIdx < 0 || len(events) <= discrepancyIdx+1 {
+		select {
+		case ev := <-evCh:
+			require.NotZero(ev.Sequence, "event sequence number should be set")
+			if len(events) > 0 {
+				require.EqualValues(events[len(events)-1].Sequence+1, ev.Sequence, "event sequence numbers should be consecutive")
+			}
+			if ev.ExecutionDiscrepancyDetected != nil {
+				discrepancyIdx = len(events)
+			}
+			events = append(events, ev)
+		case <-time.After(recvTimeout):
+			t.Fatalf("failed to receive discrepancy event")
+		}
+	}
+
+	// Watching events since the first event should replay the subsequent events.
+	sinceCh, sinceSub, err := backend.WatchEventsSince(ctx, &api.WatchEventsSinceRequest{
+		RuntimeID: s.rt.Runtime.ID,
+		Sequence:  events[0].Sequence,
+	})
+	require.NoError(err, "WatchEventsSince")
+	defer sinceSub.Close()
+	for _, expected := range events[1:] {
+		select {
+		case ev := <-sinceCh:
+			require.EqualValues(expected, ev, "replayed event should match")
+		case <-time.After(recvTimeout):
+			t.Fatalf("failed to receive replayed event")
+		}
+	}
+
+	// Sequence numbers ahead of the latest event should be rejected.
+	_, _, err = backend.WatchEventsSince(ctx, &api.WatchEventsSinceRequest{
+		RuntimeID: s.rt.Runtime.ID,
+		Sequence:  math.MaxUint64,
+	})
+	require.ErrorIs(err, api.ErrInvalidArgument, "WatchEventsSince should fail for future sequence numbers")
 }
 
 func testEpochTransitionBlock(t *testing.T, backend api.Backend, consensus consensusAPI.Backend, states []*runtimeState) {
@@ -353,6 +577,13 @@ func (s *runtimeState) generateExecutorCommitments(t *testing.T, consensus conse
 func (s *runtimeState) testSuccessfulRound(t *testing.T, backend api.Backend, consensus consensusAPI.Backend, identity *identity.Identity) {
 	require := require.New(t)
 
+	evCh, evSub, err := backend.WatchEventsFiltered(context.Background(), &api.WatchEventsFilteredRequest{
+		RuntimeID: s.rt.Runtime.ID,
+		Mask:      api.EventKindExecutorCommitted,
+	})
+	require.NoError(err, "WatchEventsFiltered")
+	defer evSub.Close()
+
 	child, err := backend.GetLatestBlock(context.Background(), &api.RuntimeRequest{
 		RuntimeID: s.rt.Runtime.ID,
 		Height:    consensusAPI.HeightLatest,
@@ -436,6 +667,16 @@ func (s *runtimeState) testSuccessfulRound(t *testing.T, backend api.Backend, co
 				require.EqualValues(1, v, "LiveRounds(%s)", nodeID)
 			}
 
+			// Only executor commitment events should be emitted by the filtered event watcher.
+			for range executorCommits {
+				select {
+				case ev := <-evCh:
+					require.NotNil(ev.ExecutorCommitted, "filtered event should be an executor committed event")
+				case <-time.After(recvTimeout):
+					t.Fatalf("failed to receive executor committed event")
+				}
+			}
+
 			// Nothing more to do after the block was received.
 			return
 		case <-time.After(recvTimeout):
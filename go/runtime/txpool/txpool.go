@@ -2,6 +2,7 @@ package txpool
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -20,6 +21,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/runtime/host"
 	"github.com/oasisprotocol/oasis-core/go/runtime/host/protocol"
 	"github.com/oasisprotocol/oasis-core/go/runtime/transaction"
+	p2pError "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/error"
 )
 
 const (
@@ -134,7 +136,7 @@ type TransactionPublisher interface {
 
 	// GetMinRepublishInterval returns the minimum republish interval that needs to be respected by
 	// the caller. If PublishTx is called for the same transaction more quickly, the transaction
-	// may be dropped and not published.
+	// is not published and an error matching p2pError.ErrRepublishTooSoon is returned.
 	GetMinRepublishInterval() time.Duration
 }
 
@@ -557,7 +559,9 @@ func (t *txPool) checkTxBatch(ctx context.Context, rr host.RichRuntime) {
 		// Publish local transactions immediately.
 		publishTime := time.Now()
 		if isLocal[i] {
-			if err := t.txPublisher.PublishTx(ctx, tx.Raw()); err != nil {
+			// Transactions that have just been published are treated as published.
+			err := t.txPublisher.PublishTx(ctx, tx.Raw())
+			if err != nil && !errors.Is(err, p2pError.ErrRepublishTooSoon) {
 				t.logger.Warn("failed to publish local transaction",
 					"err", err,
 					"tx", tx,
@@ -717,7 +721,10 @@ func (t *txPool) republishWorker() {
 				}
 			}
 
-			if err := t.txPublisher.PublishTx(ctx, tx.Raw()); err != nil {
+			// Transactions that have just been published (e.g., when forcing a republish) are
+			// treated as published as republishing them would be pointless anyway.
+			err := t.txPublisher.PublishTx(ctx, tx.Raw())
+			if err != nil && !errors.Is(err, p2pError.ErrRepublishTooSoon) {
 				t.logger.Warn("failed to publish transaction",
 					"err", err,
 					"tx", tx,
@@ -23,7 +23,7 @@ func (ap AccessPolicy) AddRulesForNodes(policy *accessctl.Policy, nodes []*node.
 
 		// Make sure to also allow the node to perform actions after it has
 		// rotated its TLS certificates.
-		if node.TLS.NextPubKey.IsValid() {
+		if node.TLS.IsRotating() {
 			subject := accessctl.SubjectFromPublicKey(node.TLS.NextPubKey)
 			for _, action := range ap.Actions {
 				policy.Allow(subject, action)
@@ -57,7 +57,7 @@ func (ap AccessPolicy) AddRulesForNodeRoles(
 
 			// Make sure to also allow the node to perform actions after is has
 			// rotated its TLS certificates.
-			if n.TLS.NextPubKey.IsValid() {
+			if n.TLS.IsRotating() {
 				subject := accessctl.SubjectFromPublicKey(n.TLS.NextPubKey)
 				for _, action := range ap.Actions {
 					policy.Allow(subject, action)
@@ -61,6 +61,27 @@ var (
 		},
 		[]string{"runtime"},
 	)
+	txReceivedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_p2p_tx_received_count",
+			Help: "Number of transactions received via P2P gossip.",
+		},
+		[]string{"runtime", "own"},
+	)
+	txPublishedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_p2p_tx_published_count",
+			Help: "Number of transactions published via P2P gossip.",
+		},
+		[]string{"runtime", "result"},
+	)
+	txRejectedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_p2p_tx_rejected_count",
+			Help: "Number of transactions received via P2P gossip that were rejected or dropped.",
+		},
+		[]string{"runtime", "reason"},
+	)
 
 	nodeCollectors = []prometheus.Collector{
 		processedBlockCount,
@@ -68,6 +89,9 @@ var (
 		failedRoundCount,
 		epochTransitionCount,
 		epochNumber,
+		txReceivedCount,
+		txPublishedCount,
+		txRejectedCount,
 	}
 
 	metricsOnce sync.Once
@@ -77,6 +101,9 @@ var (
 // These are called from the runtime's common node's worker.
 type NodeHooks interface {
 	// HandlePeerTx handles a transaction received from a (non-local) peer.
+	//
+	// Errors abort dispatch to the remaining hooks and penalize the peer unless they are marked
+	// as non-fatal via NonFatalTxError (e.g., NonFatalTxError(ErrTxNotHandled)).
 	HandlePeerTx(ctx context.Context, tx []byte) error
 
 	// Guarded by CrossNode.
@@ -110,6 +137,14 @@ type Node struct {
 	P2P              *p2p.P2P
 	TxPool           txpool.TransactionPool
 
+	// TxRuntimeExtractor is an optional function used to verify that published transaction
+	// batches are destined for this node's runtime.
+	TxRuntimeExtractor TxRuntimeExtractor
+
+	// TxAuthorizer is an optional authorizer consulted for transactions received via P2P gossip.
+	// If not set, all transactions are authorized (subject to rate limiting).
+	TxAuthorizer TxAuthorizer
+
 	ctx       context.Context
 	cancelCtx context.CancelFunc
 	stopCh    chan struct{}
@@ -120,6 +155,14 @@ type Node struct {
 
 	hooks []NodeHooks
 
+	txDedup       *txDedupCache
+	txRateLimiter *peerRateLimiter
+	suppressOwnTx bool
+
+	// maxTxSize is the maximum transaction size derived from CurrentDescriptor. It is accessed
+	// atomically so that incoming transactions can be checked without taking CrossNode.
+	maxTxSize uint64
+
 	// Mutable and shared between nodes' workers.
 	// Guarded by .CrossNode.
 	CrossNode             sync.Mutex
@@ -302,7 +345,7 @@ func (n *Node) handleSuspendLocked(height int64) {
 						return
 					}
 
-					n.CurrentDescriptor = rt
+					n.setCurrentDescriptorLocked(rt)
 					n.updateHostedRuntimeVersionLocked()
 					n.CrossNode.Unlock()
 				case <-resumeCh:
@@ -378,7 +421,7 @@ func (n *Node) handleNewBlockLocked(blk *block.Block, height int64) {
 			)
 			return
 		}
-		n.CurrentDescriptor = rs.Runtime
+		n.setCurrentDescriptorLocked(rs.Runtime)
 
 		n.CurrentEpoch, err = n.Consensus.Beacon().GetEpoch(n.ctx, height)
 		if err != nil {
@@ -503,7 +546,9 @@ func (n *Node) worker() {
 
 	// Initialize the CurrentDescriptor to make sure there is one even if the runtime gets
 	// suspended.
-	n.CurrentDescriptor = rt
+	n.CrossNode.Lock()
+	n.setCurrentDescriptorLocked(rt)
+	n.CrossNode.Unlock()
 
 	// If the runtime requires a key manager, wait for the key manager to actually become available
 	// before processing any requests.
@@ -658,6 +703,8 @@ func NewNode(
 	consensus consensus.Backend,
 	p2pHost *p2p.P2P,
 	txPoolCfg *txpool.Config,
+	txDedupCfg *TxDedupConfig,
+	txRateLimitCfg *TxRateLimitConfig,
 ) (*Node, error) {
 	metricsOnce.Do(func() {
 		prometheus.MustRegister(nodeCollectors...)
@@ -702,6 +749,13 @@ func NewNode(
 	}
 	n.TxPool = txPool
 
+	// Prepare inbound transaction deduplication.
+	if n.txDedup, err = newTxDedupCache(txDedupCfg); err != nil {
+		return nil, fmt.Errorf("error creating transaction deduplication cache: %w", err)
+	}
+	n.txRateLimiter = newPeerRateLimiter(txRateLimitCfg)
+	n.suppressOwnTx = txDedupCfg != nil && txDedupCfg.SuppressOwn
+
 	// Register transaction message handler as that is something that all workers must handle.
 	p2pHost.RegisterHandler(runtime.ID(), p2p.TopicKindTx, &txMsgHandler{n})
 
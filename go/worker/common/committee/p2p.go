@@ -2,51 +2,394 @@ package committee
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cache/lru"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p"
+	p2pError "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/error"
+)
+
+// DefaultMaxTxSize is the maximum size of a transaction that is published or accepted via P2P in
+// case the runtime descriptor does not specify a batch size limit.
+const DefaultMaxTxSize = 1024 * 1024 // 1 MiB
+
+// ErrTxNotHandled is the error returned by HandlePeerTx hooks that are not interested in the
+// given transaction. Hooks should return it wrapped via NonFatalTxError so that dispatch proceeds
+// with the remaining hooks.
+var ErrTxNotHandled = errors.New("committee: transaction not handled")
+
+// nonFatalTxError signals that a HandlePeerTx hook failed without the transaction being invalid.
+type nonFatalTxError struct {
+	error
+}
+
+func (e *nonFatalTxError) Unwrap() error {
+	return e.error
+}
+
+// NonFatalTxError wraps an error returned by HandlePeerTx hooks to mark the error as non-fatal.
+//
+// Non-fatal errors are logged and do not abort dispatch to the remaining hooks nor penalize the
+// peer that published the transaction.
+func NonFatalTxError(err error) error {
+	return &nonFatalTxError{err}
+}
+
+// IsNonFatalTxError returns true iff the error is a non-fatal HandlePeerTx hook error.
+func IsNonFatalTxError(err error) bool {
+	var nfe *nonFatalTxError
+	return errors.As(err, &nfe)
+}
+
+// Reasons for rejecting transactions received via P2P gossip, used as metric labels.
+const (
+	txRejectMalformed = "malformed"
+	txRejectSize      = "size"
+	txRejectRateLimit = "rate_limit"
+	txRejectDuplicate = "duplicate"
+	txRejectAuthorize = "unauthorized"
+
+	// txPublishRejected is the publish result metric label used for transactions rejected before
+	// being handed off to gossipsub.
+	txPublishRejected = "rejected"
 )
 
+func (n *Node) txMetricLabels(name, value string) prometheus.Labels {
+	labels := n.getMetricLabels()
+	labels[name] = value
+	return labels
+}
+
+func (n *Node) recordTxRejected(reason string, count int) {
+	txRejectedCount.With(n.txMetricLabels("reason", reason)).Add(float64(count))
+}
+
+func (n *Node) recordTxPublished(result string, count int) {
+	txPublishedCount.With(n.txMetricLabels("result", result)).Add(float64(count))
+}
+
 type txMsgHandler struct {
 	n *Node
 }
 
+const (
+	// cborMajorTypeArray is the CBOR major type of arrays.
+	cborMajorTypeArray = 4
+	// cborMajorTypeMap is the CBOR major type of maps.
+	cborMajorTypeMap = 5
+)
+
 func (h *txMsgHandler) DecodeMessage(msg []byte) (interface{}, error) {
-	var tx []byte
-	if err := cbor.Unmarshal(msg, &tx); err != nil {
+	// Versioned transaction envelope (map), legacy single transaction (byte string) and
+	// transaction batch (array) messages are supported.
+	var txs [][]byte
+	switch {
+	case len(msg) > 0 && msg[0]>>5 == cborMajorTypeMap:
+		var env p2p.TxEnvelopeMessage
+		if err := cbor.Unmarshal(msg, &env); err != nil {
+			h.n.recordTxRejected(txRejectMalformed, 1)
+			return nil, err
+		}
+		if env.Version != p2p.TxEnvelopeVersion {
+			h.n.recordTxRejected(txRejectMalformed, 1)
+			return nil, fmt.Errorf("unsupported transaction envelope version: %d", env.Version)
+		}
+		txs = [][]byte{env.Tx}
+	case len(msg) > 0 && msg[0]>>5 == cborMajorTypeArray:
+		if err := cbor.Unmarshal(msg, &txs); err != nil {
+			h.n.recordTxRejected(txRejectMalformed, 1)
+			return nil, err
+		}
+	default:
+		var tx []byte
+		if err := cbor.Unmarshal(msg, &tx); err != nil {
+			h.n.recordTxRejected(txRejectMalformed, 1)
+			return nil, err
+		}
+		txs = [][]byte{tx}
+	}
+	if len(txs) == 0 {
+		h.n.recordTxRejected(txRejectMalformed, 1)
+		return nil, fmt.Errorf("empty transaction batch")
+	}
+	if err := h.n.checkTxSize(txs...); err != nil {
+		h.n.recordTxRejected(txRejectSize, len(txs))
 		return nil, err
 	}
-	return tx, nil
+	return txs, nil
 }
 
 func (h *txMsgHandler) AuthorizeMessage(ctx context.Context, peerID signature.PublicKey, msg interface{}) error {
-	// Everyone is allowed to publish transactions.
+	txs := msg.([][]byte) // Ensured by DecodeMessage.
+
+	// Everyone is allowed to publish transactions, subject to rate limiting.
+	if h.n.txRateLimiter != nil && !h.n.txRateLimiter.allow(peerID, len(txs)) {
+		h.n.recordTxRejected(txRejectRateLimit, len(txs))
+		return p2pError.Permanent(fmt.Errorf("transaction rate limit exceeded for peer %s", peerID))
+	}
+
+	// Consult the authorizer, if any. Rejected messages are not relayed and penalize the peer.
+	if h.n.TxAuthorizer != nil {
+		if err := h.n.TxAuthorizer.AuthorizeTxs(ctx, peerID, txs); err != nil {
+			h.n.recordTxRejected(txRejectAuthorize, len(txs))
+			return p2pError.Permanent(fmt.Errorf("transactions not authorized: %w", err))
+		}
+	}
 	return nil
 }
 
 func (h *txMsgHandler) HandleMessage(ctx context.Context, peerID signature.PublicKey, msg interface{}, isOwn bool) error {
-	tx := msg.([]byte) // Ensured by DecodeMessage.
+	txs := msg.([][]byte) // Ensured by DecodeMessage.
 
-	// Dispatch to any transaction handlers.
-	for _, hooks := range h.n.hooks {
-		err := hooks.HandlePeerTx(ctx, tx)
-		if err != nil {
-			return err
+	txReceivedCount.With(h.n.txMetricLabels("own", strconv.FormatBool(isOwn))).Add(float64(len(txs)))
+
+	// Locally-originated transactions are not dispatched when suppressed.
+	if isOwn && h.n.suppressOwnTx {
+		return nil
+	}
+
+	for _, tx := range txs {
+		// Drop transactions from peers that have been recently seen. Locally-originated
+		// transactions bypass deduplication.
+		var txHash hash.Hash
+		if h.n.txDedup != nil {
+			txHash = hash.NewFromBytes(tx)
+			if !isOwn && h.n.txDedup.isRecentlySeen(txHash) {
+				h.n.recordTxRejected(txRejectDuplicate, 1)
+				continue
+			}
+		}
+
+		// Dispatch to any transaction handlers. Fatal errors abort dispatch and are propagated so
+		// that the peer gets penalized, non-fatal errors only prevent the current hook from
+		// handling the transaction.
+		for _, hooks := range h.n.hooks {
+			err := hooks.HandlePeerTx(ctx, tx)
+			switch {
+			case err == nil:
+			case IsNonFatalTxError(err):
+				h.n.logger.Debug("transaction not handled by hook",
+					"err", err,
+					"peer_id", peerID,
+				)
+			default:
+				return err
+			}
+		}
+
+		// Only mark the transaction as seen once it has been successfully handled as the message
+		// handler is re-invoked on failure.
+		if h.n.txDedup != nil {
+			h.n.txDedup.markSeen(txHash)
 		}
 	}
 	return nil
 }
 
+// TxAuthorizer authorizes transactions received via P2P gossip before they are dispatched to the
+// node hooks, e.g., by checking a runtime-specific wrapper signature or a peer allowlist.
+type TxAuthorizer interface {
+	// AuthorizeTxs authorizes the given transactions received from the given peer in a single
+	// gossip message. Returning an error rejects the whole message and penalizes the peer.
+	AuthorizeTxs(ctx context.Context, peerID signature.PublicKey, txs [][]byte) error
+}
+
+// TxDedupConfig is the configuration of the inbound transaction deduplication cache.
+type TxDedupConfig struct {
+	// CacheSize is the maximum number of recently seen transactions to remember. Zero disables
+	// deduplication.
+	CacheSize uint64
+
+	// TTL is the duration for which a transaction is considered recently seen. Zero means that
+	// transactions are only forgotten when evicted from the cache.
+	TTL time.Duration
+
+	// SuppressOwn disables dispatching locally-originated transactions to the node hooks as
+	// those have generally already been processed before being published.
+	SuppressOwn bool
+}
+
+// txDedupCache is a bounded cache of recently seen transaction hashes.
+//
+// Only the hashes are retained so the cache does not prevent the transactions from being
+// garbage collected.
+type txDedupCache struct {
+	cache *lru.Cache
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func (c *txDedupCache) isRecentlySeen(txHash hash.Hash) bool {
+	seen, ok := c.cache.Get(txHash)
+	if !ok {
+		return false
+	}
+	return c.ttl == 0 || c.now().Sub(seen.(time.Time)) < c.ttl
+}
+
+func (c *txDedupCache) markSeen(txHash hash.Hash) {
+	_ = c.cache.Put(txHash, c.now())
+}
+
+func newTxDedupCache(cfg *TxDedupConfig) (*txDedupCache, error) {
+	if cfg == nil || cfg.CacheSize == 0 {
+		return nil, nil
+	}
+
+	cache, err := lru.New(lru.Capacity(cfg.CacheSize, false))
+	if err != nil {
+		return nil, err
+	}
+	return &txDedupCache{
+		cache: cache,
+		ttl:   cfg.TTL,
+		now:   time.Now,
+	}, nil
+}
+
 // PublishTx publishes a transaction via P2P gossipsub.
+//
+// Transactions larger than MaxTxSize are rejected. In case the same transaction is published
+// again more quickly than GetMinRepublishInterval, a p2pError.RepublishTooSoonError (matching
+// p2pError.ErrRepublishTooSoon) is returned instead of the transaction being silently dropped. Use
+// PublishTxResult to find out whether the transaction was actually accepted by gossipsub.
 func (n *Node) PublishTx(ctx context.Context, tx []byte) error {
-	n.P2P.PublishTx(ctx, n.Runtime.ID(), tx)
+	_, err := n.PublishTxResult(ctx, tx)
+	return err
+}
+
+// PublishTxResult publishes a transaction via P2P gossipsub and reports whether it was accepted,
+// queued until peers are available or dropped because it was republished more quickly than
+// GetMinRepublishInterval.
+//
+// Transactions larger than MaxTxSize are rejected.
+func (n *Node) PublishTxResult(ctx context.Context, tx []byte) (p2p.PublishResult, error) {
+	if err := n.checkTxSize(tx); err != nil {
+		n.recordTxPublished(txPublishRejected, 1)
+		return p2p.PublishDropped, err
+	}
+
+	result, err := n.P2P.PublishTxResult(ctx, n.Runtime.ID(), tx)
+	n.recordTxPublished(result.String(), 1)
+	return result, err
+}
+
+// PublishTxBatch publishes a batch of transactions via P2P gossipsub and reports the result of
+// publishing each of the transactions.
+//
+// The whole batch is rejected before anything is published in case any of the transactions is
+// larger than MaxTxSize or, when TxRuntimeExtractor is configured, is not destined for the node's
+// runtime. As peers that predate batch messages reject them, each transaction is then published
+// as a separate message exactly as if PublishTx was called for it. In case publishing any of the
+// transactions fails, the remaining transactions are still published and the first error is
+// returned together with the (partial) results.
+func (n *Node) PublishTxBatch(ctx context.Context, txs [][]byte) ([]p2p.PublishResult, error) {
+	if len(txs) == 0 {
+		return nil, nil
+	}
+	for i, tx := range txs {
+		if err := n.checkTxSize(tx); err != nil {
+			n.recordTxPublished(txPublishRejected, len(txs))
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	if n.TxRuntimeExtractor != nil {
+		if err := VerifyTxBatchRuntime(n.Runtime.ID(), txs, n.TxRuntimeExtractor); err != nil {
+			n.recordTxPublished(txPublishRejected, len(txs))
+			return nil, err
+		}
+	}
+
+	var firstErr error
+	results := make([]p2p.PublishResult, 0, len(txs))
+	for i, tx := range txs {
+		result, err := n.P2P.PublishTxResult(ctx, n.Runtime.ID(), tx)
+		n.recordTxPublished(result.String(), 1)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to publish transaction %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+	return results, firstErr
+}
+
+// MaxTxSize returns the maximum size of a transaction (or the aggregate size of a transaction
+// batch) that is published or accepted via P2P.
+//
+// As transactions larger than the runtime's maximum batch size can never be scheduled, the limit
+// is taken from the current runtime descriptor, falling back to DefaultMaxTxSize in case the
+// descriptor is not yet available.
+func (n *Node) MaxTxSize() uint64 {
+	if maxTxSize := atomic.LoadUint64(&n.maxTxSize); maxTxSize != 0 {
+		return maxTxSize
+	}
+	return DefaultMaxTxSize
+}
+
+// setCurrentDescriptorLocked updates the current runtime descriptor together with the maximum
+// transaction size derived from it.
+//
+// Guarded by n.CrossNode.
+func (n *Node) setCurrentDescriptorLocked(rt *registry.Runtime) {
+	n.CurrentDescriptor = rt
+
+	var maxTxSize uint64
+	if rt != nil {
+		maxTxSize = rt.TxnScheduler.MaxBatchSizeBytes
+	}
+	atomic.StoreUint64(&n.maxTxSize, maxTxSize)
+}
+
+func (n *Node) checkTxSize(txs ...[]byte) error {
+	var size uint64
+	for _, tx := range txs {
+		size += uint64(len(tx))
+	}
+	if maxTxSize := n.MaxTxSize(); size > maxTxSize {
+		if len(txs) == 1 {
+			return fmt.Errorf("transaction too large (size: %d, max: %d)", size, maxTxSize)
+		}
+		return fmt.Errorf("transaction batch too large (size: %d, max: %d)", size, maxTxSize)
+	}
 	return nil
 }
 
 // GetMinRepublishInterval returns the minimum republish interval that needs to be respected by
-// the caller when publishing the same message. If Publish is called for the same message more
-// quickly, the message may be dropped and not published.
+// the caller when publishing the same transaction. If PublishTx is called for the same transaction
+// more quickly, the transaction is not published.
 func (n *Node) GetMinRepublishInterval() time.Duration {
-	return n.P2P.GetMinRepublishInterval()
+	return n.P2P.GetMinRepublishIntervalForTopic(p2p.TopicKindTx)
+}
+
+// TxRuntimeExtractor is a function that extracts the identifier of the runtime that the given
+// transaction is destined for.
+type TxRuntimeExtractor func(tx []byte) (common.Namespace, error)
+
+// VerifyTxBatchRuntime verifies that all transactions in the given batch are destined for the
+// given runtime.
+//
+// The whole batch is rejected in case any of the transactions is destined for a different runtime
+// or if its runtime cannot be determined.
+func VerifyTxBatchRuntime(runtimeID common.Namespace, txs [][]byte, extractor TxRuntimeExtractor) error {
+	for i, tx := range txs {
+		id, err := extractor(tx)
+		if err != nil {
+			return fmt.Errorf("failed to determine runtime of transaction %d: %w", i, err)
+		}
+		if !id.Equal(&runtimeID) {
+			return fmt.Errorf("transaction %d is destined for runtime %s (expected: %s)", i, id, runtimeID)
+		}
+	}
+	return nil
 }
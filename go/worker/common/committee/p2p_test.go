@@ -0,0 +1,432 @@
+package committee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p"
+	p2pError "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/error"
+)
+
+type testRuntime struct {
+	runtimeRegistry.Runtime
+
+	id common.Namespace
+}
+
+func (r *testRuntime) ID() common.Namespace {
+	return r.id
+}
+
+func newTestRuntime() *testRuntime {
+	return &testRuntime{id: common.NewTestNamespaceFromSeed([]byte("committee p2p test: runtime"), 0)}
+}
+
+func TestVerifyTxBatchRuntime(t *testing.T) {
+	require := require.New(t)
+
+	runtimeA := common.NewTestNamespaceFromSeed([]byte("committee p2p test: runtime A"), 0)
+	runtimeB := common.NewTestNamespaceFromSeed([]byte("committee p2p test: runtime B"), 0)
+
+	// Transactions are prefixed by the runtime they are destined for.
+	extractor := func(tx []byte) (common.Namespace, error) {
+		var id common.Namespace
+		if len(tx) < len(id) {
+			return id, fmt.Errorf("transaction too short")
+		}
+		copy(id[:], tx)
+		return id, nil
+	}
+	newTx := func(id common.Namespace, data string) []byte {
+		return append(append([]byte{}, id[:]...), data...)
+	}
+
+	uniform := [][]byte{
+		newTx(runtimeA, "tx 1"),
+		newTx(runtimeA, "tx 2"),
+		newTx(runtimeA, "tx 3"),
+	}
+	err := VerifyTxBatchRuntime(runtimeA, uniform, extractor)
+	require.NoError(err, "VerifyTxBatchRuntime should succeed for a uniform batch")
+
+	err = VerifyTxBatchRuntime(runtimeB, uniform, extractor)
+	require.Error(err, "VerifyTxBatchRuntime should fail for a batch destined for another runtime")
+
+	mixed := [][]byte{
+		newTx(runtimeA, "tx 1"),
+		newTx(runtimeB, "tx 2"),
+		newTx(runtimeA, "tx 3"),
+	}
+	err = VerifyTxBatchRuntime(runtimeA, mixed, extractor)
+	require.Error(err, "VerifyTxBatchRuntime should fail for a mixed-runtime batch")
+	require.Contains(err.Error(), "transaction 1")
+
+	malformed := [][]byte{
+		newTx(runtimeA, "tx 1"),
+		[]byte("bad"),
+	}
+	err = VerifyTxBatchRuntime(runtimeA, malformed, extractor)
+	require.Error(err, "VerifyTxBatchRuntime should fail for a batch with malformed transactions")
+
+	err = VerifyTxBatchRuntime(runtimeA, nil, extractor)
+	require.NoError(err, "VerifyTxBatchRuntime should succeed for an empty batch")
+}
+
+func TestTxSizeLimit(t *testing.T) {
+	require := require.New(t)
+
+	n := &Node{Runtime: newTestRuntime()}
+	h := &txMsgHandler{n}
+
+	// Without a runtime descriptor the default limit should be used.
+	require.EqualValues(DefaultMaxTxSize, n.MaxTxSize())
+	_, err := h.DecodeMessage(cbor.Marshal(make([]byte, DefaultMaxTxSize)))
+	require.NoError(err, "DecodeMessage should accept transactions within the default limit")
+	_, err = h.DecodeMessage(cbor.Marshal(make([]byte, DefaultMaxTxSize+1)))
+	require.Error(err, "DecodeMessage should reject transactions over the default limit")
+
+	// The limit should follow the runtime descriptor.
+	const maxTxSize = 2048
+	n.setCurrentDescriptorLocked(&registry.Runtime{
+		TxnScheduler: registry.TxnSchedulerParameters{
+			MaxBatchSizeBytes: maxTxSize,
+		},
+	})
+	require.EqualValues(maxTxSize, n.MaxTxSize())
+
+	tx, err := h.DecodeMessage(cbor.Marshal(make([]byte, maxTxSize)))
+	require.NoError(err, "DecodeMessage should accept transactions within the limit")
+	require.Len(tx, 1)
+	require.Len(tx.([][]byte)[0], maxTxSize)
+	_, err = h.DecodeMessage(cbor.Marshal(make([]byte, maxTxSize+1)))
+	require.Error(err, "DecodeMessage should reject oversized transactions")
+
+	// Oversized transactions should be rejected before being published.
+	err = n.PublishTx(context.Background(), make([]byte, maxTxSize+1))
+	require.Error(err, "PublishTx should reject oversized transactions")
+	require.Contains(err.Error(), "transaction too large")
+
+	// Descriptors without a batch size limit should use the default limit.
+	n.setCurrentDescriptorLocked(&registry.Runtime{})
+	require.EqualValues(DefaultMaxTxSize, n.MaxTxSize())
+}
+
+type testTxHooks struct {
+	NodeHooks
+
+	txs [][]byte
+	err error
+}
+
+func (h *testTxHooks) HandlePeerTx(ctx context.Context, tx []byte) error {
+	if h.err != nil {
+		return h.err
+	}
+	h.txs = append(h.txs, tx)
+	return nil
+}
+
+func TestTxDedup(t *testing.T) {
+	require := require.New(t)
+
+	txDedup, err := newTxDedupCache(&TxDedupConfig{CacheSize: 2, TTL: time.Minute})
+	require.NoError(err, "newTxDedupCache")
+	now := time.Now()
+	txDedup.now = func() time.Time { return now }
+
+	hooks := &testTxHooks{}
+	n := &Node{Runtime: newTestRuntime(), hooks: []NodeHooks{hooks}, txDedup: txDedup}
+	h := &txMsgHandler{n}
+	ctx := context.Background()
+	peerID := signature.PublicKey{}
+
+	// Duplicate transactions from peers should be dropped.
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1")}, false))
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1")}, false))
+	require.Len(hooks.txs, 1, "duplicate transaction should be dropped")
+
+	// Locally-originated transactions should always be dispatched.
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1")}, true))
+	require.Len(hooks.txs, 2, "own transaction should be dispatched")
+
+	// Transactions should be dispatched again after the TTL expires.
+	now = now.Add(time.Minute)
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1")}, false))
+	require.Len(hooks.txs, 3, "transaction should be dispatched after the TTL expires")
+
+	// Failed transactions should not be marked as seen so that retries are dispatched.
+	hooks.err = fmt.Errorf("failed")
+	require.Error(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 2")}, false))
+	hooks.err = nil
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 2")}, false))
+	require.Len(hooks.txs, 4, "retried transaction should be dispatched")
+
+	// The cache should be bounded.
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 3")}, false))
+	require.EqualValues(2, txDedup.cache.Size(), "cache should be bounded")
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1")}, false))
+	require.Len(hooks.txs, 6, "evicted transaction should be dispatched")
+
+	// Deduplication can be disabled.
+	txDedup, err = newTxDedupCache(&TxDedupConfig{})
+	require.NoError(err, "newTxDedupCache")
+	require.Nil(txDedup, "zero cache size should disable deduplication")
+}
+
+func TestTxSuppressOwn(t *testing.T) {
+	require := require.New(t)
+
+	hooks := &testTxHooks{}
+	n := &Node{Runtime: newTestRuntime(), hooks: []NodeHooks{hooks}}
+	h := &txMsgHandler{n}
+	ctx := context.Background()
+	peerID := signature.PublicKey{}
+
+	// By default, own transactions should be dispatched.
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1")}, true))
+	require.Len(hooks.txs, 1, "own transaction should be dispatched by default")
+
+	// With suppression enabled, own transactions should not be re-dispatched.
+	n.suppressOwnTx = true
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 2")}, true))
+	require.Len(hooks.txs, 1, "own transaction should not be dispatched when suppressed")
+
+	// Transactions from peers should still be dispatched.
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 2")}, false))
+	require.Len(hooks.txs, 2, "peer transaction should be dispatched")
+}
+
+func TestTxEnvelope(t *testing.T) {
+	require := require.New(t)
+
+	n := &Node{Runtime: newTestRuntime()}
+	h := &txMsgHandler{n}
+
+	// Legacy bare transactions should be accepted.
+	msg, err := h.DecodeMessage(cbor.Marshal(p2p.TxMessage("tx 1")))
+	require.NoError(err, "DecodeMessage should accept legacy transactions")
+	require.EqualValues([][]byte{[]byte("tx 1")}, msg)
+
+	// Versioned envelopes should be accepted.
+	msg, err = h.DecodeMessage(cbor.Marshal(&p2p.TxEnvelopeMessage{
+		Version: p2p.TxEnvelopeVersion,
+		Tx:      []byte("tx 2"),
+	}))
+	require.NoError(err, "DecodeMessage should accept transaction envelopes")
+	require.EqualValues([][]byte{[]byte("tx 2")}, msg)
+
+	// Unknown envelope versions should be rejected.
+	_, err = h.DecodeMessage(cbor.Marshal(&p2p.TxEnvelopeMessage{
+		Version: p2p.TxEnvelopeVersion + 1,
+		Tx:      []byte("tx 3"),
+	}))
+	require.Error(err, "DecodeMessage should reject unknown envelope versions")
+
+	// Malformed envelopes should be rejected.
+	_, err = h.DecodeMessage(cbor.Marshal(map[string]string{"tx": "tx 4"}))
+	require.Error(err, "DecodeMessage should reject malformed envelopes")
+
+	// Size limits should apply to enveloped transactions.
+	_, err = h.DecodeMessage(cbor.Marshal(&p2p.TxEnvelopeMessage{
+		Version: p2p.TxEnvelopeVersion,
+		Tx:      make([]byte, DefaultMaxTxSize+1),
+	}))
+	require.Error(err, "DecodeMessage should reject oversized transactions")
+}
+
+func TestTxBatch(t *testing.T) {
+	require := require.New(t)
+
+	hooks := &testTxHooks{}
+	n := &Node{Runtime: newTestRuntime(), hooks: []NodeHooks{hooks}}
+	h := &txMsgHandler{n}
+	ctx := context.Background()
+	peerID := signature.PublicKey{}
+
+	// Both single transaction and batch wire formats should be accepted.
+	msg, err := h.DecodeMessage(cbor.Marshal([]byte("tx 1")))
+	require.NoError(err, "DecodeMessage should accept single transactions")
+	require.EqualValues([][]byte{[]byte("tx 1")}, msg)
+
+	batch := p2p.TxBatchMessage{[]byte("tx 2"), []byte("tx 3")}
+	msg, err = h.DecodeMessage(cbor.Marshal(batch))
+	require.NoError(err, "DecodeMessage should accept transaction batches")
+	require.EqualValues([][]byte(batch), msg)
+
+	_, err = h.DecodeMessage(cbor.Marshal(p2p.TxBatchMessage{}))
+	require.Error(err, "DecodeMessage should reject empty batches")
+	_, err = h.DecodeMessage(cbor.Marshal(42))
+	require.Error(err, "DecodeMessage should reject malformed messages")
+
+	// Each transaction in a batch should be dispatched to the hooks.
+	require.NoError(h.HandleMessage(ctx, peerID, msg, false))
+	require.EqualValues([][]byte(batch), hooks.txs)
+
+	// Size limits should apply to the aggregate payload.
+	const maxTxSize = 2048
+	n.setCurrentDescriptorLocked(&registry.Runtime{
+		TxnScheduler: registry.TxnSchedulerParameters{
+			MaxBatchSizeBytes: maxTxSize,
+		},
+	})
+	_, err = h.DecodeMessage(cbor.Marshal(p2p.TxBatchMessage{make([]byte, maxTxSize/2), make([]byte, maxTxSize/2)}))
+	require.NoError(err, "DecodeMessage should accept batches within the limit")
+	_, err = h.DecodeMessage(cbor.Marshal(p2p.TxBatchMessage{make([]byte, maxTxSize/2), make([]byte, maxTxSize/2+1)}))
+	require.Error(err, "DecodeMessage should reject oversized batches")
+
+	results, err := n.PublishTxBatch(ctx, [][]byte{[]byte("tx"), make([]byte, maxTxSize+1)})
+	require.Error(err, "PublishTxBatch should reject batches with oversized transactions")
+	require.Contains(err.Error(), "transaction 1: transaction too large")
+	require.Nil(results, "nothing should be published for rejected batches")
+	results, err = n.PublishTxBatch(ctx, nil)
+	require.NoError(err, "PublishTxBatch should ignore empty batches")
+	require.Empty(results)
+}
+
+func TestTxHookErrors(t *testing.T) {
+	require := require.New(t)
+
+	hooksA := &testTxHooks{}
+	hooksB := &testTxHooks{}
+	n := &Node{
+		Runtime: newTestRuntime(),
+		hooks:   []NodeHooks{hooksA, hooksB},
+		logger:  logging.GetLogger("worker/common/committee/test"),
+	}
+	h := &txMsgHandler{n}
+	ctx := context.Background()
+	peerID := signature.PublicKey{}
+
+	// Non-fatal errors should not prevent dispatch to the remaining hooks.
+	hooksA.err = NonFatalTxError(ErrTxNotHandled)
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1")}, false))
+	require.Len(hooksB.txs, 1, "transaction should be dispatched to the remaining hooks")
+
+	hooksA.err = NonFatalTxError(fmt.Errorf("busy"))
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 2")}, false))
+	require.Len(hooksB.txs, 2, "transaction should be dispatched to the remaining hooks")
+
+	// Fatal errors should abort dispatch and be propagated.
+	hooksA.err = fmt.Errorf("invalid transaction")
+	err := h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 3")}, false)
+	require.Error(err, "fatal errors should be propagated")
+	require.False(IsNonFatalTxError(err))
+	require.Len(hooksB.txs, 2, "fatal errors should abort dispatch")
+
+	notHandled := NonFatalTxError(ErrTxNotHandled)
+	require.True(IsNonFatalTxError(notHandled))
+	require.True(IsNonFatalTxError(fmt.Errorf("wrapped: %w", notHandled)))
+	require.ErrorIs(notHandled, ErrTxNotHandled)
+
+	// Generic non-fatal errors should not match ErrTxNotHandled.
+	busy := NonFatalTxError(fmt.Errorf("busy"))
+	require.True(IsNonFatalTxError(busy))
+	require.False(errors.Is(busy, ErrTxNotHandled), "generic non-fatal errors should not match ErrTxNotHandled")
+}
+
+type testTxAuthorizer struct {
+	peerID signature.PublicKey
+	txs    [][]byte
+	err    error
+}
+
+func (a *testTxAuthorizer) AuthorizeTxs(ctx context.Context, peerID signature.PublicKey, txs [][]byte) error {
+	a.peerID = peerID
+	a.txs = txs
+	return a.err
+}
+
+func TestTxAuthorizer(t *testing.T) {
+	require := require.New(t)
+
+	n := &Node{Runtime: newTestRuntime()}
+	h := &txMsgHandler{n}
+	ctx := context.Background()
+	peerID := memorySigner.NewTestSigner("committee p2p test: authorizer").Public()
+	txs := [][]byte{[]byte("tx 1"), []byte("tx 2")}
+
+	// All transactions should be authorized by default.
+	require.NoError(h.AuthorizeMessage(ctx, peerID, txs), "AuthorizeMessage should allow all by default")
+
+	// The authorizer should be consulted.
+	authorizer := &testTxAuthorizer{}
+	n.TxAuthorizer = authorizer
+	require.NoError(h.AuthorizeMessage(ctx, peerID, txs), "AuthorizeMessage")
+	require.Equal(peerID, authorizer.peerID, "authorizer should receive the peer ID")
+	require.EqualValues(txs, authorizer.txs, "authorizer should receive the decoded message")
+
+	// Rejected messages should not be relayed and the peer should be penalized.
+	authorizer.err = fmt.Errorf("invalid signature")
+	err := h.AuthorizeMessage(ctx, peerID, txs)
+	require.ErrorIs(err, authorizer.err)
+	require.True(p2pError.IsPermanent(err), "rejections should be permanent")
+	require.False(p2pError.ShouldRelay(err), "rejected messages should not be relayed")
+}
+
+func TestTxMetrics(t *testing.T) {
+	require := require.New(t)
+
+	rt := &testRuntime{id: common.NewTestNamespaceFromSeed([]byte("committee p2p test: metrics"), 0)}
+	// Metrics are global so only changes caused by this test are checked.
+	counter := func(c *prometheus.CounterVec, value string) float64 {
+		return testutil.ToFloat64(c.WithLabelValues(rt.id.String(), value))
+	}
+
+	hooks := &testTxHooks{}
+	n := &Node{
+		Runtime:       rt,
+		hooks:         []NodeHooks{hooks},
+		txRateLimiter: newPeerRateLimiter(&TxRateLimitConfig{Rate: 1, Burst: 2}),
+	}
+	h := &txMsgHandler{n}
+	ctx := context.Background()
+	peerID := signature.PublicKey{}
+
+	// Malformed messages should be counted.
+	before := counter(txRejectedCount, txRejectMalformed)
+	_, err := h.DecodeMessage(cbor.Marshal(42))
+	require.Error(err, "DecodeMessage should reject malformed messages")
+	_, err = h.DecodeMessage(cbor.Marshal(p2p.TxBatchMessage{}))
+	require.Error(err, "DecodeMessage should reject empty batches")
+	require.EqualValues(2, counter(txRejectedCount, txRejectMalformed)-before)
+
+	// Oversized transactions should be counted.
+	before = counter(txRejectedCount, txRejectSize)
+	_, err = h.DecodeMessage(cbor.Marshal(p2p.TxBatchMessage{make([]byte, DefaultMaxTxSize), []byte("tx")}))
+	require.Error(err, "DecodeMessage should reject oversized batches")
+	require.EqualValues(2, counter(txRejectedCount, txRejectSize)-before)
+
+	// Rate limited transactions should be counted.
+	before = counter(txRejectedCount, txRejectRateLimit)
+	require.Error(h.AuthorizeMessage(ctx, peerID, [][]byte{[]byte("tx 1"), []byte("tx 2"), []byte("tx 3")}))
+	require.EqualValues(3, counter(txRejectedCount, txRejectRateLimit)-before)
+
+	// Received transactions should be counted by origin.
+	beforeGossip := counter(txReceivedCount, "false")
+	beforeDirect := counter(txReceivedCount, "true")
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 1"), []byte("tx 2")}, false))
+	require.NoError(h.HandleMessage(ctx, peerID, [][]byte{[]byte("tx 3")}, true))
+	require.EqualValues(2, counter(txReceivedCount, "false")-beforeGossip)
+	require.EqualValues(1, counter(txReceivedCount, "true")-beforeDirect)
+
+	// Transactions rejected before publishing should be counted.
+	before = counter(txPublishedCount, txPublishRejected)
+	require.Error(n.PublishTx(ctx, make([]byte, DefaultMaxTxSize+1)))
+	_, err = n.PublishTxBatch(ctx, [][]byte{make([]byte, DefaultMaxTxSize+1), []byte("tx")})
+	require.Error(err)
+	require.EqualValues(3, counter(txPublishedCount, txPublishRejected)-before)
+}
@@ -0,0 +1,95 @@
+package committee
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// maxRateLimitedPeers is the number of tracked peers after which idle peers are pruned.
+const maxRateLimitedPeers = 4096
+
+// TxRateLimitConfig is the configuration of the per-peer inbound transaction rate limiter.
+type TxRateLimitConfig struct {
+	// Rate is the sustained number of transactions per second that a single peer may publish.
+	// Zero disables rate limiting.
+	Rate float64
+
+	// Burst is the maximum number of transactions that a single peer may publish at once. Batches
+	// containing more transactions are always rejected.
+	Burst uint64
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// peerRateLimiter is a token bucket rate limiter keyed by peer.
+type peerRateLimiter struct {
+	sync.Mutex
+
+	rate    float64
+	burst   float64
+	now     func() time.Time
+	buckets map[signature.PublicKey]*tokenBucket
+}
+
+// allow consumes n tokens from the given peer's bucket and returns true iff they were available.
+func (l *peerRateLimiter) allow(peerID signature.PublicKey, n int) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	b := l.buckets[peerID]
+	if b == nil {
+		if len(l.buckets) >= maxRateLimitedPeers {
+			l.pruneLocked(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[peerID] = b
+	}
+
+	b.tokens = l.refill(b, now)
+	b.last = now
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+func (l *peerRateLimiter) refill(b *tokenBucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*l.rate
+	if tokens > l.burst {
+		tokens = l.burst
+	}
+	return tokens
+}
+
+// pruneLocked removes buckets of idle peers, which are indistinguishable from new peers.
+func (l *peerRateLimiter) pruneLocked(now time.Time) {
+	for peerID, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, peerID)
+		}
+	}
+}
+
+func newPeerRateLimiter(cfg *TxRateLimitConfig) *peerRateLimiter {
+	if cfg == nil || cfg.Rate <= 0 {
+		return nil
+	}
+
+	burst := float64(cfg.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &peerRateLimiter{
+		rate:    cfg.Rate,
+		burst:   burst,
+		now:     time.Now,
+		buckets: make(map[signature.PublicKey]*tokenBucket),
+	}
+}
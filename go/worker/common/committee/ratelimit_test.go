@@ -0,0 +1,60 @@
+package committee
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	p2pError "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/error"
+)
+
+func TestTxRateLimit(t *testing.T) {
+	require := require.New(t)
+
+	limiter := newPeerRateLimiter(&TxRateLimitConfig{Rate: 1, Burst: 3})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	n := &Node{Runtime: newTestRuntime(), txRateLimiter: limiter}
+	h := &txMsgHandler{n}
+	ctx := context.Background()
+	peerA := memorySigner.NewTestSigner("committee rate limit test: peer A").Public()
+	peerB := memorySigner.NewTestSigner("committee rate limit test: peer B").Public()
+
+	// Drive peer A over the limit.
+	for i := 0; i < 3; i++ {
+		require.NoError(h.AuthorizeMessage(ctx, peerA, [][]byte{[]byte("tx")}), "burst should be allowed")
+	}
+	err := h.AuthorizeMessage(ctx, peerA, [][]byte{[]byte("tx")})
+	require.Error(err, "messages over the limit should be refused")
+	require.True(p2pError.IsPermanent(err), "rate limit errors should be permanent")
+	require.Error(h.AuthorizeMessage(ctx, peerA, [][]byte{[]byte("tx")}), "later messages should be refused")
+
+	// Other peers should be unaffected.
+	for i := 0; i < 3; i++ {
+		require.NoError(h.AuthorizeMessage(ctx, peerB, [][]byte{[]byte("tx")}), "other peers should be unaffected")
+	}
+
+	// Tokens should be replenished over time.
+	now = now.Add(time.Second)
+	require.NoError(h.AuthorizeMessage(ctx, peerA, [][]byte{[]byte("tx")}), "tokens should be replenished")
+	require.Error(h.AuthorizeMessage(ctx, peerA, [][]byte{[]byte("tx")}), "only replenished tokens should be available")
+
+	// Idle peers should be pruned.
+	now = now.Add(time.Minute)
+	limiter.pruneLocked(now)
+	require.Empty(limiter.buckets, "idle peers should be pruned")
+
+	// Batches should be charged per transaction.
+	batch := [][]byte{[]byte("tx 1"), []byte("tx 2"), []byte("tx 3"), []byte("tx 4")}
+	require.Error(h.AuthorizeMessage(ctx, peerA, batch), "batches over the burst should be refused")
+	require.NoError(h.AuthorizeMessage(ctx, peerA, batch[:3]), "batches within the burst should be allowed")
+	require.Error(h.AuthorizeMessage(ctx, peerA, batch[:1]), "batches should consume all tokens")
+
+	// Rate limiting can be disabled.
+	require.Nil(newPeerRateLimiter(&TxRateLimitConfig{}), "zero rate should disable rate limiting")
+	require.NoError((&txMsgHandler{&Node{Runtime: newTestRuntime()}}).AuthorizeMessage(ctx, peerA, [][]byte{[]byte("tx")}))
+}
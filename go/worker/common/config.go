@@ -11,6 +11,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/runtime/txpool"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
 	"github.com/oasisprotocol/oasis-core/go/worker/common/configparser"
 )
 
@@ -30,6 +31,12 @@ var (
 	cfgCheckTxMaxBatchSize = "worker.tx_pool.check_tx_max_batch_size"
 	cfgRecheckInterval     = "worker.tx_pool.recheck_interval"
 
+	cfgTxDedupCacheSize = "worker.p2p.tx_dedup_cache_size"
+	cfgTxDedupTTL       = "worker.p2p.tx_dedup_ttl"
+	cfgTxSuppressOwn    = "worker.p2p.tx_suppress_own"
+	cfgTxRateLimit      = "worker.p2p.tx_rate_limit"
+	cfgTxRateLimitBurst = "worker.p2p.tx_rate_limit_burst"
+
 	// Flags has the configuration flags.
 	Flags = flag.NewFlagSet("", flag.ContinueOnError)
 )
@@ -40,7 +47,9 @@ type Config struct { // nolint: maligned
 	ClientAddresses []node.Address
 	SentryAddresses []node.TLSAddress
 
-	TxPool txpool.Config
+	TxPool      txpool.Config
+	TxDedup     committee.TxDedupConfig
+	TxRateLimit committee.TxRateLimitConfig
 
 	logger *logging.Logger
 }
@@ -103,6 +112,15 @@ func NewConfig() (*Config, error) {
 
 			RecheckInterval: viper.GetUint64(cfgRecheckInterval),
 		},
+		TxDedup: committee.TxDedupConfig{
+			CacheSize:   viper.GetUint64(cfgTxDedupCacheSize),
+			TTL:         viper.GetDuration(cfgTxDedupTTL),
+			SuppressOwn: viper.GetBool(cfgTxSuppressOwn),
+		},
+		TxRateLimit: committee.TxRateLimitConfig{
+			Rate:  viper.GetFloat64(cfgTxRateLimit),
+			Burst: viper.GetUint64(cfgTxRateLimitBurst),
+		},
 		logger: logging.GetLogger("worker/config"),
 	}
 
@@ -120,5 +138,11 @@ func init() {
 	Flags.Uint64(cfgCheckTxMaxBatchSize, 10_000, "Maximum check tx batch size")
 	Flags.Uint64(cfgRecheckInterval, 32, "Transaction recheck interval (in rounds)")
 
+	Flags.Uint64(cfgTxDedupCacheSize, 10_000, "Maximum number of recently seen gossiped transactions to ignore (0 disables deduplication)")
+	Flags.Duration(cfgTxDedupTTL, 1*time.Minute, "Duration for which a gossiped transaction is considered recently seen")
+	Flags.Bool(cfgTxSuppressOwn, false, "Do not dispatch locally-originated gossiped transactions to the node hooks")
+	Flags.Float64(cfgTxRateLimit, 1_000, "Maximum sustained number of gossiped transactions per second accepted from a single peer (0 disables rate limiting)")
+	Flags.Uint64(cfgTxRateLimitBurst, 10_000, "Maximum number of gossiped transactions accepted from a single peer in a burst")
+
 	_ = viper.BindPFlags(Flags)
 }
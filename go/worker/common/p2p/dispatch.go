@@ -12,6 +12,8 @@ import (
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	cmnBackoff "github.com/oasisprotocol/oasis-core/go/common/backoff"
+	"github.com/oasisprotocol/oasis-core/go/common/cache/lru"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	p2pError "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/error"
@@ -22,6 +24,10 @@ const (
 	redispatchMaxRetries = 5
 	rawMsgQueueSize      = 50
 
+	// recentlyPublishedCacheSize is the maximum number of recently published messages to track
+	// per topic in order to detect messages dropped due to being republished too quickly.
+	recentlyPublishedCacheSize = 10000
+
 	// peerMessageProcessTimeout is the maximum time that peer message processing can take.
 	peerMessageProcessTimeout = 10 * time.Second
 )
@@ -51,7 +57,8 @@ type Handler interface {
 type topicHandler struct {
 	ctx context.Context
 
-	p2p *P2P
+	p2p  *P2P
+	kind TopicKind
 
 	topic       *pubsub.Topic
 	host        core.Host
@@ -62,6 +69,8 @@ type topicHandler struct {
 
 	pendingQueue chan *rawMessage
 
+	recentlyPublished *lru.Cache
+
 	logger *logging.Logger
 }
 
@@ -214,7 +223,18 @@ func (h *topicHandler) retryWorker(m *queuedMsg) {
 	}
 }
 
-func (h *topicHandler) tryPublishing(rawMsg []byte) error {
+func (h *topicHandler) tryPublishing(rawMsg []byte) (PublishResult, error) {
+	// Messages republished more quickly than the minimum republish interval will be treated as
+	// duplicates and silently dropped by gossipsub, so report them to the caller instead.
+	msgHash := hash.NewFromBytes(rawMsg)
+	now := time.Now()
+	if v, ok := h.recentlyPublished.Peek(msgHash); ok {
+		interval := h.p2p.GetMinRepublishIntervalForTopic(h.kind)
+		if sinceLast := now.Sub(v.(time.Time)); sinceLast < interval {
+			return PublishDropped, &p2pError.RepublishTooSoonError{Remaining: interval - sinceLast}
+		}
+	}
+
 	if len(h.topic.ListPeers()) == 0 {
 		// On init, if there are no peers, the library will sometimes just
 		// swallow the message and mark it as seen without retrying or returning
@@ -222,13 +242,18 @@ func (h *topicHandler) tryPublishing(rawMsg []byte) error {
 		h.logger.Debug("no connected peers, handing off to retry worker")
 		select {
 		case h.pendingQueue <- &rawMessage{rawMsg}:
-			return nil
+			return PublishQueued, nil
 		default:
-			return fmt.Errorf("worker/common/p2p: message queue overflow, libp2p still not initialized")
+			return PublishDropped, fmt.Errorf("worker/common/p2p: message queue overflow, libp2p still not initialized")
 		}
 	}
 
-	return h.topic.Publish(h.ctx, rawMsg)
+	if err := h.topic.Publish(h.ctx, rawMsg); err != nil {
+		return PublishDropped, err
+	}
+	_ = h.recentlyPublished.Put(msgHash, now)
+
+	return PublishAccepted, nil
 }
 
 // pendingMessagesWorker handles retrying for P2P messages when there are no connected peers.
@@ -272,14 +297,21 @@ func newTopicHandler(p *P2P, runtimeID common.Namespace, kind TopicKind, handler
 		return "", nil, fmt.Errorf("worker/common/p2p: failed to join topic '%s': %w", topicID, err)
 	}
 
+	recentlyPublished, err := lru.New(lru.Capacity(recentlyPublishedCacheSize, false))
+	if err != nil {
+		return "", nil, fmt.Errorf("worker/common/p2p: failed to create recently published cache: %w", err)
+	}
+
 	h := &topicHandler{
-		ctx:          p.ctx, // TODO: Should this support individual cancelation?
-		p2p:          p,
-		topic:        topic,
-		host:         p.host,
-		handler:      handler,
-		pendingQueue: make(chan *rawMessage, rawMsgQueueSize),
-		logger:       logging.GetLogger("worker/common/p2p/" + topicID),
+		ctx:               p.ctx, // TODO: Should this support individual cancelation?
+		p2p:               p,
+		kind:              kind,
+		topic:             topic,
+		host:              p.host,
+		handler:           handler,
+		pendingQueue:      make(chan *rawMessage, rawMsgQueueSize),
+		recentlyPublished: recentlyPublished,
+		logger:            logging.GetLogger("worker/common/p2p/" + topicID),
 	}
 	if h.cancelRelay, err = h.topic.Relay(); err != nil {
 		// Well, ok, fine.  This should NEVER happen, but try to back out
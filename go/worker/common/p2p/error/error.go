@@ -4,6 +4,8 @@ package error
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/cenkalti/backoff/v4"
 )
@@ -11,6 +13,26 @@ import (
 // ErrUnhandledMessage indicates to the dispatcher that the handler didn't handle the message.
 var ErrUnhandledMessage = Permanent(errors.New("unhandled message"))
 
+// ErrRepublishTooSoon is the error returned when the same message is published again more quickly
+// than the topic's minimum republish interval. The returned error is a RepublishTooSoonError which
+// also carries the remaining time to wait before republishing.
+var ErrRepublishTooSoon = errors.New("message republished too soon")
+
+// RepublishTooSoonError is the error returned when the same message is published again more
+// quickly than the topic's minimum republish interval.
+type RepublishTooSoonError struct {
+	// Remaining is the remaining time to wait before the message can be republished.
+	Remaining time.Duration
+}
+
+func (e *RepublishTooSoonError) Error() string {
+	return fmt.Sprintf("%s (retry in %s)", ErrRepublishTooSoon, e.Remaining)
+}
+
+func (e *RepublishTooSoonError) Unwrap() error {
+	return ErrRepublishTooSoon
+}
+
 // relayError signals that the message should be relayed.
 type relayError struct {
 	error
@@ -2,6 +2,7 @@ package error
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -47,6 +48,18 @@ func TestShouldRelay(t *testing.T) {
 	require.True(ShouldRelay(Relayable(Permanent(io.EOF))), "relayable permanent errors should be relayed")
 }
 
+func TestRepublishTooSoonError(t *testing.T) {
+	require := require.New(t)
+
+	var err error = &RepublishTooSoonError{Remaining: 40 * time.Second}
+	require.ErrorIs(err, ErrRepublishTooSoon)
+	require.Contains(err.Error(), "40s")
+
+	var rtsErr *RepublishTooSoonError
+	require.ErrorAs(fmt.Errorf("wrapped: %w", err), &rtsErr)
+	require.EqualValues(40*time.Second, rtsErr.Remaining)
+}
+
 func TestEnsurePermanent(t *testing.T) {
 	require := require.New(t)
 
@@ -30,6 +30,9 @@ const (
 	CfgP2PMaxNumPeers = "worker.p2p.max_num_peers"
 	// CfgP2PPeerGracePeriod is the peer grace period.
 	CfgP2PPeerGracePeriod = "worker.p2p.peer_grace_period"
+	// CfgP2PTxMinRepublishInterval is the minimum republish interval for transactions. If not
+	// set, the default minimum republish interval is used.
+	CfgP2PTxMinRepublishInterval = "worker.p2p.tx_min_republish_interval"
 )
 
 // Flags has the configuration flags.
@@ -45,6 +48,7 @@ func init() {
 	Flags.Float64(CfgP2PConnectednessLowWater, 0.2, "Set the low water mark at which the peer manager will try to reconnect to peers")
 	Flags.Uint32(CfgP2PMaxNumPeers, 100, "Set maximum number of P2P peers")
 	Flags.Duration(CfgP2PPeerGracePeriod, 20*time.Second, "Time duration for new peer connections to be immune from pruning")
+	Flags.Duration(CfgP2PTxMinRepublishInterval, 0, "Minimum republish interval for transactions (if not set, the default interval is used)")
 
 	_ = viper.BindPFlags(Flags)
 }
@@ -3,6 +3,7 @@ package p2p
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -28,6 +29,7 @@ import (
 	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
 	registryAPI "github.com/oasisprotocol/oasis-core/go/registry/api"
 	"github.com/oasisprotocol/oasis-core/go/worker/common/configparser"
+	p2pError "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/error"
 	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
 )
 
@@ -47,6 +49,10 @@ const (
 	TopicKindCommittee TopicKind = "committee"
 	// TopicKindTx is the topic kind for the topic that is used to gossip transactions.
 	TopicKindTx TopicKind = "tx"
+
+	// TopicKindDefault is the topic kind used to query the default minimum republish interval,
+	// which applies to all topic kinds without a specific one. It is not a valid gossipsub topic.
+	TopicKindDefault TopicKind = ""
 )
 
 var allowUnroutableAddresses bool
@@ -71,6 +77,10 @@ type P2P struct {
 	registerAddresses []multiaddr.Multiaddr
 	topics            map[common.Namespace]map[TopicKind]*topicHandler
 
+	// minRepublishIntervals are the topic kind-specific minimum republish intervals. Topic kinds
+	// that are not listed use the default interval.
+	minRepublishIntervals map[TopicKind]time.Duration
+
 	logger *logging.Logger
 }
 
@@ -163,7 +173,36 @@ func filterGloballyReachableAddresses(addrs []multiaddr.Multiaddr) []multiaddr.M
 	return ret
 }
 
-func (p *P2P) publish(ctx context.Context, runtimeID common.Namespace, kind TopicKind, msg interface{}) {
+// PublishResult is the result of publishing a message.
+type PublishResult uint8
+
+const (
+	// PublishAccepted means that the message was accepted by gossipsub.
+	PublishAccepted PublishResult = iota
+	// PublishQueued means that the message was queued for publishing as there are no connected
+	// peers yet.
+	PublishQueued
+	// PublishDropped means that the message was not published. This happens when the same message
+	// is republished more quickly than GetMinRepublishInterval (in which case the returned error
+	// matches p2pError.ErrRepublishTooSoon) or when publishing fails.
+	PublishDropped
+)
+
+// String returns a string representation of the publish result.
+func (r PublishResult) String() string {
+	switch r {
+	case PublishAccepted:
+		return "accepted"
+	case PublishQueued:
+		return "queued"
+	case PublishDropped:
+		return "dropped"
+	default:
+		return fmt.Sprintf("[unknown publish result: %d]", uint8(r))
+	}
+}
+
+func (p *P2P) publish(ctx context.Context, runtimeID common.Namespace, kind TopicKind, msg interface{}) (PublishResult, error) {
 	rawMsg := cbor.Marshal(msg)
 
 	p.RLock()
@@ -175,7 +214,7 @@ func (p *P2P) publish(ctx context.Context, runtimeID common.Namespace, kind Topi
 			"runtime_id", runtimeID,
 			"kind", kind,
 		)
-		return
+		return PublishDropped, fmt.Errorf("worker/common/p2p: unknown runtime ID: %s", runtimeID)
 	}
 
 	h := topics[kind]
@@ -184,29 +223,49 @@ func (p *P2P) publish(ctx context.Context, runtimeID common.Namespace, kind Topi
 			"runtime_id", runtimeID,
 			"kind", kind,
 		)
-		return
+		return PublishDropped, fmt.Errorf("worker/common/p2p: unsupported topic kind: %s", kind)
 	}
 
-	if err := h.tryPublishing(rawMsg); err != nil {
+	result, err := h.tryPublishing(rawMsg)
+	switch {
+	case errors.Is(err, p2pError.ErrRepublishTooSoon):
+		h.logger.Debug("not republishing message",
+			"err", err,
+		)
+		return result, err
+	case err != nil:
 		h.logger.Error("failed to publish message to the network",
 			"err", err,
 		)
+		return result, err
 	}
 
 	p.logger.Debug("published message",
 		"runtime_id", runtimeID,
 		"kind", kind,
+		"result", result,
 	)
+
+	return result, nil
 }
 
 // PublishCommittee publishes a committee message.
 func (p *P2P) PublishCommittee(ctx context.Context, runtimeID common.Namespace, msg *CommitteeMessage) {
-	p.publish(ctx, runtimeID, TopicKindCommittee, msg)
+	_, _ = p.publish(ctx, runtimeID, TopicKindCommittee, msg)
 }
 
 // PublishCommittee publishes a transaction message.
 func (p *P2P) PublishTx(ctx context.Context, runtimeID common.Namespace, msg TxMessage) {
-	p.publish(ctx, runtimeID, TopicKindTx, msg)
+	_, _ = p.PublishTxResult(ctx, runtimeID, msg)
+}
+
+// PublishTxResult publishes a transaction message and reports whether the message was accepted,
+// queued or dropped.
+//
+// The transaction is published in the bare TxMessage wire format as that is the only format
+// understood by all peers. Receivers also accept transactions wrapped in a TxEnvelopeMessage.
+func (p *P2P) PublishTxResult(ctx context.Context, runtimeID common.Namespace, msg TxMessage) (PublishResult, error) {
+	return p.publish(ctx, runtimeID, TopicKindTx, msg)
 }
 
 // RegisterHandler registers a message handler for the specified runtime and topic kind.
@@ -277,10 +336,44 @@ func (p *P2P) RegisterProtocolServer(srv rpc.Server) {
 // GetMinRepublishInterval returns the minimum republish interval that needs to be respected by
 // the caller when publishing the same message. If Publish is called for the same message more
 // quickly, the message may be dropped and not published.
+//
+// This is the same as GetMinRepublishIntervalForTopic(TopicKindDefault).
 func (p *P2P) GetMinRepublishInterval() time.Duration {
+	return p.GetMinRepublishIntervalForTopic(TopicKindDefault)
+}
+
+// GetMinRepublishIntervalForTopic returns the minimum republish interval that needs to be
+// respected by the caller when publishing the same message on a topic of the given kind.
+func (p *P2P) GetMinRepublishIntervalForTopic(kind TopicKind) time.Duration {
+	if interval, ok := p.minRepublishIntervals[kind]; ok {
+		return interval
+	}
+	return defaultMinRepublishInterval()
+}
+
+// defaultMinRepublishInterval returns the minimum republish interval that applies to all topic
+// kinds without a specific one.
+func defaultMinRepublishInterval() time.Duration {
+	// Messages must not be republished before being evicted from the seen messages cache.
 	return pubsub.TimeCacheDuration + 5*time.Second
 }
 
+// minRepublishIntervalsFromConfig returns the configured topic kind-specific minimum republish
+// intervals.
+//
+// As the gossipsub seen messages cache is shared by all topics, the intervals must never be
+// shorter than the default interval.
+func minRepublishIntervalsFromConfig() (map[TopicKind]time.Duration, error) {
+	intervals := make(map[TopicKind]time.Duration)
+	if interval := viper.GetDuration(CfgP2PTxMinRepublishInterval); interval != 0 {
+		if def := defaultMinRepublishInterval(); interval < def {
+			return nil, fmt.Errorf("worker/common/p2p: %s must be at least %s", CfgP2PTxMinRepublishInterval, def)
+		}
+		intervals[TopicKindTx] = interval
+	}
+	return intervals, nil
+}
+
 func messageIdFn(pmsg *pb.Message) string {
 	// id := TupleHash[messageIdContext](topic, data)
 	h := tuplehash.New256(32, messageIdContext)
@@ -312,6 +405,11 @@ func New(ctx context.Context, identity *identity.Identity, consensus consensus.B
 		fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port),
 	)
 
+	minRepublishIntervals, err := minRepublishIntervalsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	// Set up a connection manager so we can limit the number of connections.
 	low := int(viper.GetUint32(CfgP2PMaxNumPeers))
 	cm, err := connmgr.NewConnManager(
@@ -368,14 +466,15 @@ func New(ctx context.Context, identity *identity.Identity, consensus consensus.B
 	}
 
 	p := &P2P{
-		PeerManager:       newPeerManager(ctx, host, cg, consensus),
-		ctx:               ctx,
-		chainContext:      chainContext,
-		host:              host,
-		pubsub:            pubsub,
-		registerAddresses: registerAddresses,
-		topics:            make(map[common.Namespace]map[TopicKind]*topicHandler),
-		logger:            logging.GetLogger("worker/common/p2p"),
+		PeerManager:           newPeerManager(ctx, host, cg, consensus),
+		ctx:                   ctx,
+		chainContext:          chainContext,
+		host:                  host,
+		pubsub:                pubsub,
+		registerAddresses:     registerAddresses,
+		topics:                make(map[common.Namespace]map[TopicKind]*topicHandler),
+		minRepublishIntervals: minRepublishIntervals,
+		logger:                logging.GetLogger("worker/common/p2p"),
 	}
 
 	p.logger.Info("p2p host initialized",
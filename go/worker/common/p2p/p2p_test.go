@@ -0,0 +1,40 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinRepublishInterval(t *testing.T) {
+	require := require.New(t)
+
+	def := defaultMinRepublishInterval()
+
+	// Without configuration, all topic kinds use the default interval.
+	viper.Set(CfgP2PTxMinRepublishInterval, 0)
+	intervals, err := minRepublishIntervalsFromConfig()
+	require.NoError(err, "minRepublishIntervalsFromConfig")
+	p := &P2P{minRepublishIntervals: intervals}
+	require.Equal(def, p.GetMinRepublishInterval())
+	require.Equal(def, p.GetMinRepublishIntervalForTopic(TopicKindCommittee))
+	require.Equal(def, p.GetMinRepublishIntervalForTopic(TopicKindTx))
+
+	// A configured transaction interval only applies to the transaction topic kind.
+	viper.Set(CfgP2PTxMinRepublishInterval, def+time.Minute)
+	intervals, err = minRepublishIntervalsFromConfig()
+	require.NoError(err, "minRepublishIntervalsFromConfig")
+	p = &P2P{minRepublishIntervals: intervals}
+	require.Equal(def, p.GetMinRepublishInterval())
+	require.Equal(def, p.GetMinRepublishIntervalForTopic(TopicKindCommittee))
+	require.Equal(def+time.Minute, p.GetMinRepublishIntervalForTopic(TopicKindTx))
+
+	// Intervals shorter than the default should be rejected.
+	viper.Set(CfgP2PTxMinRepublishInterval, def-time.Second)
+	_, err = minRepublishIntervalsFromConfig()
+	require.Error(err, "minRepublishIntervalsFromConfig should reject short intervals")
+
+	viper.Set(CfgP2PTxMinRepublishInterval, 0)
+}
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	core "github.com/libp2p/go-libp2p-core"
+)
+
+type breakerState struct {
+	failures  uint
+	openedAt  time.Time
+	probing   bool
+	probeTime time.Time
+}
+
+// circuitBreaker is a feedback recorder that keeps track of consecutive failures for each peer and
+// temporarily skips peers that are consistently failing.
+//
+// After maxFailures consecutive failures the breaker for the given peer is opened and the peer is
+// not acceptable until the cool-down period elapses. After that, a single probe request is allowed.
+// A successful probe closes the breaker while a failed probe opens it again for another cool-down
+// period. Since callers may never provide feedback for a probe, another probe is allowed in case
+// no feedback has been received for a full cool-down period.
+//
+// All feedback is forwarded to the underlying feedback recorder.
+type circuitBreaker struct {
+	sync.Mutex
+
+	next        feedbackRecorder
+	maxFailures uint
+	cooldown    time.Duration
+	now         func() time.Time
+
+	peers map[core.PeerID]*breakerState
+}
+
+// allow checks whether the given peer can be used. When the breaker for the peer is half-open this
+// reserves the single probe request.
+func (cb *circuitBreaker) allow(peerID core.PeerID) bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	st := cb.peers[peerID]
+	if st == nil || st.failures < cb.maxFailures {
+		// Breaker is closed.
+		return true
+	}
+
+	now := cb.now()
+	switch {
+	case now.Before(st.openedAt.Add(cb.cooldown)):
+		// Breaker is open.
+		return false
+	case st.probing && now.Before(st.probeTime.Add(cb.cooldown)):
+		// Breaker is half-open and a probe is already in progress.
+		return false
+	default:
+		// Breaker is half-open, allow a single probe.
+		st.probing = true
+		st.probeTime = now
+		return true
+	}
+}
+
+func (cb *circuitBreaker) RecordSuccess(peerID core.PeerID, latency time.Duration) {
+	cb.Lock()
+	delete(cb.peers, peerID)
+	cb.Unlock()
+
+	cb.next.RecordSuccess(peerID, latency)
+}
+
+func (cb *circuitBreaker) RecordFailure(peerID core.PeerID, latency time.Duration) {
+	cb.Lock()
+	st := cb.peers[peerID]
+	if st == nil {
+		st = &breakerState{}
+		cb.peers[peerID] = st
+	}
+	st.failures++
+	if st.probing || st.failures == cb.maxFailures {
+		// Open (or reopen after a failed probe) the breaker.
+		st.openedAt = cb.now()
+		st.probing = false
+	}
+	cb.Unlock()
+
+	cb.next.RecordFailure(peerID, latency)
+}
+
+func (cb *circuitBreaker) RecordBadPeer(peerID core.PeerID) {
+	cb.next.RecordBadPeer(peerID)
+}
+
+// reset clears the breaker state for all peers.
+func (cb *circuitBreaker) reset() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.peers = make(map[core.PeerID]*breakerState)
+}
+
+// resetPeer clears the breaker state for the given peer.
+func (cb *circuitBreaker) resetPeer(peerID core.PeerID) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	delete(cb.peers, peerID)
+}
+
+func newCircuitBreaker(next feedbackRecorder, maxFailures uint, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		next:        next,
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+		now:         time.Now,
+		peers:       make(map[core.PeerID]*breakerState),
+	}
+}
@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/stretchr/testify/require"
+)
+
+type nopFeedbackRecorder struct{}
+
+func (r *nopFeedbackRecorder) RecordSuccess(peerID core.PeerID, latency time.Duration) {
+}
+
+func (r *nopFeedbackRecorder) RecordFailure(peerID core.PeerID, latency time.Duration) {
+}
+
+func (r *nopFeedbackRecorder) RecordBadPeer(peerID core.PeerID) {
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	peerA, peerB := hosts[0].ID(), hosts[1].ID()
+
+	now := time.Now()
+	cb := newCircuitBreaker(&nopFeedbackRecorder{}, 3, time.Minute)
+	cb.now = func() time.Time { return now }
+
+	// Failures below the threshold should not open the breaker.
+	cb.RecordFailure(peerA, 0)
+	cb.RecordFailure(peerA, 0)
+	require.True(cb.allow(peerA), "breaker should be closed")
+
+	// A success should reset the consecutive failure count.
+	cb.RecordSuccess(peerA, 0)
+	cb.RecordFailure(peerA, 0)
+	cb.RecordFailure(peerA, 0)
+	require.True(cb.allow(peerA), "breaker should be closed")
+
+	// Reaching the threshold should open the breaker.
+	cb.RecordFailure(peerA, 0)
+	require.False(cb.allow(peerA), "breaker should be open")
+	require.True(cb.allow(peerB), "breaker for other peers should be closed")
+
+	// After the cool-down, a single probe should be allowed.
+	now = now.Add(time.Minute)
+	require.True(cb.allow(peerA), "probe should be allowed")
+	require.False(cb.allow(peerA), "only a single probe should be allowed")
+
+	// A failed probe should reopen the breaker.
+	cb.RecordFailure(peerA, 0)
+	require.False(cb.allow(peerA), "breaker should be reopened")
+	now = now.Add(time.Minute - time.Second)
+	require.False(cb.allow(peerA), "breaker should be open until the cool-down elapses")
+
+	// A successful probe should close the breaker.
+	now = now.Add(time.Second)
+	require.True(cb.allow(peerA), "probe should be allowed")
+	cb.RecordSuccess(peerA, 0)
+	require.True(cb.allow(peerA), "breaker should be closed")
+	require.True(cb.allow(peerA), "breaker should be closed")
+
+	// Another probe should be allowed in case no feedback is received for a probe.
+	for i := 0; i < 3; i++ {
+		cb.RecordFailure(peerA, 0)
+	}
+	now = now.Add(time.Minute)
+	require.True(cb.allow(peerA), "probe should be allowed")
+	require.False(cb.allow(peerA), "only a single probe should be allowed")
+	now = now.Add(time.Minute)
+	require.True(cb.allow(peerA), "probe should be allowed after no feedback was received")
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 3)
+	serveTestService(hosts[1])
+	serveTestService(hosts[2])
+
+	rc := newTestClient(hosts[0], hosts[1:], WithCircuitBreaker(2, time.Minute))
+	cb := rc.(*client).breaker
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	// Make both peers fail consistently.
+	var rsp string
+	for i := 0; i < 2; i++ {
+		_, err := rc.Call(context.Background(), "unknown", "hello", &rsp, time.Second)
+		require.Error(err, "Call should fail for unsupported methods")
+	}
+
+	// Both peers should be skipped.
+	require.False(rc.(*client).isPeerAcceptable(hosts[1].ID()), "failing peer should be skipped")
+	require.False(rc.(*client).isPeerAcceptable(hosts[2].ID()), "failing peer should be skipped")
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.Error(err, "Call should fail when all peers are skipped")
+
+	// Once the cool-down elapses, a successful probe should make the peer available again.
+	now = now.Add(time.Minute)
+	pf, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	require.Equal("hello", rsp)
+	pf.RecordSuccess()
+
+	_, err = rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call should succeed after the breaker has been closed")
+}
@@ -3,13 +3,18 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	core "github.com/libp2p/go-libp2p-core"
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
@@ -26,6 +31,12 @@ const (
 	// retries by setting the WithMaxRetries option to a non-zero value. It can be overridden by
 	// using the WithRetryInterval call option.
 	DefaultCallRetryInterval = 1 * time.Second
+	// DefaultMaxResponseSize is the default maximum size of a response that will be accepted from a
+	// peer. It can be overridden by using the WithMaxResponseSize client option.
+	DefaultMaxResponseSize = 16 * 1024 * 1024 // 16 MiB
+	// MinCallAttemptBudget is the minimum time budget of a call attempt when the call's deadline
+	// is split across retries. In case less time is left for an attempt, it becomes the last one.
+	MinCallAttemptBudget = 100 * time.Millisecond
 )
 
 // PeerFeedback is an interface for providing deferred peer feedback after an outcome is known.
@@ -40,26 +51,57 @@ type PeerFeedback interface {
 	//
 	// The peer will be ignored during peer selection.
 	RecordBadPeer()
+
+	// PeerID returns the identifier of the peer that the feedback is for.
+	//
+	// This can be used to correlate responses with the peers that produced them.
+	PeerID() core.PeerID
+}
+
+// VersionedPeerFeedback is peer feedback which also exposes the protocol version that was used
+// to communicate with the peer.
+//
+// The PeerFeedback instances returned by the client implement this interface so that callers can
+// determine how to interpret the response.
+type VersionedPeerFeedback interface {
+	PeerFeedback
+
+	// ProtocolVersion returns the protocol version that was used for the call.
+	ProtocolVersion() version.Version
 }
 
 type peerFeedback struct {
-	mgr     PeerManager
+	mgr     feedbackRecorder
+	metrics clientMetrics
+	method  string
 	peerID  core.PeerID
 	latency time.Duration
+	version version.Version
 }
 
 func (pf *peerFeedback) RecordSuccess() {
+	pf.metrics.observeFeedback(pf.method, feedbackSuccess)
 	pf.mgr.RecordSuccess(pf.peerID, pf.latency)
 }
 
 func (pf *peerFeedback) RecordFailure() {
+	pf.metrics.observeFeedback(pf.method, feedbackFailure)
 	pf.mgr.RecordFailure(pf.peerID, pf.latency)
 }
 
 func (pf *peerFeedback) RecordBadPeer() {
+	pf.metrics.observeFeedback(pf.method, feedbackBadPeer)
 	pf.mgr.RecordBadPeer(pf.peerID)
 }
 
+func (pf *peerFeedback) PeerID() core.PeerID {
+	return pf.peerID
+}
+
+func (pf *peerFeedback) ProtocolVersion() version.Version {
+	return pf.version
+}
+
 type nopPeerFeedback struct{}
 
 func (pf *nopPeerFeedback) RecordSuccess() {
@@ -71,15 +113,80 @@ func (pf *nopPeerFeedback) RecordFailure() {
 func (pf *nopPeerFeedback) RecordBadPeer() {
 }
 
+func (pf *nopPeerFeedback) PeerID() core.PeerID {
+	return ""
+}
+
 // NewNopPeerFeedback creates a no-op peer feedback instance.
 func NewNopPeerFeedback() PeerFeedback {
 	return &nopPeerFeedback{}
 }
 
+type aggregatePeerFeedback struct {
+	pfs []PeerFeedback
+}
+
+func (pf *aggregatePeerFeedback) RecordSuccess() {
+	for _, f := range pf.pfs {
+		f.RecordSuccess()
+	}
+}
+
+func (pf *aggregatePeerFeedback) RecordFailure() {
+	for _, f := range pf.pfs {
+		f.RecordFailure()
+	}
+}
+
+func (pf *aggregatePeerFeedback) RecordBadPeer() {
+	for _, f := range pf.pfs {
+		f.RecordBadPeer()
+	}
+}
+
+func (pf *aggregatePeerFeedback) PeerID() core.PeerID {
+	return ""
+}
+
+// AggregateFeedback creates a peer feedback instance that records the same feedback for each of
+// the given peer feedback instances.
+//
+// This is useful when a single outcome is determined for all results returned by CallMulti. The
+// aggregate feedback has an empty peer ID and the given instances remain usable independently.
+func AggregateFeedback(pfs []PeerFeedback) PeerFeedback {
+	return &aggregatePeerFeedback{
+		pfs: append([]PeerFeedback{}, pfs...),
+	}
+}
+
 // ClientOptions are client options.
 type ClientOptions struct {
-	stickyPeers bool
-	peerFilter  PeerFilter
+	stickyPeers     bool
+	peerFilter      PeerFilter
+	maxResponseSize uint64
+	connectTimeout  time.Duration
+	asyncFeedback   bool
+	metrics         prometheus.Registerer
+	compression     bool
+
+	breakerMaxFailures uint
+	breakerCooldown    time.Duration
+
+	requireConnectedPeer bool
+
+	codecFactory CodecFactory
+
+	minThroughput uint64
+
+	defaultCallOptions []CallOption
+
+	compatibleVersions []version.Version
+
+	streamPoolMaxIdlePerPeer int
+	streamPoolIdleTimeout    time.Duration
+
+	allowDial   bool
+	dialTimeout time.Duration
 }
 
 // ClientOption is a client option setter.
@@ -88,19 +195,217 @@ type ClientOption func(opts *ClientOptions)
 // WithStickyPeers configures the sticky peers feature.
 //
 // When enabled, the last successful peer will be stuck and will be reused on subsequent calls until
-// the peer is deemed bad by the received peer feedback.
+// the peer is deemed bad by the received peer feedback. Peers pinned via PinPeer always take
+// precedence over the stuck peer.
 func WithStickyPeers(enabled bool) ClientOption {
 	return func(opts *ClientOptions) {
 		opts.stickyPeers = enabled
 	}
 }
 
+// WithMaxResponseSize configures the maximum size (in bytes) of a response that will be accepted
+// from a peer.
+//
+// Responses exceeding the limit are rejected without being read in full and the interaction is
+// recorded as a failure. When not set, DefaultMaxResponseSize is used.
+func WithMaxResponseSize(size uint64) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.maxResponseSize = size
+	}
+}
+
+// WithConnectTimeout configures the maximum amount of time that can be spent on opening a stream to
+// a peer and writing the request.
+//
+// The timeout is applied to each peer separately and is independent of the maximum peer response
+// time. When the timeout is exceeded, the interaction is recorded as a failure and the next peer is
+// tried. When not set, only RequestWriteDeadline applies to writing the request.
+func WithConnectTimeout(timeout time.Duration) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.connectTimeout = timeout
+	}
+}
+
+// WithAsyncFeedback configures asynchronous peer feedback recording.
+//
+// When enabled, peer feedback is queued and recorded by a background worker so that recording
+// feedback does not block the caller. Feedback for each peer is recorded in the order it was
+// provided. Any queued feedback is recorded before Close returns.
+func WithAsyncFeedback(enabled bool) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.asyncFeedback = enabled
+	}
+}
+
+// WithMetrics configures the client to collect metrics and register them with the given registerer.
+//
+// Metrics include the number and latency of calls to peers and the number of recorded peer feedback
+// instances, labeled by protocol and method. When not set, no metrics are collected.
+func WithMetrics(registerer prometheus.Registerer) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.metrics = registerer
+	}
+}
+
+// WithCompression configures response compression.
+//
+// When enabled, the client advertises support for compressed responses and peers may compress
+// response payloads. Decompressed payloads are subject to the same maximum response size as
+// uncompressed ones. Peers that do not support compression are detected and sent requests which
+// do not advertise compression support.
+func WithCompression(enabled bool) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.compression = enabled
+	}
+}
+
+// WithCircuitBreaker configures a circuit breaker that temporarily skips consistently failing peers.
+//
+// After maxFailures consecutive failures, a peer is skipped for the given cool-down period after
+// which a single probe request is allowed. A successful probe makes the peer available again while
+// a failed probe causes the peer to be skipped for another cool-down period. Unlike RecordBadPeer,
+// this only affects peer selection temporarily. When maxFailures is zero, the circuit breaker is
+// disabled.
+func WithCircuitBreaker(maxFailures uint, cooldown time.Duration) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.breakerMaxFailures = maxFailures
+		opts.breakerCooldown = cooldown
+	}
+}
+
+// WithRequireConnectedPeer configures the client to fail fast when no peers are connected.
+//
+// When enabled, each call first checks that the host is connected to at least one peer accepted by
+// the peer filter and immediately fails with ErrNoConnectedPeers otherwise.
+func WithRequireConnectedPeer(enabled bool) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.requireConnectedPeer = enabled
+	}
+}
+
+// WithCodec configures the wire codec used for sending requests and receiving responses.
+//
+// The codec is responsible for enforcing any limits on the size of received messages. When not
+// set, the CBOR codec is used and the maximum response size is enforced by the codec.
+func WithCodec(factory CodecFactory) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.codecFactory = factory
+	}
+}
+
+// WithMinThroughput configures the minimum throughput in bytes per second at which peers must send
+// responses.
+//
+// The throughput is measured from the first received byte of each response (or response chunk in
+// case of streamed responses) and peers are given a grace period of MinThroughputGracePeriod. Reads
+// from peers that fall below the minimum throughput are aborted with ErrPeerTooSlow. When zero,
+// only the maximum peer response time is enforced.
+func WithMinThroughput(bytesPerSec uint64) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.minThroughput = bytesPerSec
+	}
+}
+
+// WithCompatibleVersions configures the range of protocol versions that the client is compatible
+// with, in addition to the version it was created with.
+//
+// As protocol identifiers only include the major version, the client will attempt peers that
+// advertise any major version in the (inclusive) range, preferring the highest version that is
+// supported by both sides. The negotiated version is reported via VersionedPeerFeedback.
+//
+// Note that requests and responses are encoded the same way regardless of the negotiated version,
+// so this must only be used when the protocol messages are wire-compatible across the whole range.
+func WithCompatibleVersions(min, max version.Version) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.compatibleVersions = []version.Version{min, max}
+	}
+}
+
+// WithStreamPool configures reuse of streams across requests.
+//
+// When enabled, the client asks peers to keep streams open after a response and keeps up to
+// maxIdlePerPeer idle streams for each peer, closing streams that have been idle for longer than
+// idleTimeout (which should be lower than the peer's KeepAliveIdleTimeout). Streams are only
+// reused after successful non-streamed responses and are discarded on any error. Peers that do not
+// support keep-alive streams are detected (they close the stream without a response) and are sent
+// requests that do not ask for the stream to be kept alive, opening a new stream for each request.
+func WithStreamPool(maxIdlePerPeer int, idleTimeout time.Duration) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.streamPoolMaxIdlePerPeer = maxIdlePerPeer
+		opts.streamPoolIdleTimeout = idleTimeout
+	}
+}
+
+// WithAllowDial configures dialing of peers that the host is not connected to.
+//
+// By default, only peers with an existing connection are used so that calls do not incur the
+// latency of establishing a connection. When enabled, a connection to the peer is established in
+// case there is none, bounded by connectTimeout. Peers that cannot be dialed in time are recorded
+// as failed and the next peer is tried.
+func WithAllowDial(connectTimeout time.Duration) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.allowDial = true
+		opts.dialTimeout = connectTimeout
+	}
+}
+
+// WithDefaultCallOptions configures the default per-call options used for all calls made by the
+// client.
+//
+// The default options are applied first so they can be overridden by the options passed to the
+// individual calls.
+func WithDefaultCallOptions(callOpts ...CallOption) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.defaultCallOptions = append(opts.defaultCallOptions, callOpts...)
+	}
+}
+
 // PeerFilter is a peer filtering interface.
 type PeerFilter interface {
 	// IsPeerAcceptable checks whether the given peer should be used.
 	IsPeerAcceptable(peerID core.PeerID) bool
 }
 
+type andFilter []PeerFilter
+
+// Implements PeerFilter.
+func (f andFilter) IsPeerAcceptable(peerID core.PeerID) bool {
+	for _, filter := range f {
+		if !filter.IsPeerAcceptable(peerID) {
+			return false
+		}
+	}
+	return true
+}
+
+// AndFilter creates a peer filter that accepts a peer iff all of the given filters accept it.
+//
+// Filters are evaluated in order and evaluation stops at the first filter that rejects the peer.
+// When no filters are given, all peers are accepted.
+func AndFilter(filters ...PeerFilter) PeerFilter {
+	return andFilter(filters)
+}
+
+type orFilter []PeerFilter
+
+// Implements PeerFilter.
+func (f orFilter) IsPeerAcceptable(peerID core.PeerID) bool {
+	for _, filter := range f {
+		if filter.IsPeerAcceptable(peerID) {
+			return true
+		}
+	}
+	return false
+}
+
+// OrFilter creates a peer filter that accepts a peer iff any of the given filters accepts it.
+//
+// Filters are evaluated in order and evaluation stops at the first filter that accepts the peer.
+// When no filters are given, all peers are rejected.
+func OrFilter(filters ...PeerFilter) PeerFilter {
+	return orFilter(filters)
+}
+
 // WithPeerFilter configures peer filtering.
 //
 // When set, only peers accepted by the filter will be used for calls.
@@ -120,6 +425,9 @@ type CallOptions struct {
 type CallOption func(opts *CallOptions)
 
 // WithMaxRetries configures the maximum number of retries to use for the call.
+//
+// In case the call context has a deadline, the remaining time is split across the remaining
+// attempts so that a slow peer cannot exhaust the whole budget on the first attempt.
 func WithMaxRetries(maxRetries uint64) CallOption {
 	return func(opts *CallOptions) {
 		opts.maxRetries = maxRetries
@@ -151,17 +459,61 @@ type Client interface {
 		opts ...CallOption,
 	) (PeerFeedback, error)
 
+	// CallRaw is like Call but returns the raw CBOR-encoded response instead of decoding it.
+	//
+	// This is useful for callers that only pass the response on. Error responses are still
+	// returned as errors.
+	CallRaw(
+		ctx context.Context,
+		method string,
+		body interface{},
+		maxPeerResponseTime time.Duration,
+		opts ...CallOption,
+	) ([]byte, PeerFeedback, error)
+
 	// CallMulti routes the given RPC method call to multiple peers that support the protocol based
 	// on past experience with the peers.
 	//
+	// At most maxParallelRequests requests are performed concurrently. In case maxPeers is
+	// non-zero, only up to maxPeers of the best acceptable peers are called.
+	//
 	// It returns all successfully retrieved results and their corresponding PeerFeedback instances.
+	// In case the context is cancelled, all results that are already available are returned
+	// together with the context error.
 	CallMulti(
 		ctx context.Context,
 		method string,
 		body, rspTyp interface{},
 		maxPeerResponseTime time.Duration,
 		maxParallelRequests uint,
+		maxPeers uint,
 	) ([]interface{}, []PeerFeedback, error)
+
+	// CallStream attempts to route the given RPC method call to one of the peers that supports the
+	// protocol and returns a reader of the response body streamed by the peer.
+	//
+	// The peer must respond with a StreamResponse in which case the returned reader yields the
+	// data streamed by the peer. In case the peer responds with a regular response, the reader
+	// yields the CBOR-encoded response instead.
+	//
+	// Any failure while reading the stream is recorded as a failure for the given peer. The caller
+	// must close the returned reader after use.
+	CallStream(
+		ctx context.Context,
+		method string,
+		body interface{},
+		maxPeerResponseTime time.Duration,
+	) (io.ReadCloser, PeerFeedback, error)
+
+	// ProtocolID returns the protocol identifier of the version the client was created with.
+	ProtocolID() protocol.ID
+
+	// SupportsPeer returns true iff the given peer advertises support for the client's protocol
+	// (or any of the compatible protocol versions) according to the host's peerstore.
+	SupportsPeer(peerID core.PeerID) bool
+
+	// Close stops the client and waits for any pending peer feedback to be recorded.
+	Close()
 }
 
 type client struct {
@@ -169,19 +521,86 @@ type client struct {
 
 	host       core.Host
 	protocolID protocol.ID
+	version    version.Version
 	runtimeID  common.Namespace
 
-	opts *ClientOptions
+	// protocolIDs are the protocols the client attempts when opening streams, in order of
+	// preference. The versions map them to the corresponding protocol versions.
+	protocolIDs []protocol.ID
+	versions    map[protocol.ID]version.Version
+
+	opts          *ClientOptions
+	feedback      feedbackRecorder
+	asyncFeedback *asyncFeedbackRecorder
+	breaker       *circuitBreaker
+	metrics       clientMetrics
+	compression   *featureTracker
+	keepAlive     *featureTracker
+	pool          *streamPool
 
 	logger *logging.Logger
 }
 
+// Implements Client.
+func (c *client) ProtocolID() protocol.ID {
+	return c.protocolID
+}
+
+// Implements Client.
+func (c *client) SupportsPeer(peerID core.PeerID) bool {
+	pids := make([]string, 0, len(c.protocolIDs))
+	for _, pid := range c.protocolIDs {
+		pids = append(pids, string(pid))
+	}
+
+	protocols, err := c.host.Peerstore().SupportsProtocols(peerID, pids...)
+	if err != nil {
+		c.logger.Error("failed to get peer's protocols",
+			"err", err,
+			"peer_id", peerID,
+		)
+		return false
+	}
+	return len(protocols) > 0
+}
+
 func (c *client) isPeerAcceptable(peerID core.PeerID) bool {
-	if c.opts.peerFilter == nil {
-		return true
+	if c.opts.peerFilter != nil && !c.opts.peerFilter.IsPeerAcceptable(peerID) {
+		return false
+	}
+	if c.breaker != nil && !c.breaker.allow(peerID) {
+		return false
+	}
+	return true
+}
+
+// ensureConnectedPeer makes sure that the host is connected to at least one acceptable peer in case
+// this is required by the client options.
+func (c *client) ensureConnectedPeer() error {
+	if !c.opts.requireConnectedPeer {
+		return nil
+	}
+
+	for _, peer := range c.GetBestPeers() {
+		if c.opts.peerFilter != nil && !c.opts.peerFilter.IsPeerAcceptable(peer) {
+			continue
+		}
+		if c.host.Network().Connectedness(peer) == network.Connected {
+			return nil
+		}
 	}
+	return ErrNoConnectedPeers
+}
 
-	return c.opts.peerFilter.IsPeerAcceptable(peerID)
+// newRequest prepares a new request for the given method.
+func (c *client) newRequest(method string, body interface{}) Request {
+	start := time.Now()
+	request := Request{
+		Method: method,
+		Body:   cbor.Marshal(body),
+	}
+	c.metrics.observeCodecTime(method, codecOpEncode, time.Since(start))
+	return request
 }
 
 func (c *client) Call(
@@ -193,22 +612,48 @@ func (c *client) Call(
 ) (PeerFeedback, error) {
 	c.logger.Debug("call", "method", method)
 
+	if err := c.ensureConnectedPeer(); err != nil {
+		return nil, err
+	}
+
 	co := CallOptions{
 		retryInterval: DefaultCallRetryInterval,
 	}
+	for _, opt := range c.opts.defaultCallOptions {
+		opt(&co)
+	}
 	for _, opt := range opts {
 		opt(&co)
 	}
 
 	// Prepare the request.
-	request := Request{
-		Method: method,
-		Body:   cbor.Marshal(body),
-	}
+	request := c.newRequest(method, body)
 
-	var pf PeerFeedback
+	var (
+		pf       PeerFeedback
+		attempts uint64
+	)
 	tryPeers := func() error {
+		// Bound the attempt to its share of the remaining time budget.
+		var lastAttempt bool
+		attemptCtx := ctx
+		if co.maxRetries > 0 {
+			budget, ok := attemptBudget(ctx, co.maxRetries+1-attempts, co.retryInterval)
+			if ok {
+				if budget < MinCallAttemptBudget {
+					// The deadline is near, use the remaining time for a final attempt.
+					lastAttempt = true
+				} else {
+					var cancel context.CancelFunc
+					attemptCtx, cancel = context.WithTimeout(ctx, budget)
+					defer cancel()
+				}
+			}
+		}
+		attempts++
+
 		// Iterate through the prioritized list of peers and attempt to execute the request.
+		var failures callFailures
 		for _, peer := range c.GetBestPeers() {
 			if !c.isPeerAcceptable(peer) {
 				continue
@@ -220,8 +665,9 @@ func (c *client) Call(
 			)
 
 			var err error
-			pf, err = c.call(ctx, peer, &request, rsp, maxPeerResponseTime)
+			pf, err = c.call(attemptCtx, peer, &request, rsp, maxPeerResponseTime)
 			if err != nil {
+				failures.record(err)
 				continue
 			}
 			return nil
@@ -232,7 +678,11 @@ func (c *client) Call(
 			"method", method,
 		)
 
-		return fmt.Errorf("call failed on all peers")
+		err := failures.err()
+		if lastAttempt {
+			return backoff.Permanent(err)
+		}
+		return err
 	}
 
 	var err error
@@ -246,21 +696,53 @@ func (c *client) Call(
 	return pf, err
 }
 
+// attemptBudget returns the time budget of the next call attempt in case the context has a
+// deadline. The time remaining until the deadline, minus the retry intervals, is split evenly
+// across the given number of remaining attempts (including the next one).
+func attemptBudget(ctx context.Context, remainingAttempts uint64, retryInterval time.Duration) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok || remainingAttempts == 0 {
+		return 0, false
+	}
+	remaining := time.Until(deadline) - time.Duration(remainingAttempts-1)*retryInterval
+	if remaining <= 0 {
+		return 0, true
+	}
+	return remaining / time.Duration(remainingAttempts), true
+}
+
+func (c *client) CallRaw(
+	ctx context.Context,
+	method string,
+	body interface{},
+	maxPeerResponseTime time.Duration,
+	opts ...CallOption,
+) ([]byte, PeerFeedback, error) {
+	var rsp cbor.RawMessage
+	pf, err := c.Call(ctx, method, body, &rsp, maxPeerResponseTime, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rsp, pf, nil
+}
+
 func (c *client) CallMulti(
 	ctx context.Context,
 	method string,
 	body, rspTyp interface{},
 	maxPeerResponseTime time.Duration,
 	maxParallelRequests uint,
+	maxPeers uint,
 ) ([]interface{}, []PeerFeedback, error) {
 	c.logger.Debug("call multiple", "method", method)
 
-	// Prepare the request.
-	request := Request{
-		Method: method,
-		Body:   cbor.Marshal(body),
+	if err := c.ensureConnectedPeer(); err != nil {
+		return nil, nil, err
 	}
 
+	// Prepare the request.
+	request := c.newRequest(method, body)
+
 	// Create a worker pool.
 	pool := workerpool.New("p2p/rpc")
 	pool.Resize(maxParallelRequests)
@@ -274,10 +756,14 @@ func (c *client) CallMulti(
 	}
 	var resultCh []chan *result
 	for _, peer := range c.GetBestPeers() {
+		if maxPeers > 0 && uint(len(resultCh)) >= maxPeers {
+			break
+		}
 		if !c.isPeerAcceptable(peer) {
 			continue
 		}
 
+		peer := peer // Make sure each request is routed to its own peer.
 		ch := make(chan *result, 1)
 		resultCh = append(resultCh, ch)
 
@@ -294,19 +780,37 @@ func (c *client) CallMulti(
 		rsps []interface{}
 		pfs  []PeerFeedback
 	)
-	for _, ch := range resultCh {
+	gather := func(result *result) {
+		// Ignore failed results.
+		if result.err != nil {
+			return
+		}
+
+		rsps = append(rsps, result.rsp)
+		pfs = append(pfs, result.pf)
+	}
+	for next := 0; next < len(resultCh); {
 		select {
 		case <-ctx.Done():
-			return nil, nil, ctx.Err()
-		case result := <-ch:
-			// Ignore failed results.
-			if result.err != nil {
-				continue
-			}
+		case result := <-resultCh[next]:
+			gather(result)
+			next++
+		}
+		if ctx.Err() == nil {
+			continue
+		}
 
-			rsps = append(rsps, result.rsp)
-			pfs = append(pfs, result.pf)
+		// Return any results that are already available, including the ones from peers after
+		// a still pending request. Pending requests will be aborted as they use the same context
+		// and the result channels are buffered so no workers are blocked.
+		for _, ch := range resultCh[next:] {
+			select {
+			case result := <-ch:
+				gather(result)
+			default:
+			}
 		}
+		return rsps, pfs, ctx.Err()
 	}
 	return rsps, pfs, nil
 }
@@ -326,7 +830,9 @@ func (c *client) call(
 
 	startTime := time.Now()
 
-	err := c.sendRequestAndDecodeResponse(ctx, peerID, request, rsp, maxPeerResponseTime)
+	protocolVersion, err := c.sendRequestAndDecodeResponse(ctx, peerID, request, rsp, maxPeerResponseTime)
+	latency := time.Since(startTime)
+	c.metrics.observeCall(request.Method, latency)
 	if err != nil {
 		c.logger.Debug("failed to call method",
 			"err", err,
@@ -334,14 +840,18 @@ func (c *client) call(
 			"peer_id", peerID,
 		)
 
-		c.RecordFailure(peerID, time.Since(startTime))
+		c.metrics.observeFeedback(request.Method, feedbackFailure)
+		c.feedback.RecordFailure(peerID, latency)
 		return nil, err
 	}
 
 	pf := &peerFeedback{
-		mgr:     c.PeerManager,
+		mgr:     c.feedback,
+		metrics: c.metrics,
+		method:  request.Method,
 		peerID:  peerID,
-		latency: time.Since(startTime),
+		latency: latency,
+		version: protocolVersion,
 	}
 	return pf, nil
 }
@@ -352,53 +862,315 @@ func (c *client) sendRequestAndDecodeResponse(
 	request *Request,
 	rsp interface{},
 	maxPeerResponseTime time.Duration,
-) error {
-	// Attempt to open stream to the given peer.
-	stream, err := c.host.NewStream(
-		network.WithNoDial(ctx, "should already have connection"),
-		peerID,
-		c.protocolID,
-	)
+) (version.Version, error) {
+	stream, codec, rawRsp, err := c.sendRequest(ctx, peerID, request, maxPeerResponseTime)
+	if err != nil {
+		return version.Version{}, err
+	}
+
+	if rawRsp.Stream {
+		_ = stream.Reset()
+		return version.Version{}, fmt.Errorf("unexpected streamed response")
+	}
+
+	if rsp != nil {
+		start := time.Now()
+		err = cbor.Unmarshal(rawRsp.Ok, rsp)
+		c.metrics.observeCodecTime(request.Method, codecOpDecode, time.Since(start))
+		if err != nil {
+			_ = stream.Close()
+			return version.Version{}, newMalformedResponseError(request.Method, peerID, rawRsp.Ok, err)
+		}
+	}
+	protocolVersion := c.streamVersion(stream)
+	c.releaseStream(peerID, stream, codec, rawRsp)
+	return protocolVersion, nil
+}
+
+// releaseStream returns the stream to the pool in case the peer agreed to keep it alive and
+// closes it otherwise. It must only be called after a response has been successfully handled.
+func (c *client) releaseStream(peerID core.PeerID, stream network.Stream, codec Codec, rsp *Response) {
+	if c.pool == nil || !rsp.KeepAlive || rsp.Stream {
+		_ = stream.Close()
+		return
+	}
+	c.pool.put(peerID, stream, codec)
+}
+
+// streamVersion returns the protocol version negotiated for the given stream.
+func (c *client) streamVersion(stream network.Stream) version.Version {
+	if v, ok := c.versions[stream.Protocol()]; ok {
+		return v
+	}
+	return c.version
+}
+
+func (c *client) sendRequest(
+	ctx context.Context,
+	peerID core.PeerID,
+	request *Request,
+	maxPeerResponseTime time.Duration,
+) (network.Stream, Codec, *Response, error) {
+	rq := request
+	compression := c.opts.compression && c.compression.isSupported(peerID)
+	keepAlive := c.pool != nil && c.keepAlive.isSupported(peerID)
+	if compression || keepAlive {
+		rq = &Request{
+			Method:      request.Method,
+			Body:        request.Body,
+			Compression: compression,
+			KeepAlive:   keepAlive,
+		}
+	}
+
+	stream, codec, pooled, err := c.openStreamAndSendRequest(ctx, peerID, rq)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	rawRsp, err := c.readResponse(ctx, stream, codec, peerID, maxPeerResponseTime)
+	if err != nil {
+		_ = stream.Close()
+
+		if pooled && isClosedWithoutResponse(err) {
+			// The peer may have closed the idle stream in the meantime, retry on another stream.
+			c.logger.Debug("peer closed pooled stream without response, retrying",
+				"peer_id", peerID,
+			)
+			return c.sendRequest(ctx, peerID, request, maxPeerResponseTime)
+		}
+		if keepAlive && isClosedWithoutResponse(err) {
+			// The peer may not support keep-alive streams, retry without requesting it.
+			c.logger.Debug("peer closed stream without response, disabling keep-alive",
+				"peer_id", peerID,
+			)
+			c.keepAlive.markUnsupported(peerID)
+			return c.sendRequest(ctx, peerID, request, maxPeerResponseTime)
+		}
+		if compression && isClosedWithoutResponse(err) {
+			// The peer may not support compression, retry without advertising it.
+			c.logger.Debug("peer closed stream without response, disabling compression",
+				"peer_id", peerID,
+			)
+			c.compression.markUnsupported(peerID)
+			return c.sendRequest(ctx, peerID, request, maxPeerResponseTime)
+		}
+		return nil, nil, nil, err
+	}
+	return stream, codec, rawRsp, nil
+}
+
+func (c *client) openStreamAndSendRequest(
+	ctx context.Context,
+	peerID core.PeerID,
+	request *Request,
+) (network.Stream, Codec, bool, error) {
+	// Bound the time spent on opening the stream and writing the request.
+	connectCtx := ctx
+	if c.opts.connectTimeout > 0 {
+		var cancel context.CancelFunc
+		connectCtx, cancel = context.WithTimeout(ctx, c.opts.connectTimeout)
+		defer cancel()
+	}
+
+	// Prefer reusing an idle stream. Streams that fail are discarded and a new one is opened.
+	if c.pool != nil {
+		for ps := c.pool.get(peerID); ps != nil; ps = c.pool.get(peerID) {
+			if err := c.writeRequest(connectCtx, ps.stream, ps.codec, peerID, request); err == nil {
+				return ps.stream, ps.codec, true, nil
+			}
+		}
+	}
+
+	// Attempt to open stream to the given peer, negotiating the most preferred protocol.
+	streamCtx := network.WithNoDial(connectCtx, "should already have connection")
+	if c.opts.allowDial {
+		streamCtx = connectCtx
+		if c.opts.dialTimeout > 0 {
+			var cancel context.CancelFunc
+			streamCtx, cancel = context.WithTimeout(connectCtx, c.opts.dialTimeout)
+			defer cancel()
+		}
+	}
+	stream, err := c.host.NewStream(streamCtx, peerID, c.protocolIDs...)
 	if err != nil {
-		return fmt.Errorf("failed to open stream: %w", err)
+		return nil, nil, false, fmt.Errorf("failed to open stream: %w", err)
 	}
-	defer stream.Close()
 
-	codec := cbor.NewMessageCodec(stream, codecModuleName)
+	if c.opts.minThroughput > 0 {
+		stream = newThroughputStream(stream, c.opts.minThroughput)
+	}
+	codec := c.opts.codecFactory(stream)
 
-	// Send request.
-	_ = stream.SetWriteDeadline(time.Now().Add(RequestWriteDeadline))
-	if err = codec.Write(request); err != nil {
+	if err = c.writeRequest(connectCtx, stream, codec, peerID, request); err != nil {
+		return nil, nil, false, err
+	}
+	return stream, codec, false, nil
+}
+
+// writeRequest sends the request over the given stream and resets the stream on failure.
+func (c *client) writeRequest(
+	ctx context.Context,
+	stream network.Stream,
+	codec Codec,
+	peerID core.PeerID,
+	request *Request,
+) error {
+	writeDeadline := time.Now().Add(RequestWriteDeadline)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(writeDeadline) {
+		writeDeadline = deadline
+	}
+	_ = stream.SetWriteDeadline(writeDeadline)
+	if err := codec.Write(request); err != nil {
 		c.logger.Debug("failed to send request",
 			"err", err,
 			"peer_id", peerID,
 		)
+		_ = stream.Reset()
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 	_ = stream.SetWriteDeadline(time.Time{})
 
+	if request.KeepAlive {
+		// The stream may be reused for further requests.
+		return nil
+	}
+
+	// Half-close the stream as no more data will be sent so that the peer observes EOF on its read
+	// side. The response is still read in full before the stream is closed.
+	if err := stream.CloseWrite(); err != nil {
+		c.logger.Debug("failed to close stream for writing",
+			"err", err,
+			"peer_id", peerID,
+		)
+		_ = stream.Reset()
+		return fmt.Errorf("failed to close stream for writing: %w", err)
+	}
+	return nil
+}
+
+func (c *client) readResponse(
+	ctx context.Context,
+	stream network.Stream,
+	codec Codec,
+	peerID core.PeerID,
+	maxPeerResponseTime time.Duration,
+) (*Response, error) {
+	readDeadline := time.Now().Add(maxPeerResponseTime)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(readDeadline) {
+		readDeadline = deadline
+	}
+
+	// Abort the read in case the context is done as not all transports support deadlines.
+	const (
+		readPending uint32 = iota
+		readDone
+		readAborted
+	)
+	var readState uint32
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if atomic.CompareAndSwapUint32(&readState, readPending, readAborted) {
+				_ = stream.Reset()
+			}
+		case <-doneCh:
+		}
+	}()
+
 	// Read response.
-	// TODO: Add required minimum speed.
 	var rawRsp Response
-	_ = stream.SetReadDeadline(time.Now().Add(maxPeerResponseTime))
-	if err = codec.Read(&rawRsp); err != nil {
+	_ = stream.SetReadDeadline(readDeadline)
+	err := codec.Read(&rawRsp)
+	if !atomic.CompareAndSwapUint32(&readState, readPending, readDone) {
+		err = ctx.Err()
+	}
+	if err != nil {
 		c.logger.Debug("failed to read response",
 			"err", err,
 			"peer_id", peerID,
 		)
-		return fmt.Errorf("failed to read response: %w", err)
+		// Abort the stream so that the peer stops sending any remaining data.
+		_ = stream.Reset()
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
-	_ = stream.SetWriteDeadline(time.Time{})
+	_ = stream.SetReadDeadline(time.Time{})
 
 	// Decode response.
 	if rawRsp.Error != nil {
-		return errors.FromCode(rawRsp.Error.Module, rawRsp.Error.Code, rawRsp.Error.Message)
+		return nil, errors.FromCode(rawRsp.Error.Module, rawRsp.Error.Code, rawRsp.Error.Message)
+	}
+	if rawRsp.OkCompressed != nil {
+		ok, err := decompressPayload(rawRsp.OkCompressed, c.opts.maxResponseSize)
+		if err != nil {
+			_ = stream.Reset()
+			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		rawRsp.Ok = ok
+		rawRsp.OkCompressed = nil
 	}
+	return &rawRsp, nil
+}
 
-	if rsp != nil {
-		return cbor.Unmarshal(rawRsp.Ok, rsp)
+func (c *client) ResetPeerReputation() {
+	if c.breaker != nil {
+		c.breaker.reset()
 	}
-	return nil
+	c.PeerManager.ResetPeerReputation()
+}
+
+func (c *client) ResetPeer(peerID core.PeerID) {
+	if c.breaker != nil {
+		c.breaker.resetPeer(peerID)
+	}
+	c.PeerManager.ResetPeer(peerID)
+}
+
+func (c *client) Close() {
+	if c.asyncFeedback != nil {
+		c.asyncFeedback.Close()
+	}
+	if c.pool != nil {
+		c.pool.close()
+	}
+}
+
+// compatibleProtocolIDs returns the protocol identifiers, in order of preference, for the given
+// protocol version and the optional compatible version range together with their versions.
+func compatibleProtocolIDs(
+	runtimeID common.Namespace,
+	protocolID string,
+	ver version.Version,
+	compatibleVersions []version.Version,
+) ([]protocol.ID, map[protocol.ID]version.Version) {
+	majors := map[uint16]version.Version{
+		ver.Major: ver,
+	}
+	if len(compatibleVersions) == 2 {
+		for major := compatibleVersions[0].Major; major <= compatibleVersions[1].Major; major++ {
+			if _, exists := majors[major]; !exists {
+				majors[major] = version.Version{Major: major}
+			}
+			if major == math.MaxUint16 {
+				break
+			}
+		}
+	}
+
+	protocolIDs := make([]protocol.ID, 0, len(majors))
+	versions := make(map[protocol.ID]version.Version, len(majors))
+	for _, v := range majors {
+		pid := NewRuntimeProtocolID(runtimeID, protocolID, v)
+		protocolIDs = append(protocolIDs, pid)
+		versions[pid] = v
+	}
+	sort.Slice(protocolIDs, func(i, j int) bool {
+		return versions[protocolIDs[i]].Major > versions[protocolIDs[j]].Major
+	})
+
+	return protocolIDs, versions
 }
 
 // NewClient creates a new RPC client for the given protocol.
@@ -409,16 +1181,69 @@ func NewClient(p2p P2P, runtimeID common.Namespace, protocolID string, version v
 	for _, opt := range opts {
 		opt(&co)
 	}
+	if co.maxResponseSize == 0 {
+		co.maxResponseSize = DefaultMaxResponseSize
+	}
+	if co.codecFactory == nil {
+		maxResponseSize := co.maxResponseSize
+		co.codecFactory = func(rw io.ReadWriter) Codec {
+			codec := cbor.NewMessageCodec(rw, codecModuleName)
+			codec.SetMaxMessageSize(maxResponseSize)
+			return codec
+		}
+	}
+
+	logger := logging.GetLogger("worker/common/p2p/rpc/client").With(
+		"protocol", protocolID,
+		"runtime_id", runtimeID,
+	)
+
+	protocolIDs, versions := compatibleProtocolIDs(runtimeID, protocolID, version, co.compatibleVersions)
+	mgr := newPeerManager(p2p, protocolIDs, co.stickyPeers)
+	var (
+		feedback      feedbackRecorder = mgr
+		asyncFeedback *asyncFeedbackRecorder
+		breaker       *circuitBreaker
+	)
+	if co.asyncFeedback {
+		asyncFeedback = newAsyncFeedbackRecorder(mgr)
+		feedback = asyncFeedback
+	}
+	if co.breakerMaxFailures > 0 {
+		// The circuit breaker needs to observe feedback synchronously.
+		breaker = newCircuitBreaker(feedback, co.breakerMaxFailures, co.breakerCooldown)
+		feedback = breaker
+	}
+
+	var pool *streamPool
+	if co.streamPoolMaxIdlePerPeer > 0 {
+		pool = newStreamPool(co.streamPoolMaxIdlePerPeer, co.streamPoolIdleTimeout)
+	}
+
+	metrics, err := newClientMetrics(co.metrics, pid)
+	if err != nil {
+		logger.Error("failed to register metrics, metrics will not be collected",
+			"err", err,
+		)
+		metrics = &nopClientMetrics{}
+	}
 
 	return &client{
-		PeerManager: NewPeerManager(p2p, pid, co.stickyPeers),
-		host:        p2p.GetHost(),
-		protocolID:  pid,
-		runtimeID:   runtimeID,
-		opts:        &co,
-		logger: logging.GetLogger("worker/common/p2p/rpc/client").With(
-			"protocol", protocolID,
-			"runtime_id", runtimeID,
-		),
+		PeerManager:   mgr,
+		host:          p2p.GetHost(),
+		protocolID:    pid,
+		version:       version,
+		runtimeID:     runtimeID,
+		protocolIDs:   protocolIDs,
+		versions:      versions,
+		opts:          &co,
+		feedback:      feedback,
+		asyncFeedback: asyncFeedback,
+		breaker:       breaker,
+		metrics:       metrics,
+		compression:   newFeatureTracker(),
+		keepAlive:     newFeatureTracker(),
+		pool:          pool,
+		logger:        logger,
 	}
 }
@@ -0,0 +1,1316 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/network"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/errors"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+)
+
+const testProtocolName = "test"
+
+var (
+	testRuntimeID = common.NewTestNamespaceFromSeed([]byte("p2p rpc client test"), 0)
+	testVersion   = version.Version{Major: 1}
+
+	// errTestRequest is a test error that gets registered as a request error.
+	errTestRequest = errors.New("p2p/rpc/test", 1, "test: invalid request")
+	// errTestOther is a test error that is never registered as a request error.
+	errTestOther = errors.New("p2p/rpc/test", 2, "test: other error")
+)
+
+type testP2P struct {
+	host core.Host
+}
+
+func (p *testP2P) BlockPeer(peerID core.PeerID) {
+}
+
+func (p *testP2P) GetHost() core.Host {
+	return p.host
+}
+
+type testService struct{}
+
+func (s *testService) HandleRequest(ctx context.Context, method string, body cbor.RawMessage) (interface{}, error) {
+	switch method {
+	case "echo":
+		var msg string
+		if err := cbor.Unmarshal(body, &msg); err != nil {
+			return nil, ErrBadRequest
+		}
+		return msg, nil
+	case "stream":
+		var size int
+		if err := cbor.Unmarshal(body, &size); err != nil {
+			return nil, ErrBadRequest
+		}
+		return NewStreamResponse(bytes.NewReader(testStreamData(size))), nil
+	case "stream_fail":
+		var size int
+		if err := cbor.Unmarshal(body, &size); err != nil {
+			return nil, ErrBadRequest
+		}
+		return NewStreamResponse(io.MultiReader(
+			bytes.NewReader(testStreamData(size)),
+			iotest.ErrReader(ErrBadRequest),
+		)), nil
+	default:
+		return nil, ErrMethodNotSupported
+	}
+}
+
+func testStreamData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+// newTestNetwork creates a connected mock network with the given number of hosts.
+func newTestNetwork(t *testing.T, n int) []core.Host {
+	require := require.New(t)
+
+	mn := mocknet.New()
+	t.Cleanup(func() {
+		_ = mn.Close()
+	})
+
+	hosts := make([]core.Host, 0, n)
+	for i := 0; i < n; i++ {
+		h, err := mn.GenPeer()
+		require.NoError(err, "GenPeer")
+		hosts = append(hosts, h)
+	}
+	require.NoError(mn.LinkAll(), "LinkAll")
+	require.NoError(mn.ConnectAllButSelf(), "ConnectAllButSelf")
+
+	return hosts
+}
+
+// newTestClient creates a new client on the given host and registers the given peers.
+func newTestClient(host core.Host, peers []core.Host, opts ...ClientOption) Client {
+	client := NewClient(&testP2P{host}, testRuntimeID, testProtocolName, testVersion, opts...)
+	for _, peer := range peers {
+		client.AddPeer(peer.ID())
+	}
+	return client
+}
+
+// serveTestService registers the test service on the given host.
+func serveTestService(host core.Host) {
+	srv := NewServer(testRuntimeID, testProtocolName, testVersion, &testService{})
+	host.SetStreamHandler(srv.Protocol(), srv.HandleStream)
+}
+
+func TestClientCall(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+	client := newTestClient(hosts[0], hosts[1:])
+
+	var rsp string
+	pf, err := client.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	require.NotNil(pf, "Call should return peer feedback")
+	require.Equal("hello", rsp)
+
+	_, err = client.Call(context.Background(), "unknown", "hello", &rsp, time.Second)
+	require.Error(err, "Call should fail for unsupported methods")
+}
+
+func TestClientProtocolVersion(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+	rc := newTestClient(hosts[0], hosts[1:])
+
+	requireVersion := func(pf PeerFeedback) {
+		vpf, ok := pf.(VersionedPeerFeedback)
+		require.True(ok, "peer feedback should expose the protocol version")
+		require.Equal(testVersion, vpf.ProtocolVersion(), "protocol version should match the peer's")
+	}
+
+	var rsp string
+	pf, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	requireVersion(pf)
+
+	_, pfs, err := rc.CallMulti(context.Background(), "echo", "hello", "", time.Second, 1, 0)
+	require.NoError(err, "CallMulti")
+	require.Len(pfs, 1)
+	requireVersion(pfs[0])
+
+	rd, pf, err := rc.CallStream(context.Background(), "echo", "hello", time.Second)
+	require.NoError(err, "CallStream")
+	require.NoError(rd.Close(), "Close")
+	requireVersion(pf)
+}
+
+func TestClientMaxResponseSize(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+
+	// Configure a peer that declares a huge response and then keeps streaming data.
+	hosts[1].SetStreamHandler(pid, func(stream network.Stream) {
+		defer stream.Close()
+
+		var request Request
+		codec := cbor.NewMessageCodec(stream, codecModuleName)
+		if err := codec.Read(&request); err != nil {
+			return
+		}
+
+		var rawLength [4]byte
+		binary.BigEndian.PutUint32(rawLength[:], 32*1024*1024)
+		if _, err := stream.Write(rawLength[:]); err != nil {
+			return
+		}
+		chunk := make([]byte, 64*1024)
+		for i := 0; i < 512; i++ {
+			if _, err := stream.Write(chunk); err != nil {
+				return
+			}
+		}
+	})
+
+	client := newTestClient(hosts[0], hosts[1:], WithMaxResponseSize(1024))
+
+	var rsp string
+	_, err := client.Call(context.Background(), "echo", "hello", &rsp, 5*time.Second)
+	require.Error(err, "Call should fail for oversized responses")
+
+	// The peer should not be treated as bad.
+	require.Contains(client.GetBestPeers(), hosts[1].ID(), "peer should still be available")
+}
+
+func TestClientAsyncFeedback(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 4)
+	syncClient := newTestClient(hosts[0], hosts[1:])
+	asyncClient := newTestClient(hosts[0], hosts[1:], WithAsyncFeedback(true))
+
+	// Record the same feedback via both clients.
+	for _, c := range []Client{syncClient, asyncClient} {
+		feedback, metrics := c.(*client).feedback, c.(*client).metrics
+		for i := 0; i < 100; i++ {
+			for j, peer := range hosts[1:] {
+				latency := time.Duration(i*(j+1)) * time.Millisecond
+				pf := &peerFeedback{mgr: feedback, metrics: metrics, peerID: peer.ID(), latency: latency}
+				switch (i + j) % 3 {
+				case 0:
+					pf.RecordFailure()
+				default:
+					pf.RecordSuccess()
+				}
+			}
+		}
+		pf := &peerFeedback{mgr: feedback, metrics: metrics, peerID: hosts[3].ID()}
+		pf.RecordBadPeer()
+	}
+
+	// Closing the client should record all pending feedback.
+	syncClient.Close()
+	asyncClient.Close()
+
+	syncMgr := syncClient.(*client).PeerManager.(*peerManager)
+	asyncMgr := asyncClient.(*client).PeerManager.(*peerManager)
+	require.EqualValues(syncMgr.peers, asyncMgr.peers, "peer stats should match")
+	require.EqualValues(syncMgr.ignoredPeers, asyncMgr.ignoredPeers, "ignored peers should match")
+	require.EqualValues(syncMgr.avgRequestLatency, asyncMgr.avgRequestLatency, "average latency should match")
+
+	// Feedback recorded after close should still be recorded.
+	pf := &peerFeedback{mgr: asyncClient.(*client).feedback, metrics: &nopClientMetrics{}, peerID: hosts[1].ID()}
+	pf.RecordSuccess()
+	require.Equal(syncMgr.peers[hosts[1].ID()].successes+1, asyncMgr.peers[hosts[1].ID()].successes)
+}
+
+func TestClientMetrics(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+
+	registry := prometheus.NewRegistry()
+	rc := newTestClient(hosts[0], hosts[1:], WithMetrics(registry))
+	// Creating another client with the same registerer should work.
+	_ = newTestClient(hosts[0], hosts[1:], WithMetrics(registry))
+
+	pid := string(NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion))
+	labels := prometheus.Labels{"protocol": pid, "method": "echo"}
+	calls := testutil.ToFloat64(rpcClientCalls.With(labels))
+	successes := testutil.ToFloat64(rpcClientPeerFeedback.With(prometheus.Labels{"protocol": pid, "method": "echo", "kind": "success"}))
+
+	var rsp string
+	pf, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	pf.RecordSuccess()
+
+	require.Equal(calls+1, testutil.ToFloat64(rpcClientCalls.With(labels)), "call should be counted")
+	require.Equal(successes+1, testutil.ToFloat64(rpcClientPeerFeedback.With(prometheus.Labels{"protocol": pid, "method": "echo", "kind": "success"})), "feedback should be counted")
+
+	count, err := testutil.GatherAndCount(registry, "oasis_rpc_client_latency")
+	require.NoError(err, "GatherAndCount")
+	require.NotZero(count, "latency should be observed")
+
+	// Both encoding and decoding time should be observed.
+	count, err = testutil.GatherAndCount(registry, "oasis_rpc_client_codec_time")
+	require.NoError(err, "GatherAndCount")
+	require.EqualValues(2, count, "codec time should be observed for encoding and decoding")
+}
+
+func TestClientCallStream(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+	rc := newTestClient(hosts[0], hosts[1:])
+	mgr := rc.(*client).PeerManager.(*peerManager)
+
+	// Stream a response spanning multiple chunks.
+	size := 3*StreamChunkSize + 42
+	rd, pf, err := rc.CallStream(context.Background(), "stream", size, time.Second)
+	require.NoError(err, "CallStream")
+	data, err := io.ReadAll(rd)
+	require.NoError(err, "ReadAll")
+	require.NoError(rd.Close(), "Close")
+	require.Equal(testStreamData(size), data, "streamed data should be correct")
+	pf.RecordSuccess()
+	require.Equal(1, mgr.peers[hosts[1].ID()].successes)
+
+	// Buffered responses should be exposed as a stream.
+	rd, _, err = rc.CallStream(context.Background(), "echo", "hello", time.Second)
+	require.NoError(err, "CallStream")
+	data, err = io.ReadAll(rd)
+	require.NoError(err, "ReadAll")
+	require.NoError(rd.Close(), "Close")
+	require.EqualValues(cbor.Marshal("hello"), data)
+
+	// Partial read failures should be recorded as failures.
+	rd, _, err = rc.CallStream(context.Background(), "stream_fail", size, time.Second)
+	require.NoError(err, "CallStream")
+	data, err = io.ReadAll(rd)
+	require.ErrorIs(err, ErrBadRequest, "ReadAll should fail")
+	require.Len(data, size, "data before the failure should be streamed")
+	_ = rd.Close()
+	require.Equal(1, mgr.peers[hosts[1].ID()].failures)
+}
+
+func TestClientCompression(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+	msg := strings.Repeat("hello", 1024)
+
+	for _, compression := range []bool{false, true} {
+		client := newTestClient(hosts[0], hosts[1:], WithCompression(compression))
+
+		var rsp string
+		_, err := client.Call(context.Background(), "echo", msg, &rsp, time.Second)
+		require.NoError(err, "Call")
+		require.Equal(msg, rsp)
+	}
+
+	// Decompressed responses should be subject to the maximum response size.
+	client := newTestClient(hosts[0], hosts[1:], WithCompression(true), WithMaxResponseSize(1024))
+	var rsp string
+	_, err := client.Call(context.Background(), "echo", msg, &rsp, time.Second)
+	require.Error(err, "Call should fail for oversized decompressed responses")
+}
+
+// serveLegacyTestService registers a test service on the given host that only understands the
+// request method and body and closes the stream when a request contains any other fields.
+func serveLegacyTestService(host core.Host) {
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+	host.SetStreamHandler(pid, func(stream network.Stream) {
+		defer stream.Close()
+
+		var request struct {
+			Method string          `json:"method"`
+			Body   cbor.RawMessage `json:"body"`
+		}
+		codec := cbor.NewMessageCodec(stream, codecModuleName)
+		if err := codec.Read(&request); err != nil {
+			return
+		}
+		_ = codec.Write(&Response{Ok: request.Body})
+	})
+}
+
+func TestClientCompressionUnsupported(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveLegacyTestService(hosts[1])
+
+	rc := newTestClient(hosts[0], hosts[1:], WithCompression(true))
+	for i := 0; i < 2; i++ {
+		var rsp string
+		_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+		require.NoError(err, "Call")
+		require.Equal("hello", rsp)
+	}
+	require.False(rc.(*client).compression.isSupported(hosts[1].ID()), "compression should be disabled for peer")
+}
+
+func TestClientKeepAliveUnsupported(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveLegacyTestService(hosts[1])
+
+	for _, compression := range []bool{false, true} {
+		rc := newTestClient(hosts[0], hosts[1:], WithStreamPool(2, time.Second), WithCompression(compression))
+		for i := 0; i < 2; i++ {
+			var rsp string
+			pf, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+			require.NoError(err, "Call")
+			require.Equal("hello", rsp)
+			pf.RecordSuccess()
+		}
+		require.False(rc.(*client).keepAlive.isSupported(hosts[1].ID()), "keep-alive should be disabled for peer")
+		require.Equal(!compression, rc.(*client).compression.isSupported(hosts[1].ID()))
+	}
+}
+
+type testPeerFilter struct {
+	accepted map[core.PeerID]bool
+	calls    int
+}
+
+func (f *testPeerFilter) IsPeerAcceptable(peerID core.PeerID) bool {
+	f.calls++
+	return f.accepted[peerID]
+}
+
+func TestPeerFilterCombinators(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 3)
+	a, b, c := hosts[0].ID(), hosts[1].ID(), hosts[2].ID()
+
+	f1 := &testPeerFilter{accepted: map[core.PeerID]bool{a: true, b: true}}
+	f2 := &testPeerFilter{accepted: map[core.PeerID]bool{b: true, c: true}}
+
+	and := AndFilter(f1, f2)
+	require.False(and.IsPeerAcceptable(a))
+	require.True(and.IsPeerAcceptable(b))
+	require.False(and.IsPeerAcceptable(c))
+
+	or := OrFilter(f1, f2)
+	require.True(or.IsPeerAcceptable(a))
+	require.True(or.IsPeerAcceptable(b))
+	require.True(or.IsPeerAcceptable(c))
+
+	// Evaluation should short-circuit.
+	f1.calls, f2.calls = 0, 0
+	require.False(AndFilter(f2, f1).IsPeerAcceptable(a))
+	require.Equal(0, f1.calls, "AndFilter should stop at the first rejecting filter")
+	f1.calls, f2.calls = 0, 0
+	require.True(OrFilter(f1, f2).IsPeerAcceptable(a))
+	require.Equal(0, f2.calls, "OrFilter should stop at the first accepting filter")
+
+	// Edge cases without any filters.
+	require.True(AndFilter().IsPeerAcceptable(a), "empty AndFilter should accept all peers")
+	require.False(OrFilter().IsPeerAcceptable(a), "empty OrFilter should reject all peers")
+
+	// Combined filters should be usable for calls.
+	serveTestService(hosts[1])
+	serveTestService(hosts[2])
+	rc := newTestClient(hosts[0], hosts[1:], WithPeerFilter(AndFilter(f1, f2)))
+	var rsp string
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	require.Equal("hello", rsp)
+
+	rc = newTestClient(hosts[0], hosts[1:], WithPeerFilter(OrFilter()))
+	_, err = rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.Error(err, "Call should fail when all peers are rejected")
+}
+
+func TestClientRequireConnectedPeer(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 3)
+	serveTestService(hosts[1])
+	serveTestService(hosts[2])
+
+	rc := newTestClient(hosts[0], hosts[1:], WithRequireConnectedPeer(true))
+
+	// With at least one connected peer, calls should proceed.
+	var rsp string
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	require.Equal("hello", rsp)
+
+	// Connected peers rejected by the peer filter should not count.
+	filter := &testPeerFilter{accepted: map[core.PeerID]bool{hosts[2].ID(): true}}
+	frc := newTestClient(hosts[0], hosts[1:], WithRequireConnectedPeer(true), WithPeerFilter(filter))
+	require.NoError(hosts[0].Network().ClosePeer(hosts[2].ID()), "ClosePeer")
+	_, err = frc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.ErrorIs(err, ErrNoConnectedPeers, "Call should fail fast")
+
+	// With zero connected peers, calls should fail immediately.
+	require.NoError(hosts[0].Network().ClosePeer(hosts[1].ID()), "ClosePeer")
+	_, err = rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.ErrorIs(err, ErrNoConnectedPeers, "Call should fail fast")
+	_, _, err = rc.CallMulti(context.Background(), "echo", "hello", "", time.Second, 2, 0)
+	require.ErrorIs(err, ErrNoConnectedPeers, "CallMulti should fail fast")
+	_, _, err = rc.CallStream(context.Background(), "echo", "hello", time.Second)
+	require.ErrorIs(err, ErrNoConnectedPeers, "CallStream should fail fast")
+}
+
+func TestClientAllowDial(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+	require.NoError(hosts[0].Network().ClosePeer(hosts[1].ID()), "ClosePeer")
+
+	// When dialing is allowed, a connection should be established.
+	rc := newTestClient(hosts[0], hosts[1:], WithAllowDial(time.Second))
+	var rsp string
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	require.Equal("hello", rsp)
+	require.Equal(network.Connected, hosts[0].Network().Connectedness(hosts[1].ID()), "peer should be connected")
+}
+
+type testCodecRecorder struct {
+	sync.Mutex
+
+	writes []interface{}
+	reads  []interface{}
+}
+
+type testCodec struct {
+	inner Codec
+	rec   *testCodecRecorder
+}
+
+func (c *testCodec) Write(msg interface{}) error {
+	c.rec.Lock()
+	c.rec.writes = append(c.rec.writes, msg)
+	c.rec.Unlock()
+	return c.inner.Write(msg)
+}
+
+func (c *testCodec) Read(msg interface{}) error {
+	c.rec.Lock()
+	c.rec.reads = append(c.rec.reads, msg)
+	c.rec.Unlock()
+	return c.inner.Read(msg)
+}
+
+func TestClientCodec(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+
+	var rec testCodecRecorder
+	factory := func(rw io.ReadWriter) Codec {
+		return &testCodec{
+			inner: cbor.NewMessageCodec(rw, codecModuleName),
+			rec:   &rec,
+		}
+	}
+	rc := newTestClient(hosts[0], hosts[1:], WithCodec(factory))
+
+	var rsp string
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	require.Equal("hello", rsp)
+
+	// Both the request and the response should flow through the codec.
+	require.Len(rec.writes, 1, "request should be written via the codec")
+	require.IsType(&Request{}, rec.writes[0])
+	require.Equal("echo", rec.writes[0].(*Request).Method)
+	require.Len(rec.reads, 1, "response should be read via the codec")
+	require.IsType(&Response{}, rec.reads[0])
+}
+
+// truncatingCodec is a codec that truncates the Ok field of received responses.
+type truncatingCodec struct {
+	Codec
+
+	size int
+}
+
+func (c *truncatingCodec) Read(msg interface{}) error {
+	if err := c.Codec.Read(msg); err != nil {
+		return err
+	}
+	if rsp, ok := msg.(*Response); ok && len(rsp.Ok) > c.size {
+		rsp.Ok = rsp.Ok[:c.size]
+	}
+	return nil
+}
+
+func TestClientMalformedResponse(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+
+	const truncatedSize = 3
+	factory := func(rw io.ReadWriter) Codec {
+		return &truncatingCodec{
+			Codec: cbor.NewMessageCodec(rw, codecModuleName),
+			size:  truncatedSize,
+		}
+	}
+	rc := newTestClient(hosts[0], hosts[1:], WithCodec(factory))
+
+	var rsp string
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.ErrorIs(err, ErrMalformedResponse, "Call should fail with malformed response")
+
+	var merr *MalformedResponseError
+	require.ErrorAs(err, &merr)
+	require.Equal("echo", merr.Method)
+	require.Equal(hosts[1].ID(), merr.PeerID)
+	require.Equal(truncatedSize, merr.Offset, "offset should point to the truncation")
+	require.Contains(err.Error(), "at offset 3")
+	require.Contains(err.Error(), hosts[1].ID().String())
+}
+
+func TestClientPinnedPeers(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 4)
+	for _, host := range hosts[1:] {
+		serveTestService(host)
+	}
+	rc := newTestClient(hosts[0], hosts[1:], WithStickyPeers(true))
+	mgr := rc.(*client).PeerManager.(*peerManager)
+
+	callPeer := func() core.PeerID {
+		var rsp string
+		pf, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+		require.NoError(err, "Call")
+		return pf.PeerID()
+	}
+
+	// Pinned peers should always be tried first, in pin order.
+	rc.PinPeer(hosts[2].ID())
+	rc.PinPeer(hosts[3].ID())
+	require.Equal(hosts[2].ID(), callPeer(), "Call should route to the pinned peer")
+	require.Equal([]core.PeerID{hosts[2].ID(), hosts[3].ID()}, rc.GetBestPeers()[:2])
+
+	// Pinned peers should take precedence over the sticky peer.
+	mgr.RecordSuccess(hosts[1].ID(), time.Millisecond)
+	require.Equal(hosts[2].ID(), callPeer(), "pinned peer should take precedence over the sticky peer")
+
+	// Failures should not unpin peers.
+	mgr.RecordFailure(hosts[2].ID(), time.Millisecond)
+	require.Equal(hosts[2].ID(), callPeer(), "failures should not unpin peers")
+
+	// Bad peers should be unpinned.
+	rc.RecordBadPeer(hosts[2].ID())
+	require.Equal(hosts[3].ID(), callPeer(), "bad peers should be unpinned")
+
+	// Unpinned peers should no longer be preferred.
+	rc.UnpinPeer(hosts[3].ID())
+	require.Empty(mgr.pinnedPeers)
+	mgr.RecordSuccess(hosts[1].ID(), time.Millisecond)
+	require.Equal(hosts[1].ID(), callPeer(), "sticky peer should be used once peers are unpinned")
+}
+
+func TestClientPeerFeedbackPeerID(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 4)
+	peers := make(map[core.PeerID]bool)
+	for _, host := range hosts[1:] {
+		serveTestService(host)
+		peers[host.ID()] = true
+	}
+	rc := newTestClient(hosts[0], hosts[1:])
+
+	// Results should be correlated with the peers that produced them.
+	rsps, pfs, err := rc.CallMulti(context.Background(), "echo", "hello", "", time.Second, 3, 0)
+	require.NoError(err, "CallMulti")
+	require.Len(rsps, 3)
+	require.Len(pfs, 3)
+	seen := make(map[core.PeerID]bool)
+	for _, pf := range pfs {
+		require.True(peers[pf.PeerID()], "peer feedback should identify the peer")
+		seen[pf.PeerID()] = true
+	}
+	require.Len(seen, 3, "each result should come from a different peer")
+
+	require.Empty(NewNopPeerFeedback().PeerID(), "no-op peer feedback should have an empty peer ID")
+}
+
+type testPeerFeedback struct {
+	successes, failures, badPeers int
+}
+
+func (pf *testPeerFeedback) RecordSuccess() {
+	pf.successes++
+}
+
+func (pf *testPeerFeedback) RecordFailure() {
+	pf.failures++
+}
+
+func (pf *testPeerFeedback) RecordBadPeer() {
+	pf.badPeers++
+}
+
+func (pf *testPeerFeedback) PeerID() core.PeerID {
+	return ""
+}
+
+func TestAggregateFeedback(t *testing.T) {
+	require := require.New(t)
+
+	pf1, pf2 := &testPeerFeedback{}, &testPeerFeedback{}
+	pfs := []PeerFeedback{pf1, pf2}
+	apf := AggregateFeedback(pfs)
+	require.Empty(apf.PeerID(), "aggregate peer feedback should have an empty peer ID")
+
+	// Feedback should be recorded for all wrapped instances.
+	apf.RecordSuccess()
+	apf.RecordFailure()
+	apf.RecordBadPeer()
+	for _, pf := range []*testPeerFeedback{pf1, pf2} {
+		require.Equal(1, pf.successes, "success should be recorded")
+		require.Equal(1, pf.failures, "failure should be recorded")
+		require.Equal(1, pf.badPeers, "bad peer should be recorded")
+	}
+
+	// Individual instances should remain usable and unaffected by changes to the slice.
+	pf1.RecordSuccess()
+	pfs[1] = NewNopPeerFeedback()
+	apf.RecordSuccess()
+	require.Equal(3, pf1.successes)
+	require.Equal(2, pf2.successes)
+}
+
+type testSlowService struct{}
+
+func (s *testSlowService) HandleRequest(ctx context.Context, method string, body cbor.RawMessage) (interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(5 * time.Second):
+		return nil, ErrMethodNotSupported
+	}
+}
+
+func TestClientCallMultiPartialResults(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 3)
+	serveTestService(hosts[1])
+	srv := NewServer(testRuntimeID, testProtocolName, testVersion, &testSlowService{})
+	hosts[2].SetStreamHandler(srv.Protocol(), srv.HandleStream)
+
+	// Results should be returned regardless of whether the fast peer is called first or last.
+	for _, first := range []core.PeerID{hosts[1].ID(), hosts[2].ID()} {
+		rc := newTestClient(hosts[0], hosts[1:])
+		rc.PinPeer(first)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		rsps, pfs, err := rc.CallMulti(ctx, "echo", "hello", "", 10*time.Second, 2, 0)
+		cancel()
+		require.ErrorIs(err, context.DeadlineExceeded, "CallMulti should return the context error")
+		require.Len(rsps, 1, "CallMulti should return the results that are available")
+		require.Len(pfs, 1)
+		require.Equal("hello", *rsps[0].(*string))
+		require.Equal(hosts[1].ID(), pfs[0].PeerID())
+	}
+}
+
+type testCountingService struct {
+	testService
+
+	requests uint64
+}
+
+func (s *testCountingService) HandleRequest(ctx context.Context, method string, body cbor.RawMessage) (interface{}, error) {
+	atomic.AddUint64(&s.requests, 1)
+	return s.testService.HandleRequest(ctx, method, body)
+}
+
+func TestClientCallMultiMaxPeers(t *testing.T) {
+	require := require.New(t)
+
+	const numPeers = 32
+	hosts := newTestNetwork(t, numPeers+1)
+	svcs := make(map[core.PeerID]*testCountingService)
+	for _, host := range hosts[1:] {
+		svc := &testCountingService{}
+		srv := NewServer(testRuntimeID, testProtocolName, testVersion, svc)
+		host.SetStreamHandler(srv.Protocol(), srv.HandleStream)
+		svcs[host.ID()] = svc
+	}
+	rc := newTestClient(hosts[0], hosts[1:])
+
+	// Make sure that the best peers are known.
+	best := []core.PeerID{hosts[7].ID(), hosts[3].ID()}
+	for _, peer := range best {
+		rc.PinPeer(peer)
+	}
+
+	rsps, pfs, err := rc.CallMulti(context.Background(), "echo", "hello", "", time.Second, 1, 2)
+	require.NoError(err, "CallMulti")
+	require.Len(rsps, 2, "CallMulti should only call up to maxPeers peers")
+	require.ElementsMatch(best, []core.PeerID{pfs[0].PeerID(), pfs[1].PeerID()}, "CallMulti should call the best peers")
+
+	var requests uint64
+	for _, svc := range svcs {
+		requests += atomic.LoadUint64(&svc.requests)
+	}
+	require.EqualValues(2, requests, "other peers should not be called")
+
+	// Zero maxPeers should call all peers.
+	rsps, _, err = rc.CallMulti(context.Background(), "echo", "hello", "", time.Second, 4, 0)
+	require.NoError(err, "CallMulti")
+	require.Len(rsps, numPeers)
+}
+
+func TestClientDefaultCallOptions(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	svc := &testCountingService{}
+	srv := NewServer(testRuntimeID, testProtocolName, testVersion, svc)
+	hosts[1].SetStreamHandler(srv.Protocol(), srv.HandleStream)
+	rc := newTestClient(hosts[0], hosts[1:], WithDefaultCallOptions(
+		WithMaxRetries(2),
+		WithRetryInterval(time.Hour),
+	))
+
+	// Per-call options should override the client defaults while inheriting the rest.
+	var rsp string
+	start := time.Now()
+	_, err := rc.Call(context.Background(), "unknown", "hello", &rsp, time.Second, WithRetryInterval(10*time.Millisecond))
+	require.Error(err, "Call should fail for unsupported methods")
+	require.Less(time.Since(start), time.Minute, "per-call retry interval should override the client default")
+	require.EqualValues(3, atomic.LoadUint64(&svc.requests), "client default max retries should be used")
+}
+
+func TestClientResetPeerReputation(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 3)
+	for _, host := range hosts[1:] {
+		serveTestService(host)
+	}
+	rc := newTestClient(hosts[0], hosts[1:], WithStickyPeers(true), WithCircuitBreaker(1, time.Hour))
+	c := rc.(*client)
+	mgr := c.PeerManager.(*peerManager)
+	peerA, peerB := hosts[1].ID(), hosts[2].ID()
+
+	// Accumulate some state.
+	c.breaker.RecordFailure(peerA, time.Millisecond)
+	c.breaker.RecordSuccess(peerB, time.Millisecond)
+	require.False(c.isPeerAcceptable(peerA), "peer should be skipped by the circuit breaker")
+	require.Equal(peerB, mgr.stickyPeer)
+
+	// Resetting a single peer should only clear the state of that peer.
+	rc.ResetPeer(peerA)
+	require.True(c.isPeerAcceptable(peerA), "circuit breaker should be reset for the peer")
+	require.Zero(*mgr.peers[peerA], "peer statistics should be reset")
+	require.Equal(1, mgr.peers[peerB].successes)
+	require.Equal(peerB, mgr.stickyPeer)
+
+	// Resetting a bad peer should allow it to be used again.
+	rc.RecordBadPeer(peerA)
+	require.NotContains(rc.GetBestPeers(), peerA)
+	rc.ResetPeer(peerA)
+	rc.AddPeer(peerA)
+	require.Contains(rc.GetBestPeers(), peerA, "bad peer mark should be cleared")
+
+	// Resetting all peers should clear all state.
+	rc.RecordBadPeer(peerA)
+	c.breaker.RecordFailure(peerB, time.Millisecond)
+	require.False(c.isPeerAcceptable(peerB), "peer should be skipped by the circuit breaker")
+	rc.ResetPeerReputation()
+	rc.AddPeer(peerA)
+	require.True(c.isPeerAcceptable(peerB), "circuit breaker should be reset")
+	require.ElementsMatch([]core.PeerID{peerA, peerB}, rc.GetBestPeers(), "bad peer mark should be cleared")
+	require.Zero(*mgr.peers[peerB], "peer statistics should be reset")
+	require.Empty(mgr.stickyPeer, "sticky peer should be reset")
+	require.Zero(mgr.avgRequestLatency)
+}
+
+func TestClientCallRaw(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	serveTestService(hosts[1])
+	client := newTestClient(hosts[0], hosts[1:])
+
+	rsp, pf, err := client.CallRaw(context.Background(), "echo", "hello", time.Second)
+	require.NoError(err, "CallRaw")
+	require.NotNil(pf, "CallRaw should return peer feedback")
+	require.EqualValues(cbor.Marshal("hello"), rsp, "CallRaw should return the raw response")
+
+	_, _, err = client.CallRaw(context.Background(), "unknown", "hello", time.Second)
+	require.ErrorIs(err, ErrMethodNotSupported, "CallRaw should convert error responses")
+}
+
+// serveTricklingService registers a handler on the given host which responds to any request with
+// the given response, sending chunkSize bytes at a time every interval.
+func serveTricklingService(host core.Host, rsp string, chunkSize int, interval time.Duration) {
+	host.SetStreamHandler(NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion), func(stream network.Stream) {
+		defer stream.Close()
+
+		var rq Request
+		if err := cbor.NewMessageCodec(stream, codecModuleName).Read(&rq); err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := cbor.NewMessageCodec(&buf, codecModuleName).Write(&Response{Ok: cbor.Marshal(rsp)}); err != nil {
+			return
+		}
+		data := buf.Bytes()
+		for len(data) > 0 {
+			n := chunkSize
+			if n > len(data) {
+				n = len(data)
+			}
+			if _, err := stream.Write(data[:n]); err != nil {
+				return
+			}
+			data = data[n:]
+			time.Sleep(interval)
+		}
+	})
+}
+
+func TestClientMinThroughput(t *testing.T) {
+	require := require.New(t)
+
+	msg := strings.Repeat("a", 200)
+	hosts := newTestNetwork(t, 3)
+	serveTricklingService(hosts[1], msg, 1, 20*time.Millisecond)
+	serveTricklingService(hosts[2], msg, 100, 10*time.Millisecond)
+
+	// Peers trickling the response should be aborted.
+	rc := newTestClient(hosts[0], hosts[1:2], WithMinThroughput(1000))
+	var rsp string
+	start := time.Now()
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Minute)
+	require.ErrorIs(err, ErrPeerTooSlow, "Call should fail for slow peers")
+	require.Less(time.Since(start), 10*time.Second, "slow peers should be aborted early")
+	require.Equal(1, rc.(*client).PeerManager.(*peerManager).peers[hosts[1].ID()].failures)
+
+	// Peers that are fast enough should be used.
+	rc = newTestClient(hosts[0], hosts[2:], WithMinThroughput(1000))
+	_, err = rc.Call(context.Background(), "echo", "hello", &rsp, time.Minute)
+	require.NoError(err, "Call")
+	require.Equal(msg, rsp)
+}
+
+func TestClientSupportsPeer(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 3)
+	client := newTestClient(hosts[0], nil)
+
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+	require.Equal(pid, client.ProtocolID())
+
+	// Only peers advertising the exact protocol should be supported.
+	otherVersion := testVersion
+	otherVersion.Major++
+	ps := hosts[0].Peerstore()
+	require.NoError(ps.AddProtocols(hosts[1].ID(), string(pid)), "AddProtocols")
+	require.NoError(ps.AddProtocols(hosts[2].ID(), string(NewRuntimeProtocolID(testRuntimeID, testProtocolName, otherVersion))), "AddProtocols")
+
+	require.True(client.SupportsPeer(hosts[1].ID()), "peer advertising the protocol should be supported")
+	require.False(client.SupportsPeer(hosts[2].ID()), "peer advertising another protocol version should not be supported")
+}
+
+func TestClientCompatibleVersions(t *testing.T) {
+	require := require.New(t)
+
+	newerVersion := version.Version{Major: testVersion.Major + 1}
+	serveVersion := func(host core.Host, v version.Version) {
+		srv := NewServer(testRuntimeID, testProtocolName, v, &testService{})
+		host.SetStreamHandler(srv.Protocol(), srv.HandleStream)
+	}
+	requireVersion := func(pf PeerFeedback, v version.Version) {
+		vpf, ok := pf.(VersionedPeerFeedback)
+		require.True(ok, "peer feedback should expose the protocol version")
+		require.Equal(v, vpf.ProtocolVersion(), "protocol version should be the negotiated one")
+	}
+
+	hosts := newTestNetwork(t, 3)
+	serveVersion(hosts[1], newerVersion)
+	serveVersion(hosts[2], testVersion)
+	serveVersion(hosts[2], newerVersion)
+
+	// Without a compatibility range, peers only supporting a newer version cannot be reached.
+	var rsp string
+	rc := newTestClient(hosts[0], hosts[1:2])
+	_, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.Error(err, "Call should fail without a compatibility range")
+
+	// With a compatibility range, the newer version should be negotiated.
+	rc = newTestClient(hosts[0], hosts[1:2], WithCompatibleVersions(testVersion, newerVersion))
+	require.Equal(NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion), rc.ProtocolID())
+	pf, err := rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	require.Equal("hello", rsp)
+	requireVersion(pf, newerVersion)
+
+	// The highest mutually supported version should be preferred.
+	rc = newTestClient(hosts[0], hosts[2:3], WithCompatibleVersions(testVersion, newerVersion))
+	pf, err = rc.Call(context.Background(), "echo", "hello", &rsp, time.Second)
+	require.NoError(err, "Call")
+	requireVersion(pf, newerVersion)
+
+	rd, pf, err := rc.CallStream(context.Background(), "echo", "hello", time.Second)
+	require.NoError(err, "CallStream")
+	require.NoError(rd.Close(), "Close")
+	requireVersion(pf, newerVersion)
+
+	// Peers advertising any compatible version should be supported.
+	require.NoError(hosts[0].Peerstore().AddProtocols(
+		hosts[1].ID(),
+		string(NewRuntimeProtocolID(testRuntimeID, testProtocolName, newerVersion)),
+	), "AddProtocols")
+	require.True(rc.SupportsPeer(hosts[1].ID()), "peer advertising a compatible version should be supported")
+	require.False(newTestClient(hosts[0], nil).SupportsPeer(hosts[1].ID()))
+}
+
+func TestClientHalfClose(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+
+	// Configure a peer that only responds after observing EOF on its read side.
+	hosts[1].SetStreamHandler(pid, func(stream network.Stream) {
+		defer stream.Close()
+
+		var request Request
+		codec := cbor.NewMessageCodec(stream, codecModuleName)
+		if err := codec.Read(&request); err != nil {
+			return
+		}
+
+		// Mock streams do not support deadlines, so reset the stream in case EOF is not observed.
+		eofCh := make(chan bool, 1)
+		go func() {
+			n, err := stream.Read(make([]byte, 1))
+			eofCh <- n == 0 && err == io.EOF
+		}()
+		select {
+		case eof := <-eofCh:
+			if !eof {
+				_ = stream.Reset()
+				return
+			}
+		case <-time.After(time.Second):
+			_ = stream.Reset()
+			return
+		}
+
+		_ = codec.Write(&Response{Ok: request.Body})
+	})
+
+	client := newTestClient(hosts[0], hosts[1:])
+
+	var rsp string
+	_, err := client.Call(context.Background(), "echo", "hello", &rsp, 5*time.Second)
+	require.NoError(err, "Call")
+	require.Equal("hello", rsp)
+}
+
+func TestClientStreamPool(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	srv := NewServer(testRuntimeID, testProtocolName, testVersion, &testService{})
+	var streams uint64
+	hosts[1].SetStreamHandler(srv.Protocol(), func(stream network.Stream) {
+		atomic.AddUint64(&streams, 1)
+		srv.HandleStream(stream)
+	})
+
+	client := newTestClient(hosts[0], hosts[1:], WithStreamPool(1, time.Minute))
+	defer client.Close()
+
+	// Successful calls should reuse the same stream.
+	for i := 0; i < 3; i++ {
+		var rsp string
+		_, err := client.Call(context.Background(), "echo", "hello", &rsp, 5*time.Second)
+		require.NoError(err, "Call")
+		require.Equal("hello", rsp)
+	}
+	require.EqualValues(1, atomic.LoadUint64(&streams), "stream should be reused")
+
+	// Failed calls should discard the stream.
+	_, err := client.Call(context.Background(), "unknown", "hello", nil, 5*time.Second)
+	require.ErrorIs(err, ErrMethodNotSupported)
+
+	var rsp string
+	_, err = client.Call(context.Background(), "echo", "hello", &rsp, 5*time.Second)
+	require.NoError(err, "Call")
+	require.EqualValues(2, atomic.LoadUint64(&streams), "stream should be discarded after a failure")
+
+	// Streamed responses should not be reused.
+	rd, _, err := client.CallStream(context.Background(), "stream", 16, 5*time.Second)
+	require.NoError(err, "CallStream")
+	data, err := io.ReadAll(rd)
+	require.NoError(err, "ReadAll")
+	require.Equal(testStreamData(16), data)
+	require.NoError(rd.Close())
+
+	_, err = client.Call(context.Background(), "echo", "hello", &rsp, 5*time.Second)
+	require.NoError(err, "Call")
+	require.EqualValues(3, atomic.LoadUint64(&streams), "streamed responses should not be reused")
+}
+
+func TestClientStreamPoolStale(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+
+	// Configure a peer that agrees to keep streams alive but closes them right after responding.
+	closedCh := make(chan struct{}, 2)
+	var streams uint64
+	hosts[1].SetStreamHandler(pid, func(stream network.Stream) {
+		atomic.AddUint64(&streams, 1)
+
+		var request Request
+		codec := cbor.NewMessageCodec(stream, codecModuleName)
+		if err := codec.Read(&request); err != nil {
+			_ = stream.Reset()
+			return
+		}
+		_ = codec.Write(&Response{Ok: request.Body, KeepAlive: request.KeepAlive})
+		_ = stream.Close()
+		closedCh <- struct{}{}
+	})
+
+	client := newTestClient(hosts[0], hosts[1:], WithStreamPool(1, time.Minute))
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		var rsp string
+		_, err := client.Call(context.Background(), "echo", "hello", &rsp, 5*time.Second)
+		require.NoError(err, "Call")
+		require.Equal("hello", rsp)
+		<-closedCh
+	}
+	require.EqualValues(2, atomic.LoadUint64(&streams), "stale stream should be replaced")
+}
+
+func TestStreamPoolExpiry(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+	hosts[1].SetStreamHandler(pid, func(stream network.Stream) {
+		_, _ = io.Copy(io.Discard, stream)
+		_ = stream.Close()
+	})
+
+	now := time.Now()
+	pool := newStreamPool(2, time.Minute)
+	pool.now = func() time.Time {
+		return now
+	}
+
+	peerID := hosts[1].ID()
+	newStream := func() network.Stream {
+		stream, err := hosts[0].NewStream(context.Background(), peerID, pid)
+		require.NoError(err, "NewStream")
+		return stream
+	}
+
+	// The pool should be bounded per peer.
+	s1, s2, s3 := newStream(), newStream(), newStream()
+	pool.put(peerID, s1, nil)
+	now = now.Add(30 * time.Second)
+	pool.put(peerID, s2, nil)
+	pool.put(peerID, s3, nil)
+	require.Len(pool.idle[peerID], 2)
+
+	// The most recently used stream should be returned first.
+	ps := pool.get(peerID)
+	require.NotNil(ps)
+	require.Equal(s2, ps.stream)
+	pool.put(peerID, ps.stream, nil)
+
+	// Expired streams should be discarded.
+	now = now.Add(45 * time.Second)
+	ps = pool.get(peerID)
+	require.NotNil(ps)
+	require.Equal(s2, ps.stream, "stream that became idle later should not be expired")
+	require.Nil(pool.get(peerID), "expired streams should be discarded")
+
+	pool.close()
+	pool.put(peerID, ps.stream, nil)
+	require.Nil(pool.get(peerID), "closed pool should not keep streams")
+}
+
+func TestClientCallDeadlineBudget(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 2)
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+
+	// Configure a peer that is too slow for the first request and fast afterwards.
+	doneCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(doneCh)
+	})
+	var requests uint64
+	hosts[1].SetStreamHandler(pid, func(stream network.Stream) {
+		defer stream.Close()
+
+		var request Request
+		codec := cbor.NewMessageCodec(stream, codecModuleName)
+		if err := codec.Read(&request); err != nil {
+			return
+		}
+		if atomic.AddUint64(&requests, 1) == 1 {
+			select {
+			case <-time.After(10 * time.Second):
+			case <-doneCh:
+				return
+			}
+		}
+		_ = codec.Write(&Response{Ok: request.Body})
+	})
+
+	client := newTestClient(hosts[0], hosts[1:])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var rsp string
+	_, err := client.Call(ctx, "echo", "hello", &rsp, 10*time.Second,
+		WithMaxRetries(3),
+		WithRetryInterval(10*time.Millisecond),
+	)
+	require.NoError(err, "Call should succeed on a later attempt")
+	require.Equal("hello", rsp)
+	require.EqualValues(2, atomic.LoadUint64(&requests), "slow attempt should be aborted")
+
+	// Retrying should stop early when the deadline is near.
+	atomic.StoreUint64(&requests, 0)
+	ctx, cancel = context.WithTimeout(context.Background(), MinCallAttemptBudget)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.Call(ctx, "echo", "hello", &rsp, 10*time.Second,
+		WithMaxRetries(3),
+		WithRetryInterval(10*time.Millisecond),
+	)
+	require.Error(err, "Call should fail")
+	require.EqualValues(1, atomic.LoadUint64(&requests), "no retries should be attempted")
+	require.Less(time.Since(start), time.Second)
+}
+
+func TestAttemptBudget(t *testing.T) {
+	require := require.New(t)
+
+	_, ok := attemptBudget(context.Background(), 4, time.Second)
+	require.False(ok, "no budget without a deadline")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	budget, ok := attemptBudget(ctx, 4, time.Second)
+	require.True(ok)
+	require.InDelta(float64(7*time.Second/4), float64(budget), float64(100*time.Millisecond))
+
+	budget, ok = attemptBudget(ctx, 1, time.Second)
+	require.True(ok)
+	require.InDelta(float64(10*time.Second), float64(budget), float64(100*time.Millisecond))
+
+	budget, ok = attemptBudget(ctx, 20, time.Second)
+	require.True(ok)
+	require.Zero(budget, "retry intervals exceed the remaining time")
+}
+
+func TestClientErrorClassification(t *testing.T) {
+	require := require.New(t)
+
+	hosts := newTestNetwork(t, 3)
+	serveTestService(hosts[1])
+	serveTestService(hosts[2])
+
+	client := newTestClient(hosts[0], hosts[1:])
+
+	// All peers returning the same request error should surface that error.
+	_, err := client.Call(context.Background(), "unknown", nil, nil, 5*time.Second,
+		WithMaxRetries(1),
+		WithRetryInterval(10*time.Millisecond),
+	)
+	require.ErrorIs(err, ErrMethodNotSupported)
+	require.True(IsRequestError(err), "error should be a request error")
+	require.False(IsUnavailableError(err), "error should not be an unavailable error")
+
+	_, _, err = client.CallStream(context.Background(), "unknown", nil, 5*time.Second)
+	require.ErrorIs(err, ErrMethodNotSupported)
+	require.True(IsRequestError(err), "error should be a request error")
+
+	// Peer faults should be classified as unavailable.
+	pid := NewRuntimeProtocolID(testRuntimeID, testProtocolName, testVersion)
+	hosts[2].SetStreamHandler(pid, func(stream network.Stream) {
+		_ = stream.Reset()
+	})
+
+	_, err = client.Call(context.Background(), "unknown", nil, nil, 5*time.Second)
+	require.ErrorIs(err, ErrUnavailable)
+	require.True(IsUnavailableError(err), "error should be an unavailable error")
+	require.False(IsRequestError(err), "error should not be a request error")
+	require.Contains(err.Error(), "call failed on all peers")
+}
+
+func TestRequestErrors(t *testing.T) {
+	require := require.New(t)
+
+	require.False(IsRequestError(nil))
+	require.False(IsRequestError(fmt.Errorf("some error")))
+	require.True(IsRequestError(ErrBadRequest))
+	require.False(IsRequestError(ErrPeerTooSlow))
+	require.False(IsRequestError(&unavailableError{err: ErrMethodNotSupported}), "unavailable errors are not request errors")
+
+	// Errors received over the wire should be classified by module and code.
+	require.True(IsRequestError(errors.FromCode(ModuleName, 1, "rpc: method not supported: context")))
+
+	RegisterRequestErrors(errTestRequest)
+	require.True(IsRequestError(errTestRequest))
+	require.True(IsRequestError(errors.FromCode("p2p/rpc/test", 1, "test: invalid request")))
+	require.True(IsRequestError(fmt.Errorf("wrapped: %w", errTestRequest)))
+	require.False(IsRequestError(errTestOther), "errors from the same module should not be request errors unless registered")
+}
@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+)
+
+// compressPayload compresses the given response payload.
+//
+// The second return value is false in case compression would not reduce the payload size, in
+// which case the payload should be sent uncompressed.
+func compressPayload(data []byte) ([]byte, bool) {
+	compressed := snappy.Encode(nil, data)
+	if len(compressed) >= len(data) {
+		return nil, false
+	}
+	return compressed, true
+}
+
+// decompressPayload decompresses the given response payload, making sure that the decompressed
+// payload does not exceed the given maximum size.
+func decompressPayload(data []byte, maxSize uint64) ([]byte, error) {
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, fmt.Errorf("malformed compressed payload: %w", err)
+	}
+	if uint64(size) > maxSize {
+		return nil, fmt.Errorf("decompressed payload too large (size: %d max: %d)", size, maxSize)
+	}
+	return snappy.Decode(nil, data)
+}
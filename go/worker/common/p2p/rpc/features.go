@@ -0,0 +1,48 @@
+package rpc
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	core "github.com/libp2p/go-libp2p-core"
+)
+
+// featureTracker keeps track of peers which do not support an optional protocol feature (e.g.,
+// response compression or keep-alive streams).
+//
+// Peers that do not understand the corresponding request flag reject the request and close the
+// stream without sending a response, so such peers are remembered and subsequent requests sent to
+// them do not advertise the feature.
+type featureTracker struct {
+	sync.RWMutex
+
+	unsupported map[core.PeerID]struct{}
+}
+
+func (ft *featureTracker) isSupported(peerID core.PeerID) bool {
+	ft.RLock()
+	defer ft.RUnlock()
+
+	_, unsupported := ft.unsupported[peerID]
+	return !unsupported
+}
+
+func (ft *featureTracker) markUnsupported(peerID core.PeerID) {
+	ft.Lock()
+	defer ft.Unlock()
+
+	ft.unsupported[peerID] = struct{}{}
+}
+
+func newFeatureTracker() *featureTracker {
+	return &featureTracker{
+		unsupported: make(map[core.PeerID]struct{}),
+	}
+}
+
+// isClosedWithoutResponse returns true iff the error indicates that the peer closed the stream
+// without sending any response.
+func isClosedWithoutResponse(err error) bool {
+	return errors.Is(err, io.EOF)
+}
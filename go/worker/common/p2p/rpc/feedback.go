@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	core "github.com/libp2p/go-libp2p-core"
+)
+
+// asyncFeedbackQueueSize is the maximum number of pending peer feedback records when asynchronous
+// peer feedback recording is enabled.
+const asyncFeedbackQueueSize = 1024
+
+// feedbackRecorder is an interface for recording peer feedback.
+type feedbackRecorder interface {
+	// RecordSuccess records a successful protocol interaction with the given peer.
+	RecordSuccess(peerID core.PeerID, latency time.Duration)
+
+	// RecordFailure records an unsuccessful protocol interaction with the given peer.
+	RecordFailure(peerID core.PeerID, latency time.Duration)
+
+	// RecordBadPeer records a malicious protocol interaction with the given peer.
+	RecordBadPeer(peerID core.PeerID)
+}
+
+type feedbackKind uint8
+
+const (
+	feedbackSuccess feedbackKind = iota
+	feedbackFailure
+	feedbackBadPeer
+)
+
+type feedback struct {
+	kind    feedbackKind
+	peerID  core.PeerID
+	latency time.Duration
+}
+
+// asyncFeedbackRecorder is a feedback recorder that queues peer feedback and records it in the
+// background.
+//
+// All feedback is processed by a single worker in the order it was queued, which preserves the
+// ordering of feedback for each peer.
+type asyncFeedbackRecorder struct {
+	sync.RWMutex
+
+	mgr feedbackRecorder
+
+	queueCh chan *feedback
+	doneCh  chan struct{}
+	closed  bool
+}
+
+func (r *asyncFeedbackRecorder) RecordSuccess(peerID core.PeerID, latency time.Duration) {
+	r.enqueue(&feedback{kind: feedbackSuccess, peerID: peerID, latency: latency})
+}
+
+func (r *asyncFeedbackRecorder) RecordFailure(peerID core.PeerID, latency time.Duration) {
+	r.enqueue(&feedback{kind: feedbackFailure, peerID: peerID, latency: latency})
+}
+
+func (r *asyncFeedbackRecorder) RecordBadPeer(peerID core.PeerID) {
+	r.enqueue(&feedback{kind: feedbackBadPeer, peerID: peerID})
+}
+
+func (r *asyncFeedbackRecorder) enqueue(fb *feedback) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.closed {
+		// Once closed, record all feedback synchronously.
+		r.record(fb)
+		return
+	}
+	r.queueCh <- fb
+}
+
+func (r *asyncFeedbackRecorder) record(fb *feedback) {
+	switch fb.kind {
+	case feedbackSuccess:
+		r.mgr.RecordSuccess(fb.peerID, fb.latency)
+	case feedbackFailure:
+		r.mgr.RecordFailure(fb.peerID, fb.latency)
+	case feedbackBadPeer:
+		r.mgr.RecordBadPeer(fb.peerID)
+	}
+}
+
+func (r *asyncFeedbackRecorder) worker() {
+	defer close(r.doneCh)
+
+	for fb := range r.queueCh {
+		r.record(fb)
+	}
+}
+
+// Close stops accepting new feedback into the queue and waits for all queued feedback to be
+// recorded.
+func (r *asyncFeedbackRecorder) Close() {
+	r.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queueCh)
+	}
+	r.Unlock()
+
+	<-r.doneCh
+}
+
+func newAsyncFeedbackRecorder(mgr feedbackRecorder) *asyncFeedbackRecorder {
+	r := &asyncFeedbackRecorder{
+		mgr:     mgr,
+		queueCh: make(chan *feedback, asyncFeedbackQueueSize),
+		doneCh:  make(chan struct{}),
+	}
+	go r.worker()
+
+	return r
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	rpcClientCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_rpc_client_calls",
+			Help: "Number of P2P RPC calls to peers.",
+		},
+		[]string{"protocol", "method"},
+	)
+	rpcClientLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "oasis_rpc_client_latency",
+			Help: "P2P RPC call latency (seconds).",
+		},
+		[]string{"protocol", "method"},
+	)
+	rpcClientPeerFeedback = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_rpc_client_peer_feedback",
+			Help: "Number of P2P RPC peer feedback records.",
+		},
+		[]string{"protocol", "method", "kind"},
+	)
+
+	rpcClientCodecTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "oasis_rpc_client_codec_time",
+			Help: "Time spent on encoding P2P RPC requests and decoding responses (seconds).",
+		},
+		[]string{"protocol", "method", "op"},
+	)
+
+	rpcClientCollectors = []prometheus.Collector{
+		rpcClientCalls,
+		rpcClientLatency,
+		rpcClientPeerFeedback,
+		rpcClientCodecTime,
+	}
+)
+
+// String returns a string representation of the feedback kind.
+func (k feedbackKind) String() string {
+	switch k {
+	case feedbackSuccess:
+		return "success"
+	case feedbackFailure:
+		return "failure"
+	case feedbackBadPeer:
+		return "bad_peer"
+	default:
+		return "unknown"
+	}
+}
+
+// codecOp is a codec operation.
+type codecOp string
+
+const (
+	codecOpEncode codecOp = "encode"
+	codecOpDecode codecOp = "decode"
+)
+
+// clientMetrics is an interface for collecting client metrics.
+type clientMetrics interface {
+	// observeCall records a call of the given method to a single peer.
+	observeCall(method string, latency time.Duration)
+
+	// observeFeedback records peer feedback for the given method.
+	observeFeedback(method string, kind feedbackKind)
+
+	// observeCodecTime records the time spent on encoding or decoding for the given method.
+	observeCodecTime(method string, op codecOp, duration time.Duration)
+}
+
+type nopClientMetrics struct{}
+
+func (m *nopClientMetrics) observeCall(method string, latency time.Duration) {
+}
+
+func (m *nopClientMetrics) observeFeedback(method string, kind feedbackKind) {
+}
+
+func (m *nopClientMetrics) observeCodecTime(method string, op codecOp, duration time.Duration) {
+}
+
+type prometheusClientMetrics struct {
+	protocolID protocol.ID
+}
+
+func (m *prometheusClientMetrics) observeCall(method string, latency time.Duration) {
+	labels := prometheus.Labels{"protocol": string(m.protocolID), "method": method}
+	rpcClientCalls.With(labels).Inc()
+	rpcClientLatency.With(labels).Observe(latency.Seconds())
+}
+
+func (m *prometheusClientMetrics) observeFeedback(method string, kind feedbackKind) {
+	labels := prometheus.Labels{"protocol": string(m.protocolID), "method": method, "kind": kind.String()}
+	rpcClientPeerFeedback.With(labels).Inc()
+}
+
+func (m *prometheusClientMetrics) observeCodecTime(method string, op codecOp, duration time.Duration) {
+	labels := prometheus.Labels{"protocol": string(m.protocolID), "method": method, "op": string(op)}
+	rpcClientCodecTime.With(labels).Observe(duration.Seconds())
+}
+
+func newClientMetrics(registerer prometheus.Registerer, protocolID protocol.ID) (clientMetrics, error) {
+	if registerer == nil {
+		return &nopClientMetrics{}, nil
+	}
+
+	for _, collector := range rpcClientCollectors {
+		if err := registerer.Register(collector); err != nil {
+			// Collectors are shared by all clients so they may have already been registered.
+			var are prometheus.AlreadyRegisteredError
+			if !errors.As(err, &are) {
+				return nil, err
+			}
+		}
+	}
+
+	return &prometheusClientMetrics{
+		protocolID: protocolID,
+	}, nil
+}
@@ -57,6 +57,27 @@ type PeerManager interface {
 	// GetBestPeers returns a set of peers sorted by the probability that they will be able to
 	// answer our requests the fastest with some randomization.
 	GetBestPeers() []core.PeerID
+
+	// PinPeer pins the given peer so that it is always tried first, until it is either unpinned
+	// via UnpinPeer or recorded as bad via RecordBadPeer.
+	//
+	// Pinned peers take precedence over the sticky peer and are never evicted by the sticky peers
+	// logic (e.g., on failures). When multiple peers are pinned, they are tried in pin order.
+	PinPeer(peerID core.PeerID)
+
+	// UnpinPeer unpins a previously pinned peer.
+	UnpinPeer(peerID core.PeerID)
+
+	// ResetPeerReputation clears all accumulated peer statistics, bad peer marks and the sticky
+	// peer so that the peer set is re-evaluated from scratch on the next request. Pinned peers
+	// remain pinned.
+	//
+	// This only affects RPC-level peer scoring and does not change the underlying libp2p
+	// connection state. In particular, peers blocked via P2P.BlockPeer remain blocked.
+	ResetPeerReputation()
+
+	// ResetPeer is like ResetPeerReputation but only clears the state of the given peer.
+	ResetPeer(peerID core.PeerID)
 }
 
 type peerStats struct {
@@ -92,6 +113,8 @@ type peerManager struct {
 	p2p        P2P
 	host       core.Host
 	protocolID protocol.ID
+	// protocolIDs are all protocols supported by the peer manager, including protocolID.
+	protocolIDs []protocol.ID
 
 	peers        map[core.PeerID]*peerStats
 	ignoredPeers map[core.PeerID]bool
@@ -99,6 +122,8 @@ type peerManager struct {
 	stickyPeers bool
 	stickyPeer  core.PeerID
 
+	pinnedPeers []core.PeerID
+
 	avgRequestLatency time.Duration
 
 	logger *logging.Logger
@@ -186,6 +211,79 @@ func (mgr *peerManager) RecordBadPeer(peerID core.PeerID) {
 	mgr.ignoredPeers[peerID] = true
 	delete(mgr.peers, peerID)
 	mgr.unstickPeerLocked(peerID)
+	mgr.unpinPeerLocked(peerID)
+}
+
+func (mgr *peerManager) PinPeer(peerID core.PeerID) {
+	mgr.Lock()
+	defer mgr.Unlock()
+
+	if mgr.isPinnedLocked(peerID) {
+		return
+	}
+	mgr.pinnedPeers = append(mgr.pinnedPeers, peerID)
+}
+
+func (mgr *peerManager) UnpinPeer(peerID core.PeerID) {
+	mgr.Lock()
+	defer mgr.Unlock()
+
+	mgr.unpinPeerLocked(peerID)
+}
+
+func (mgr *peerManager) ResetPeerReputation() {
+	mgr.Lock()
+	for peerID := range mgr.peers {
+		mgr.peers[peerID] = &peerStats{}
+	}
+	mgr.ignoredPeers = make(map[core.PeerID]bool)
+	mgr.stickyPeer = ""
+	mgr.avgRequestLatency = 0
+	mgr.Unlock()
+
+	mgr.logger.Info("reset peer reputation")
+
+	// Re-add any previously ignored peers that are still connected.
+	for _, peerID := range mgr.host.Network().Peers() {
+		mgr.addPeerIfSupported(peerID)
+	}
+}
+
+func (mgr *peerManager) ResetPeer(peerID core.PeerID) {
+	mgr.Lock()
+	if _, exists := mgr.peers[peerID]; exists {
+		mgr.peers[peerID] = &peerStats{}
+	}
+	wasIgnored := mgr.ignoredPeers[peerID]
+	delete(mgr.ignoredPeers, peerID)
+	mgr.unstickPeerLocked(peerID)
+	mgr.Unlock()
+
+	mgr.logger.Debug("reset peer reputation",
+		"peer_id", peerID,
+	)
+
+	if wasIgnored && len(mgr.host.Network().ConnsToPeer(peerID)) > 0 {
+		mgr.addPeerIfSupported(peerID)
+	}
+}
+
+func (mgr *peerManager) isPinnedLocked(peerID core.PeerID) bool {
+	for _, p := range mgr.pinnedPeers {
+		if p == peerID {
+			return true
+		}
+	}
+	return false
+}
+
+func (mgr *peerManager) unpinPeerLocked(peerID core.PeerID) {
+	for i, p := range mgr.pinnedPeers {
+		if p == peerID {
+			mgr.pinnedPeers = append(mgr.pinnedPeers[:i], mgr.pinnedPeers[i+1:]...)
+			return
+		}
+	}
 }
 
 func (mgr *peerManager) unstickPeerLocked(peerID core.PeerID) {
@@ -206,6 +304,10 @@ func (mgr *peerManager) GetBestPeers() []core.PeerID {
 	var haveStickyPeer bool
 	peers := make([]core.PeerID, 0, len(mgr.peers))
 	for peer := range mgr.peers {
+		if mgr.isPinnedLocked(peer) {
+			// Do not include pinned peers so we can prepend them later.
+			continue
+		}
 		if mgr.stickyPeer == peer {
 			// Do not include the sticky peer so we can prepend it later.
 			haveStickyPeer = true
@@ -242,9 +344,56 @@ func (mgr *peerManager) GetBestPeers() []core.PeerID {
 		peers = append([]core.PeerID{mgr.stickyPeer}, peers...)
 	}
 
+	// Pinned peers that are still available always go first, in pin order.
+	var pinnedPeers []core.PeerID
+	for _, peer := range mgr.pinnedPeers {
+		if _, exists := mgr.peers[peer]; exists {
+			pinnedPeers = append(pinnedPeers, peer)
+		}
+	}
+	if len(pinnedPeers) > 0 {
+		peers = append(pinnedPeers, peers...)
+	}
+
 	return peers
 }
 
+// isProtocolSupported returns true iff the given protocol is one of the supported protocols.
+func (mgr *peerManager) isProtocolSupported(p protocol.ID) bool {
+	for _, pid := range mgr.protocolIDs {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// isPeerSupported returns true iff the given peer supports any of the supported protocols
+// according to the peerstore.
+func (mgr *peerManager) isPeerSupported(peerID core.PeerID) bool {
+	pids := make([]string, 0, len(mgr.protocolIDs))
+	for _, pid := range mgr.protocolIDs {
+		pids = append(pids, string(pid))
+	}
+
+	protocols, err := mgr.host.Peerstore().SupportsProtocols(peerID, pids...)
+	if err != nil {
+		mgr.logger.Error("failed to get peer's protocols",
+			"err", err,
+			"peer_id", peerID,
+		)
+		return false
+	}
+	return len(protocols) > 0
+}
+
+// addPeerIfSupported adds the given peer in case it supports the protocol.
+func (mgr *peerManager) addPeerIfSupported(peerID core.PeerID) {
+	if mgr.isPeerSupported(peerID) {
+		mgr.AddPeer(peerID)
+	}
+}
+
 func (mgr *peerManager) peerProtocolWatcher() {
 	// Subscribe to peer protocol updates.
 	sub, err := mgr.host.EventBus().Subscribe([]interface{}{
@@ -272,51 +421,28 @@ func (mgr *peerManager) peerProtocolWatcher() {
 
 	// Now that we have subscribed, make sure to process any peers that are already there.
 	for _, peerID := range mgr.host.Network().Peers() {
-		protocols, err := mgr.host.Peerstore().GetProtocols(peerID)
-		if err != nil {
-			mgr.logger.Error("failed to get peer's protocols",
-				"err", err,
-				"peer_id", peerID,
-			)
-			continue
-		}
-
-		for _, p := range protocols {
-			if protocol.ID(p) == mgr.protocolID {
-				mgr.AddPeer(peerID)
-			}
-		}
+		mgr.addPeerIfSupported(peerID)
 	}
 
 	for ev := range sub.Out() {
 		switch evt := ev.(type) {
 		case event.EvtPeerIdentificationCompleted:
 			// New peer has completed the identification protocol handshake.
-			protocols, err := mgr.host.Peerstore().GetProtocols(evt.Peer)
-			if err != nil {
-				mgr.logger.Error("failed to get peer's protocols",
-					"err", err,
-					"peer_id", evt.Peer,
-				)
-				continue
-			}
-
-			for _, p := range protocols {
-				if protocol.ID(p) == mgr.protocolID {
-					mgr.AddPeer(evt.Peer)
-				}
-			}
+			mgr.addPeerIfSupported(evt.Peer)
 		case event.EvtPeerProtocolsUpdated:
 			// Peer's protocols updated.
 			for _, p := range evt.Added {
-				if p == mgr.protocolID {
+				if mgr.isProtocolSupported(p) {
 					mgr.AddPeer(evt.Peer)
+					break
 				}
 			}
 
 			for _, p := range evt.Removed {
-				if p == mgr.protocolID {
+				// Only remove the peer if it doesn't support any of the other protocols.
+				if mgr.isProtocolSupported(p) && !mgr.isPeerSupported(evt.Peer) {
 					mgr.RemovePeer(evt.Peer)
+					break
 				}
 			}
 		}
@@ -325,10 +451,18 @@ func (mgr *peerManager) peerProtocolWatcher() {
 
 // NewPeerManager creates a new peer manager for the given protocol.
 func NewPeerManager(p2p P2P, protocolID protocol.ID, stickyPeers bool) PeerManager {
+	return newPeerManager(p2p, []protocol.ID{protocolID}, stickyPeers)
+}
+
+// newPeerManager creates a new peer manager for peers supporting any of the given protocols. The
+// first protocol is the primary protocol used for tagging peers.
+func newPeerManager(p2p P2P, protocolIDs []protocol.ID, stickyPeers bool) *peerManager {
+	protocolID := protocolIDs[0]
 	mgr := &peerManager{
 		p2p:          p2p,
 		host:         p2p.GetHost(),
 		protocolID:   protocolID,
+		protocolIDs:  protocolIDs,
 		peers:        make(map[core.PeerID]*peerStats),
 		ignoredPeers: make(map[core.PeerID]bool),
 		stickyPeers:  stickyPeers,
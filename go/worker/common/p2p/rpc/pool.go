@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+type pooledStream struct {
+	stream    network.Stream
+	codec     Codec
+	idleSince time.Time
+}
+
+// streamPool keeps idle streams that the peers agreed to keep alive so that they can be reused
+// for subsequent requests.
+//
+// Each client has its own pool and all of the client's streams use the client's protocol (or one
+// of its compatible versions), so the pool is effectively keyed by peer and protocol.
+type streamPool struct {
+	sync.Mutex
+
+	maxIdlePerPeer int
+	idleTimeout    time.Duration
+	now            func() time.Time
+
+	idle   map[core.PeerID][]*pooledStream
+	closed bool
+}
+
+// get removes and returns the most recently used idle stream for the given peer. Streams that
+// have been idle for longer than the idle timeout are closed.
+func (p *streamPool) get(peerID core.PeerID) *pooledStream {
+	p.Lock()
+	defer p.Unlock()
+
+	p.expireLocked(peerID)
+
+	streams := p.idle[peerID]
+	if len(streams) == 0 {
+		return nil
+	}
+	ps := streams[len(streams)-1]
+	streams[len(streams)-1] = nil
+	p.idle[peerID] = streams[:len(streams)-1]
+	return ps
+}
+
+// put returns an idle stream to the pool. In case the pool for the given peer is full or the pool
+// has been closed, the stream is closed instead.
+func (p *streamPool) put(peerID core.PeerID, stream network.Stream, codec Codec) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.expireLocked(peerID)
+
+	if p.closed || len(p.idle[peerID]) >= p.maxIdlePerPeer {
+		_ = stream.Close()
+		return
+	}
+	p.idle[peerID] = append(p.idle[peerID], &pooledStream{
+		stream:    stream,
+		codec:     codec,
+		idleSince: p.now(),
+	})
+}
+
+// expireLocked closes all expired idle streams for the given peer.
+func (p *streamPool) expireLocked(peerID core.PeerID) {
+	streams := p.idle[peerID]
+	now := p.now()
+
+	// Streams are ordered by the time they became idle, so all expired streams are at the front.
+	var n int
+	for n < len(streams) && now.Sub(streams[n].idleSince) >= p.idleTimeout {
+		_ = streams[n].stream.Close()
+		n++
+	}
+	switch {
+	case n == len(streams):
+		delete(p.idle, peerID)
+	case n > 0:
+		p.idle[peerID] = append([]*pooledStream(nil), streams[n:]...)
+	}
+}
+
+// close closes all idle streams. Streams returned to the pool afterwards are closed immediately.
+func (p *streamPool) close() {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, streams := range p.idle {
+		for _, ps := range streams {
+			_ = ps.stream.Close()
+		}
+	}
+	p.idle = nil
+	p.closed = true
+}
+
+func newStreamPool(maxIdlePerPeer int, idleTimeout time.Duration) *streamPool {
+	return &streamPool{
+		maxIdlePerPeer: maxIdlePerPeer,
+		idleTimeout:    idleTimeout,
+		now:            time.Now,
+		idle:           make(map[core.PeerID][]*pooledStream),
+	}
+}
@@ -4,6 +4,7 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"io"
 
 	core "github.com/libp2p/go-libp2p-core"
 	"github.com/libp2p/go-libp2p-core/protocol"
@@ -23,6 +24,18 @@ type P2P interface {
 	GetHost() core.Host
 }
 
+// Codec is a wire codec used for exchanging messages over a stream.
+type Codec interface {
+	// Write encodes the given message and writes it to the underlying stream.
+	Write(msg interface{}) error
+
+	// Read reads the next message from the underlying stream and decodes it into msg.
+	Read(msg interface{}) error
+}
+
+// CodecFactory is a function that creates a new codec for the given stream.
+type CodecFactory func(rw io.ReadWriter) Codec
+
 // NewRuntimeProtocolID generates a protocol identifier for a protocol supported for a specific
 // runtime. This makes it so that one doesn't need additional checks to ensure that a peer supports
 // the given protocol for the given runtime.
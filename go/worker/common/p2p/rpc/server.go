@@ -18,6 +18,10 @@ const (
 	RequestReadDeadline   = 5 * time.Second
 	RequestHandleTimeout  = 60 * time.Second
 	ResponseWriteDeadline = 60 * time.Second
+
+	// KeepAliveIdleTimeout is the time the server waits for the next request on a stream that the
+	// client asked to keep alive.
+	KeepAliveIdleTimeout = 60 * time.Second
 )
 
 // Service is an RPC service implementation.
@@ -54,14 +58,29 @@ func (s *server) HandleStream(stream network.Stream) {
 	logger := s.logger.With("peer_id", stream.Conn().RemotePeer())
 	codec := cbor.NewMessageCodec(stream, codecModuleName)
 
+	// Keep serving requests for as long as the client asks for the stream to be kept alive.
+	readDeadline := RequestReadDeadline
+	for s.handleRequest(stream, codec, logger, readDeadline) {
+		readDeadline = KeepAliveIdleTimeout
+	}
+}
+
+// handleRequest reads a single request from the stream, handles it and writes the response. It
+// returns true iff the stream should be kept alive for further requests.
+func (s *server) handleRequest(
+	stream network.Stream,
+	codec *cbor.MessageCodec,
+	logger *logging.Logger,
+	readDeadline time.Duration,
+) bool {
 	// Read request.
 	var request Request
-	_ = stream.SetReadDeadline(time.Now().Add(RequestReadDeadline))
+	_ = stream.SetReadDeadline(time.Now().Add(readDeadline))
 	if err := codec.Read(&request); err != nil {
 		logger.Debug("failed to read request",
 			"err", err,
 		)
-		return
+		return false
 	}
 	_ = stream.SetReadDeadline(time.Time{})
 
@@ -71,15 +90,31 @@ func (s *server) HandleStream(stream network.Stream) {
 
 	// Handle request.
 	ctx, cancel := context.WithTimeout(context.Background(), RequestHandleTimeout)
+	defer cancel()
 	ctx = WithPeerID(ctx, stream.Conn().RemotePeer())
 	rsp, err := s.HandleRequest(ctx, request.Method, request.Body)
-	cancel()
 
 	// Generate response.
-	var response Response
+	var (
+		response  Response
+		streamRsp *StreamResponse
+	)
 	switch err {
 	case nil:
-		response.Ok = cbor.Marshal(rsp)
+		switch r := rsp.(type) {
+		case *StreamResponse:
+			// Response body is streamed after the response.
+			response.Stream = true
+			streamRsp = r
+		default:
+			response.Ok = cbor.Marshal(rsp)
+			if request.Compression {
+				if compressed, ok := compressPayload(response.Ok); ok {
+					response.Ok = nil
+					response.OkCompressed = compressed
+				}
+			}
+		}
 	default:
 		logger.Debug("failed to process request",
 			"err", err,
@@ -94,15 +129,28 @@ func (s *server) HandleStream(stream network.Stream) {
 		}
 	}
 
+	// Streamed responses always terminate the stream.
+	response.KeepAlive = request.KeepAlive && streamRsp == nil
+
 	// Send response.
 	_ = stream.SetWriteDeadline(time.Now().Add(ResponseWriteDeadline))
 	if err = codec.Write(&response); err != nil {
 		logger.Debug("failed to write response",
 			"err", err,
 		)
-		return
+		return false
 	}
 	_ = stream.SetWriteDeadline(time.Time{})
+
+	if streamRsp != nil {
+		if err = streamRsp.writeChunks(stream, codec); err != nil {
+			logger.Debug("failed to stream response",
+				"err", err,
+				"method", request.Method,
+			)
+		}
+	}
+	return response.KeepAlive
 }
 
 // NewServer creates a new RPC server for the given protocol.
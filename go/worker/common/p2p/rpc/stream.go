@@ -0,0 +1,275 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	core "github.com/libp2p/go-libp2p-core"
+	"github.com/libp2p/go-libp2p-core/network"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/errors"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+)
+
+// StreamChunkSize is the maximum size of a single chunk of a streamed response.
+const StreamChunkSize = 64 * 1024 // 64 KiB
+
+// StreamResponse is a response that is streamed to the client as a sequence of chunks instead of
+// being buffered in memory and sent as a single message.
+//
+// A service handler opts into a streaming response by returning a *StreamResponse from its
+// HandleRequest method. The server then reads the reader until EOF and sends the data to the
+// client in chunks of at most StreamChunkSize bytes. In case the reader also implements io.Closer,
+// it is closed after the response has been streamed.
+//
+// Clients must use CallStream in order to receive streamed responses.
+type StreamResponse struct {
+	// Reader is the reader providing the response data.
+	Reader io.Reader
+}
+
+// NewStreamResponse creates a new streaming response backed by the given reader.
+func NewStreamResponse(r io.Reader) *StreamResponse {
+	return &StreamResponse{Reader: r}
+}
+
+func (sr *StreamResponse) writeChunks(stream network.Stream, codec *cbor.MessageCodec) error {
+	if closer, ok := sr.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	writeChunk := func(chunk *StreamChunk) error {
+		_ = stream.SetWriteDeadline(time.Now().Add(ResponseWriteDeadline))
+		if err := codec.Write(chunk); err != nil {
+			return fmt.Errorf("failed to write chunk: %w", err)
+		}
+		_ = stream.SetWriteDeadline(time.Time{})
+		return nil
+	}
+
+	buf := make([]byte, StreamChunkSize)
+	for {
+		n, err := sr.Reader.Read(buf)
+		if n > 0 {
+			if werr := writeChunk(&StreamChunk{Data: buf[:n]}); werr != nil {
+				return werr
+			}
+		}
+
+		switch err {
+		case nil:
+		case io.EOF:
+			// End of stream, send an empty chunk.
+			return writeChunk(&StreamChunk{})
+		default:
+			module, code := errors.Code(err)
+			_ = writeChunk(&StreamChunk{
+				Error: &Error{
+					Module:  module,
+					Code:    code,
+					Message: err.Error(),
+				},
+			})
+			return err
+		}
+	}
+}
+
+// streamReader is a reader of a streamed response.
+type streamReader struct {
+	sync.Mutex
+
+	c                   *client
+	stream              network.Stream
+	codec               Codec
+	method              string
+	peerID              core.PeerID
+	startTime           time.Time
+	maxPeerResponseTime time.Duration
+
+	buf  []byte
+	err  error
+	done bool
+}
+
+func (sr *streamReader) Read(p []byte) (int, error) {
+	sr.Lock()
+	defer sr.Unlock()
+
+	for len(sr.buf) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		sr.err = sr.readChunk()
+	}
+
+	n := copy(p, sr.buf)
+	sr.buf = sr.buf[n:]
+	return n, nil
+}
+
+func (sr *streamReader) readChunk() error {
+	var chunk StreamChunk
+	_ = sr.stream.SetReadDeadline(time.Now().Add(sr.maxPeerResponseTime))
+	if err := sr.codec.Read(&chunk); err != nil {
+		sr.c.logger.Debug("failed to read response chunk",
+			"err", err,
+			"method", sr.method,
+			"peer_id", sr.peerID,
+		)
+		return sr.fail(fmt.Errorf("failed to read response chunk: %w", err))
+	}
+	_ = sr.stream.SetReadDeadline(time.Time{})
+
+	switch {
+	case chunk.Error != nil:
+		return sr.fail(errors.FromCode(chunk.Error.Module, chunk.Error.Code, chunk.Error.Message))
+	case len(chunk.Data) == 0:
+		// End of stream.
+		sr.done = true
+		return io.EOF
+	default:
+		sr.buf = chunk.Data
+		return nil
+	}
+}
+
+// fail aborts the stream and records a failure for the peer.
+func (sr *streamReader) fail(err error) error {
+	_ = sr.stream.Reset()
+
+	sr.c.metrics.observeFeedback(sr.method, feedbackFailure)
+	sr.c.feedback.RecordFailure(sr.peerID, time.Since(sr.startTime))
+	return err
+}
+
+func (sr *streamReader) Close() error {
+	sr.Lock()
+	defer sr.Unlock()
+
+	if sr.err == nil {
+		sr.err = fmt.Errorf("stream closed")
+	}
+	if !sr.done {
+		// Abort the stream so that the peer stops sending any remaining data.
+		return sr.stream.Reset()
+	}
+	return sr.stream.Close()
+}
+
+func (c *client) CallStream(
+	ctx context.Context,
+	method string,
+	body interface{},
+	maxPeerResponseTime time.Duration,
+) (io.ReadCloser, PeerFeedback, error) {
+	c.logger.Debug("call stream", "method", method)
+
+	if err := c.ensureConnectedPeer(); err != nil {
+		return nil, nil, err
+	}
+
+	// Prepare the request.
+	request := c.newRequest(method, body)
+
+	// Iterate through the prioritized list of peers and attempt to execute the request.
+	var failures callFailures
+	for _, peer := range c.GetBestPeers() {
+		if !c.isPeerAcceptable(peer) {
+			continue
+		}
+
+		c.logger.Debug("trying peer",
+			"method", method,
+			"peer_id", peer,
+		)
+
+		rd, pf, err := c.callStream(ctx, peer, &request, maxPeerResponseTime)
+		if err != nil {
+			failures.record(err)
+			continue
+		}
+		return rd, pf, nil
+	}
+
+	// No peers could be reached to service this request.
+	c.logger.Debug("no peers could be reached to service request",
+		"method", method,
+	)
+
+	return nil, nil, failures.err()
+}
+
+func (c *client) callStream(
+	ctx context.Context,
+	peerID core.PeerID,
+	request *Request,
+	maxPeerResponseTime time.Duration,
+) (io.ReadCloser, PeerFeedback, error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	default:
+	}
+
+	startTime := time.Now()
+
+	rd, protocolVersion, err := c.sendRequestAndOpenResponse(ctx, peerID, request, maxPeerResponseTime, startTime)
+	latency := time.Since(startTime)
+	c.metrics.observeCall(request.Method, latency)
+	if err != nil {
+		c.logger.Debug("failed to call method",
+			"err", err,
+			"method", request.Method,
+			"peer_id", peerID,
+		)
+
+		c.metrics.observeFeedback(request.Method, feedbackFailure)
+		c.feedback.RecordFailure(peerID, latency)
+		return nil, nil, err
+	}
+
+	pf := &peerFeedback{
+		mgr:     c.feedback,
+		metrics: c.metrics,
+		method:  request.Method,
+		peerID:  peerID,
+		latency: latency,
+		version: protocolVersion,
+	}
+	return rd, pf, nil
+}
+
+func (c *client) sendRequestAndOpenResponse(
+	ctx context.Context,
+	peerID core.PeerID,
+	request *Request,
+	maxPeerResponseTime time.Duration,
+	startTime time.Time,
+) (io.ReadCloser, version.Version, error) {
+	stream, codec, rawRsp, err := c.sendRequest(ctx, peerID, request, maxPeerResponseTime)
+	if err != nil {
+		return nil, version.Version{}, err
+	}
+	protocolVersion := c.streamVersion(stream)
+	if !rawRsp.Stream {
+		// Peer returned a buffered response, expose it as a stream for convenience.
+		c.releaseStream(peerID, stream, codec, rawRsp)
+		return io.NopCloser(bytes.NewReader(rawRsp.Ok)), protocolVersion, nil
+	}
+
+	return &streamReader{
+		c:                   c,
+		stream:              stream,
+		codec:               codec,
+		method:              request.Method,
+		peerID:              peerID,
+		startTime:           startTime,
+		maxPeerResponseTime: maxPeerResponseTime,
+	}, protocolVersion, nil
+}
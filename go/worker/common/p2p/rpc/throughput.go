@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"os"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+// MinThroughputGracePeriod is the time after receiving the first byte during which the minimum
+// throughput is not enforced.
+const MinThroughputGracePeriod = time.Second
+
+// throughputStream is a stream that aborts reads in case the peer is sending data slower than the
+// configured minimum throughput.
+//
+// Each read deadline set on the stream starts a new measurement which begins once the first byte
+// has been received. Like reads, deadlines must be set from the reading goroutine.
+type throughputStream struct {
+	network.Stream
+
+	minThroughput uint64
+	now           func() time.Time
+
+	readDeadline time.Time
+	start        time.Time
+	read         uint64
+
+	err error
+}
+
+// minThroughputDeadline returns the time at which the peer falls below the minimum throughput
+// in case no more data is received.
+func (s *throughputStream) minThroughputDeadline() time.Time {
+	expected := time.Duration(float64(s.read) / float64(s.minThroughput) * float64(time.Second))
+	return s.start.Add(MinThroughputGracePeriod + expected)
+}
+
+// SetReadDeadline implements network.Stream.
+func (s *throughputStream) SetReadDeadline(t time.Time) error {
+	s.readDeadline = t
+	s.start = time.Time{}
+	s.read = 0
+
+	return s.Stream.SetReadDeadline(t)
+}
+
+// Read implements io.Reader.
+func (s *throughputStream) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	// Make sure that reads do not block past the point where the peer would be too slow. Not all
+	// transports support deadlines so we also check the throughput after each read.
+	var limited bool
+	if !s.start.IsZero() {
+		deadline := s.minThroughputDeadline()
+		if s.readDeadline.IsZero() || deadline.Before(s.readDeadline) {
+			limited = true
+		} else {
+			deadline = s.readDeadline
+		}
+		_ = s.Stream.SetReadDeadline(deadline)
+	}
+
+	n, err := s.Stream.Read(p)
+	if limited && err != nil && os.IsTimeout(err) {
+		s.err = ErrPeerTooSlow
+		return n, s.err
+	}
+
+	now := s.now()
+	if n > 0 && s.start.IsZero() {
+		s.start = now
+	}
+	s.read += uint64(n)
+	if err == nil && !s.start.IsZero() && now.After(s.minThroughputDeadline()) {
+		// Some readers ignore errors returned together with data so make sure that all further
+		// reads fail.
+		s.err = ErrPeerTooSlow
+		return n, s.err
+	}
+	return n, err
+}
+
+func newThroughputStream(stream network.Stream, minThroughput uint64) *throughputStream {
+	return &throughputStream{
+		Stream:        stream,
+		minThroughput: minThroughput,
+		now:           time.Now,
+	}
+}
@@ -2,6 +2,10 @@ package rpc
 
 import (
 	"fmt"
+	"io"
+	"sync"
+
+	core "github.com/libp2p/go-libp2p-core"
 
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/errors"
@@ -16,14 +20,193 @@ var (
 
 	// ErrBadRequest is an error raised when a given request is malformed.
 	ErrBadRequest = errors.New(ModuleName, 2, "rpc: bad request")
+
+	// ErrNoConnectedPeers is an error raised when no peers are connected.
+	ErrNoConnectedPeers = errors.New(ModuleName, 3, "rpc: no connected peers")
+
+	// ErrMalformedResponse is an error raised when a response from a peer cannot be decoded.
+	ErrMalformedResponse = errors.New(ModuleName, 4, "rpc: malformed response")
+
+	// ErrPeerTooSlow is an error raised when a peer sends the response slower than the configured
+	// minimum throughput.
+	ErrPeerTooSlow = errors.New(ModuleName, 5, "rpc: peer too slow")
+
+	// ErrUnavailable is an error raised when a call failed on all peers for reasons that may be
+	// transient (e.g., peers being unreachable, slow or faulty).
+	ErrUnavailable = errors.New(ModuleName, 6, "rpc: call failed on all peers")
 )
 
+var requestErrors = struct {
+	sync.RWMutex
+	codes map[string]map[uint32]bool
+}{
+	codes: make(map[string]map[uint32]bool),
+}
+
+func init() {
+	RegisterRequestErrors(ErrMethodNotSupported, ErrBadRequest)
+}
+
+// RegisterRequestErrors classifies the given errors as request errors, meaning that the request
+// is invalid and retrying it (on any peer) will not help. Errors are matched by module and code
+// so services should register their request-level errors.
+func RegisterRequestErrors(errs ...error) {
+	requestErrors.Lock()
+	defer requestErrors.Unlock()
+
+	for _, err := range errs {
+		module, code := errors.Code(err)
+		if requestErrors.codes[module] == nil {
+			requestErrors.codes[module] = make(map[uint32]bool)
+		}
+		requestErrors.codes[module][code] = true
+	}
+}
+
+// IsRequestError returns true iff the error is a request error returned by a peer or a call
+// where all peers returned the same request error.
+func IsRequestError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrUnavailable) {
+		// The wrapped error of the last attempted peer may be a request error, but the peers did
+		// not agree on it.
+		return false
+	}
+	module, code := errors.Code(err)
+
+	requestErrors.RLock()
+	defer requestErrors.RUnlock()
+	return requestErrors.codes[module][code]
+}
+
+// IsUnavailableError returns true iff the error indicates that the call could not be served by
+// any peer for reasons that may be transient, so retrying later might help.
+func IsUnavailableError(err error) bool {
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrNoConnectedPeers)
+}
+
+// unavailableError is the error returned when a call failed on all peers. It matches
+// ErrUnavailable via errors.Is.
+type unavailableError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *unavailableError) Error() string {
+	if e.err == nil {
+		return "call failed on all peers"
+	}
+	return fmt.Sprintf("call failed on all peers: %s", e.err)
+}
+
+// Unwrap returns the error returned by the last attempted peer.
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+// Is returns true iff the target is ErrUnavailable.
+func (e *unavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// callFailures keeps track of the errors returned by peers while serving a call.
+type callFailures struct {
+	lastErr    error
+	requestErr error
+	mixed      bool
+}
+
+// record records an error returned by a peer.
+func (f *callFailures) record(err error) {
+	f.lastErr = err
+
+	switch {
+	case !IsRequestError(err):
+		f.mixed = true
+	case f.requestErr == nil:
+		f.requestErr = err
+	default:
+		module, code := errors.Code(err)
+		prevModule, prevCode := errors.Code(f.requestErr)
+		if module != prevModule || code != prevCode {
+			f.mixed = true
+		}
+	}
+}
+
+// isRequestError returns true iff all peers returned the same request error.
+func (f *callFailures) isRequestError() bool {
+	return f.requestErr != nil && !f.mixed
+}
+
+// err returns the error of the call. In case all peers returned the same request error, that error
+// is returned as is, otherwise the error matches ErrUnavailable.
+func (f *callFailures) err() error {
+	if f.isRequestError() {
+		return f.requestErr
+	}
+	return &unavailableError{err: f.lastErr}
+}
+
+// MalformedResponseError is the error returned when a response received from a peer cannot be
+// decoded. It matches ErrMalformedResponse via errors.Is.
+type MalformedResponseError struct {
+	// Method is the name of the called method.
+	Method string
+	// PeerID is the identifier of the peer that sent the response.
+	PeerID core.PeerID
+	// Offset is the byte offset within the response at which decoding failed or -1 if unknown.
+	Offset int
+	// Err is the underlying decoding error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *MalformedResponseError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("%s: method '%s' from peer %s: %s", ErrMalformedResponse, e.Method, e.PeerID, e.Err)
+	}
+	return fmt.Sprintf("%s: method '%s' from peer %s at offset %d: %s", ErrMalformedResponse, e.Method, e.PeerID, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *MalformedResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true iff the target is ErrMalformedResponse.
+func (e *MalformedResponseError) Is(target error) bool {
+	return target == ErrMalformedResponse
+}
+
+// newMalformedResponseError wraps the error that occurred while decoding the given response data.
+func newMalformedResponseError(method string, peerID core.PeerID, data []byte, err error) error {
+	offset := -1
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		// The response was truncated so decoding failed at its end.
+		offset = len(data)
+	}
+	return &MalformedResponseError{
+		Method: method,
+		PeerID: peerID,
+		Offset: offset,
+		Err:    err,
+	}
+}
+
 // Request is a request sent by the client.
 type Request struct {
 	// Method is the name of the method.
 	Method string `json:"method"`
 	// Body is the method-specific body.
 	Body cbor.RawMessage `json:"body"`
+	// Compression is a flag specifying that the client accepts compressed responses.
+	Compression bool `json:"compression,omitempty"`
+	// KeepAlive is a flag specifying that the client would like to reuse the stream for
+	// subsequent requests.
+	KeepAlive bool `json:"keep_alive,omitempty"`
 }
 
 // Error is a message body representing an error.
@@ -44,4 +227,23 @@ type Response struct {
 	Ok cbor.RawMessage `json:"ok,omitempty"`
 	// Error is an error response in case of failure.
 	Error *Error `json:"error,omitempty"`
+	// Stream is a flag specifying that the response is streamed as a sequence of StreamChunk
+	// messages following this response.
+	Stream bool `json:"stream,omitempty"`
+	// OkCompressed is the compressed method-specific response in case of success. It is only used
+	// when the client accepts compressed responses and is used instead of Ok.
+	OkCompressed []byte `json:"ok_compressed,omitempty"`
+	// KeepAlive is a flag specifying that the server will keep reading requests from the stream
+	// after this response.
+	KeepAlive bool `json:"keep_alive,omitempty"`
+}
+
+// StreamChunk is a chunk of a streamed response.
+//
+// A chunk without any data and without an error terminates the stream.
+type StreamChunk struct {
+	// Data is the chunk data.
+	Data []byte `json:"data,omitempty"`
+	// Error is an error in case the stream failed.
+	Error *Error `json:"error,omitempty"`
 }
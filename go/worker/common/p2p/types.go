@@ -20,3 +20,26 @@ type CommitteeMessage struct {
 // TxMessage is a message published to nodes via gossipsub on the transaction topic. It contains the
 // raw signed transaction with runtime-dependent semantics.
 type TxMessage []byte
+
+// TxEnvelopeVersion is the current version of the transaction message envelope.
+const TxEnvelopeVersion = 1
+
+// TxEnvelopeMessage is a versioned message published to nodes via gossipsub on the transaction
+// topic. It contains the raw signed transaction with runtime-dependent semantics.
+//
+// Envelopes are accepted when received, but transactions are still published in the bare
+// TxMessage wire format until all peers are able to decode envelopes.
+type TxEnvelopeMessage struct {
+	// Version is the envelope version.
+	Version uint16 `json:"v"`
+
+	// Tx is the raw signed transaction.
+	Tx []byte `json:"tx"`
+}
+
+// TxBatchMessage is a message published to nodes via gossipsub on the transaction topic. It
+// contains a batch of raw signed transactions with runtime-dependent semantics.
+//
+// Batches are accepted when received, but transactions are still published one per message until
+// all peers are able to decode batches.
+type TxBatchMessage [][]byte
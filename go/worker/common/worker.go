@@ -176,6 +176,8 @@ func (w *Worker) registerRuntime(runtime runtimeRegistry.Runtime) error {
 		w.Consensus,
 		w.P2P,
 		&w.cfg.TxPool,
+		&w.cfg.TxDedup,
+		&w.cfg.TxRateLimit,
 	)
 	if err != nil {
 		return err
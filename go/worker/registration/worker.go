@@ -817,6 +817,9 @@ func (w *Worker) registerNode(epoch beacon.EpochTime, hook RegisterNodeHook) err
 		nodeSigners = append([]signature.Signer{w.identity.NodeSigner}, nodeSigners...)
 	}
 
+	// Normalize the descriptor so the reported status matches what is registered.
+	nodeDesc.Normalize()
+
 	sigNode, err := node.MultiSignNode(nodeSigners, registry.RegisterNodeSignatureContext, &nodeDesc)
 	if err != nil {
 		w.logger.Error("failed to register node: unable to sign node descriptor",
@@ -36,7 +36,7 @@ func (c *client) GetDiff(ctx context.Context, request *GetDiffRequest) (*GetDiff
 
 func (c *client) GetCheckpoints(ctx context.Context, request *GetCheckpointsRequest) (*GetCheckpointsResponse, error) {
 	var rsp GetCheckpointsResponse
-	rsps, pfs, err := c.rc.CallMulti(ctx, MethodGetCheckpoints, request, rsp, MaxGetCheckpointsResponseTime, MaxGetCheckpointsParallelRequests)
+	rsps, pfs, err := c.rc.CallMulti(ctx, MethodGetCheckpoints, request, rsp, MaxGetCheckpointsResponseTime, MaxGetCheckpointsParallelRequests, 0)
 	if err != nil {
 		return nil, err
 	}